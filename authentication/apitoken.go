@@ -0,0 +1,61 @@
+package authentication
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+)
+
+var errNoApiToken = errors.New("No api token in request.")
+
+// The last used date of a token is only written to the database if it is older than this
+const apiTokenLastUsedInterval = time.Minute
+
+// HasApiToken reports whether the request is authenticated with an api token (as opposed to a session cookie).
+func HasApiToken(request *http.Request) bool {
+	_, ok := bearerToken(request)
+	return ok
+}
+
+// GetApiToken returns the api token the request is authenticated with.
+func GetApiToken(request *http.Request) (*structure.ApiToken, error) {
+	token, ok := bearerToken(request)
+	if !ok {
+		return nil, errNoApiToken
+	}
+	apiToken, err := database.RetrieveApiTokenByTokenHash(HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	currentTime := date.GetCurrentTime()
+	if apiToken.LastUsedAt == nil || currentTime.Sub(*apiToken.LastUsedAt) > apiTokenLastUsedInterval {
+		err = database.UpdateApiTokenLastUsed(apiToken.Id, currentTime)
+		if err != nil {
+			return nil, err
+		}
+		apiToken.LastUsedAt = &currentTime
+	}
+	return apiToken, nil
+}
+
+// ApiTokenAllows reports whether the api token of the request may be used for the request method. Read-only tokens are limited to GET requests.
+func ApiTokenAllows(apiToken *structure.ApiToken, request *http.Request) bool {
+	if apiToken.Scope == structure.ApiTokenScopeFull {
+		return true
+	}
+	return request.Method == "GET" || request.Method == "HEAD"
+}
+
+func bearerToken(request *http.Request) (string, bool) {
+	header := request.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
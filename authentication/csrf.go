@@ -0,0 +1,70 @@
+package authentication
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+)
+
+// The names AngularJS uses by default: $http copies the cookie into the header of every request to the same origin
+const csrfCookieName = "XSRF-TOKEN"
+const csrfHeaderName = "X-XSRF-TOKEN"
+
+// SetCsrfCookie sets the cookie with the csrf token of the session in the request. The cookie is readable by scripts on
+// pages of the blog (unlike the session cookie), but pages on other sites can neither read it nor set the header.
+func SetCsrfCookie(response http.ResponseWriter, request *http.Request) {
+	cookie, err := request.Cookie(sessionCookieName)
+	if err != nil {
+		return
+	}
+	setCsrfCookie(csrfToken(cookie.Value), response, request)
+}
+
+// CsrfTokenIsValid reports whether the csrf header of the request matches the session the request was made with.
+func CsrfTokenIsValid(request *http.Request) bool {
+	cookie, err := request.Cookie(sessionCookieName)
+	if err != nil {
+		return false
+	}
+	token := request.Header.Get(csrfHeaderName)
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(csrfToken(cookie.Value))) == 1
+}
+
+// OriginIsValid reports whether the request was sent from a page of the blog. Requests without Origin and Referer header
+// (e.g. from command line tools) are accepted, because browsers always send at least one of them with forms and scripts.
+func OriginIsValid(request *http.Request) bool {
+	origin := request.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		origin = request.Header.Get("Referer")
+	}
+	if origin == "" {
+		return true
+	}
+	originUrl, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return originUrl.Host == request.Host
+}
+
+// The token is derived from the session token, so it changes with every login and doesn't need to be stored
+func csrfToken(sessionToken string) string {
+	return HashToken("csrf:" + sessionToken)
+}
+
+func setCsrfCookie(token string, response http.ResponseWriter, request *http.Request) {
+	cookie := &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/admin/",
+		Secure:   request.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	}
+	if token == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(response, cookie)
+}
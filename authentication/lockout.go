@@ -0,0 +1,141 @@
+package authentication
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"journey/configuration"
+	"journey/database"
+	"journey/date"
+)
+
+// Failed logins older than this don't count anymore
+const loginFailureWindow = 24 * time.Hour
+
+// No lockout lasts longer than this
+const maxLoginDelay = 24 * time.Hour
+
+// LoginDelay returns how long the client has to wait before it may try to log in as the user again. Every failed login doubles the delay.
+// The returned bool is true if the account or the ip address of the client is locked (as opposed to just being slowed down).
+func LoginDelay(name string, request *http.Request) (time.Duration, bool, error) {
+	ip := RemoteIp(request)
+	if isAllowListed(ip) {
+		return 0, false, nil
+	}
+	currentTime := date.GetCurrentTime()
+	failures, lastFailure, err := failedLoginsByName(name, currentTime)
+	if err != nil {
+		return 0, false, err
+	}
+	delay, locked := loginDelay(failures, lastFailure, int64(configuration.Config.LoginAttempts), currentTime)
+	failures, lastFailure, err = failedLoginsByIp(ip, currentTime)
+	if err != nil {
+		return 0, false, err
+	}
+	ipDelay, ipLocked := loginDelay(failures, lastFailure, int64(configuration.Config.LoginAttemptsPerIp), currentTime)
+	if ipDelay > delay {
+		return ipDelay, ipLocked, nil
+	}
+	return delay, locked, nil
+}
+
+// RecordLoginAttempt stores the result of a login attempt. If a failed attempt locks the account or the ip address, the lockout is recorded too.
+func RecordLoginAttempt(name string, successful bool, request *http.Request) error {
+	ip := RemoteIp(request)
+	// Failed logins from allowed addresses never count towards a lockout
+	if !successful && isAllowListed(ip) {
+		return nil
+	}
+	currentTime := date.GetCurrentTime()
+	err := database.InsertLoginAttempt(name, ip, successful, currentTime)
+	if err != nil {
+		return err
+	}
+	if successful {
+		// Older attempts don't matter anymore
+		return database.DeleteLoginAttemptsBefore(currentTime.Add(-loginFailureWindow))
+	}
+	failures, lastFailure, err := failedLoginsByName(name, currentTime)
+	if err != nil {
+		return err
+	}
+	if delay, locked := loginDelay(failures, lastFailure, int64(configuration.Config.LoginAttempts), currentTime); locked {
+		err = database.InsertLockout(name, "", failures, currentTime.Add(delay), currentTime)
+		if err != nil {
+			return err
+		}
+	}
+	failures, lastFailure, err = failedLoginsByIp(ip, currentTime)
+	if err != nil {
+		return err
+	}
+	if delay, locked := loginDelay(failures, lastFailure, int64(configuration.Config.LoginAttemptsPerIp), currentTime); locked {
+		err = database.InsertLockout("", ip, failures, currentTime.Add(delay), currentTime)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loginDelay calculates the remaining delay after the given number of failed logins. Until the number of failures reaches the threshold,
+// the delay grows from one second. After that, the account is locked for the configured lockout time, which doubles with every further failure.
+func loginDelay(failures int64, lastFailure *time.Time, threshold int64, currentTime time.Time) (time.Duration, bool) {
+	if failures == 0 || lastFailure == nil {
+		return 0, false
+	}
+	locked := failures >= threshold
+	var delay time.Duration
+	if locked {
+		delay = time.Duration(configuration.Config.LoginLockout) * time.Minute << uint(minInt64(failures-threshold, 16))
+	} else {
+		delay = time.Second << uint(minInt64(failures-1, 16))
+	}
+	if delay > maxLoginDelay {
+		delay = maxLoginDelay
+	}
+	remaining := lastFailure.Add(delay).Sub(currentTime)
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, locked
+}
+
+// Failed logins of an account only count if they happened after the last successful login and inside the failure window
+func failedLoginsByName(name string, currentTime time.Time) (int64, *time.Time, error) {
+	since := currentTime.Add(-loginFailureWindow)
+	if lastSuccess, err := database.RetrieveLastSuccessfulLoginByName(name); err == nil && lastSuccess.After(since) {
+		since = *lastSuccess
+	}
+	return database.RetrieveFailedLoginsByName(name, since)
+}
+
+// All failed logins from an ip address inside the failure window count. Otherwise an attacker could log into their own account in between guesses.
+func failedLoginsByIp(ip string, currentTime time.Time) (int64, *time.Time, error) {
+	return database.RetrieveFailedLoginsByIp(ip, currentTime.Add(-loginFailureWindow))
+}
+
+func isAllowListed(ip string) bool {
+	address := net.ParseIP(ip)
+	if address == nil {
+		return false
+	}
+	for _, entry := range configuration.Config.LoginAllowList {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(address) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(address) {
+			return true
+		}
+	}
+	return false
+}
+
+func minInt64(a int64, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -2,6 +2,7 @@ package authentication
 
 import (
 	"journey/database"
+	"journey/structure"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -17,7 +18,17 @@ func LoginIsCorrect(name string, password string) bool {
 	if err != nil {
 		return false
 	}
-	return true
+	// Suspended users are not allowed to log in
+	return UserIsActive(name)
+}
+
+// UserIsActive returns false if the user doesn't exist or has been suspended.
+func UserIsActive(name string) bool {
+	user, err := database.RetrieveUserByName([]byte(name))
+	if err != nil {
+		return false
+	}
+	return user.Status != structure.UserStatusInactive
 }
 
 // EncryptPassword generates a bcrypt hash from the provided plaintext password.
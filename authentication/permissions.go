@@ -0,0 +1,81 @@
+package authentication
+
+import (
+	"journey/structure"
+)
+
+// isAdministrator returns true for owners and administrators of the blog.
+func isAdministrator(user *structure.User) bool {
+	return user.Role == structure.RoleOwner || user.Role == structure.RoleAdministrator
+}
+
+// CanManageSettings reports whether the user may change the blog settings.
+func CanManageSettings(user *structure.User) bool {
+	return isAdministrator(user)
+}
+
+// CanRequireTwoFactor reports whether the user may make two-factor authentication mandatory for all users.
+func CanRequireTwoFactor(user *structure.User) bool {
+	return user.Role == structure.RoleOwner
+}
+
+// CanListUsers reports whether the user may see the other users of the blog.
+func CanListUsers(user *structure.User) bool {
+	return isAdministrator(user) || user.Role == structure.RoleEditor
+}
+
+// CanEditPost reports whether the user may change or delete the post. Authors may only touch their own posts.
+func CanEditPost(user *structure.User, post *structure.Post) bool {
+	if user.Role == structure.RoleAuthor {
+		return post.Author != nil && post.Author.Id == user.Id
+	}
+	return isAdministrator(user) || user.Role == structure.RoleEditor
+}
+
+// CanSeeAllPosts reports whether the user may list posts written by other users.
+func CanSeeAllPosts(user *structure.User) bool {
+	return user.Role != structure.RoleAuthor
+}
+
+// CanDeleteImages reports whether the user may delete uploaded images (which could be used by posts of other users).
+func CanDeleteImages(user *structure.User) bool {
+	return isAdministrator(user) || user.Role == structure.RoleEditor
+}
+
+// CanManageTags reports whether the user may create and edit tags (which are shared by the posts of all users).
+func CanManageTags(user *structure.User) bool {
+	return isAdministrator(user) || user.Role == structure.RoleEditor
+}
+
+// CanAssignRole reports whether the user may invite or create a user with the given role, or give an existing user that role.
+func CanAssignRole(user *structure.User, role int) bool {
+	switch user.Role {
+	case structure.RoleOwner, structure.RoleAdministrator:
+		return role == structure.RoleAdministrator || role == structure.RoleEditor || role == structure.RoleAuthor
+	case structure.RoleEditor:
+		return role == structure.RoleAuthor
+	}
+	return false
+}
+
+// CanEditUser reports whether the user may view and change the profile of target.
+func CanEditUser(user *structure.User, target *structure.User) bool {
+	if user.Id == target.Id {
+		return true
+	}
+	return CanManageUser(user, target)
+}
+
+// CanManageUser reports whether the user may change the role of, suspend or delete target. Nobody can manage the owner or themselves.
+func CanManageUser(user *structure.User, target *structure.User) bool {
+	if user.Id == target.Id || target.Role == structure.RoleOwner {
+		return false
+	}
+	switch user.Role {
+	case structure.RoleOwner, structure.RoleAdministrator:
+		return true
+	case structure.RoleEditor:
+		return target.Role == structure.RoleAuthor
+	}
+	return false
+}
@@ -1,48 +1,143 @@
 package authentication
 
 import (
+	"errors"
+	"net"
 	"net/http"
+	"time"
 
-	"github.com/gorilla/securecookie"
+	"journey/configuration"
+	"journey/database"
+	"journey/date"
+	"journey/structure"
 )
 
-var cookieHandler = securecookie.New(
-	securecookie.GenerateRandomKey(64),
-	securecookie.GenerateRandomKey(32))
+const sessionCookieName = "session"
 
-// SetSession creates a secure session cookie for the authenticated user.
-func SetSession(userName string, response http.ResponseWriter) {
-	value := map[string]string{
-		"name": userName,
+var errSessionExpired = errors.New("Session expired.")
+
+// The last seen date of a session is only written to the database if it is older than this
+const sessionLastSeenInterval = time.Minute
+
+// SetSession creates a new session for the authenticated user and sets the session cookie.
+// If remember is true, the cookie is kept after the browser is closed and the session lasts longer.
+func SetSession(userId int64, remember bool, response http.ResponseWriter, request *http.Request) error {
+	token, err := GenerateToken()
+	if err != nil {
+		return err
+	}
+	currentTime := date.GetCurrentTime()
+	lifetime := time.Duration(configuration.Config.SessionLifetime) * time.Hour
+	if remember {
+		lifetime = time.Duration(configuration.Config.RememberMeLifetime) * 24 * time.Hour
+	}
+	expiresAt := currentTime.Add(lifetime)
+	// Remove the sessions of all users that have expired in the meantime
+	err = database.DeleteExpiredSessions(currentTime)
+	if err != nil {
+		return err
+	}
+	_, err = database.InsertSession(HashToken(token), userId, remember, request.UserAgent(), RemoteIp(request), currentTime, expiresAt)
+	if err != nil {
+		return err
+	}
+	cookie := &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/admin/",
+		HttpOnly: true,
+		Secure:   request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if remember {
+		cookie.Expires = expiresAt
+		cookie.MaxAge = int(lifetime.Seconds())
+	}
+	http.SetCookie(response, cookie)
+	setCsrfCookie(csrfToken(token), response, request)
+	return nil
+}
+
+// GetSession returns the valid session belonging to the session cookie in the request.
+func GetSession(request *http.Request) (*structure.Session, error) {
+	cookie, err := request.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, err
 	}
-	if encoded, err := cookieHandler.Encode("session", value); err == nil {
-		cookie := &http.Cookie{
-			Name:  "session",
-			Value: encoded,
-			Path:  "/admin/",
+	session, err := database.RetrieveSessionByTokenHash(HashToken(cookie.Value))
+	if err != nil {
+		return nil, err
+	}
+	currentTime := date.GetCurrentTime()
+	if !session.ExpiresAt.After(currentTime) {
+		return nil, errSessionExpired
+	}
+	if session.LastSeenAt == nil || currentTime.Sub(*session.LastSeenAt) > sessionLastSeenInterval {
+		err = database.UpdateSessionLastSeen(session.Id, currentTime)
+		if err != nil {
+			return nil, err
 		}
-		http.SetCookie(response, cookie)
+		session.LastSeenAt = &currentTime
 	}
+	return session, nil
 }
 
-// GetUserName extracts the username from the session cookie in the request.
-func GetUserName(request *http.Request) (userName string) {
-	if cookie, err := request.Cookie("session"); err == nil {
-		cookieValue := make(map[string]string)
-		if err = cookieHandler.Decode("session", cookie.Value, &cookieValue); err == nil {
-			userName = cookieValue["name"]
+// GetUserName returns the name of the user the session or the api token in the request belongs to. Returns an empty string if there is no valid session or token
+// or the user has been suspended in the meantime. The scope of api tokens is checked by the handlers that change data.
+func GetUserName(request *http.Request) string {
+	var userName string
+	if HasApiToken(request) {
+		apiToken, err := GetApiToken(request)
+		if err != nil {
+			return ""
+		}
+		userName = string(apiToken.UserName)
+	} else {
+		session, err := GetSession(request)
+		if err != nil {
+			return ""
 		}
+		userName = string(session.UserName)
+	}
+	if !UserIsActive(userName) {
+		return ""
 	}
 	return userName
 }
 
-// ClearSession removes the session cookie by setting it to expire.
-func ClearSession(response http.ResponseWriter) {
+// ClearSession ends the session in the request and removes the session cookie by setting it to expire.
+func ClearSession(response http.ResponseWriter, request *http.Request) error {
 	cookie := &http.Cookie{
-		Name:   "session",
-		Value:  "",
-		Path:   "/admin/",
-		MaxAge: -1,
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/admin/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
 	}
 	http.SetCookie(response, cookie)
+	setCsrfCookie("", response, request)
+	if requestCookie, err := request.Cookie(sessionCookieName); err == nil {
+		return database.DeleteSessionByTokenHash(HashToken(requestCookie.Value))
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends all sessions of the user except the session of the request (e.g. after a password change).
+func RevokeOtherSessions(userId int64, request *http.Request) error {
+	currentSessionId := int64(0)
+	if session, err := GetSession(request); err == nil && session.UserId == userId {
+		currentSessionId = session.Id
+	}
+	return database.DeleteSessionsByUserId(userId, currentSessionId)
+}
+
+// RemoteIp returns the ip address of the client without the port.
+func RemoteIp(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
 }
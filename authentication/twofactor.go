@@ -0,0 +1,169 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+	"journey/totp"
+)
+
+const twoFactorCookieName = "twofactor"
+
+// The second factor has to be entered within this time after the password
+const twoFactorChallengeLifetime = 5 * time.Minute
+
+// Number of recovery codes a user gets when enabling two-factor authentication
+const recoveryCodeCount = 10
+
+var errTwoFactorChallengeExpired = errors.New("Two-factor login expired.")
+
+// TwoFactorEnabled returns true if the user has to enter a code after the password.
+func TwoFactorEnabled(userId int64) (bool, error) {
+	twoFactor, err := database.RetrieveTwoFactor(userId)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return twoFactor.Enabled, nil
+}
+
+// StartTwoFactorChallenge remembers that the user entered the correct password and sets the cookie for the second step of the login.
+// If enroll is true, the user doesn't have two-factor authentication yet and has to set it up with a new secret to finish the login.
+func StartTwoFactorChallenge(userId int64, remember bool, enroll bool, response http.ResponseWriter, request *http.Request) error {
+	token, err := GenerateToken()
+	if err != nil {
+		return err
+	}
+	secret := ""
+	if enroll {
+		secret, err = totp.GenerateSecret()
+		if err != nil {
+			return err
+		}
+	}
+	currentTime := date.GetCurrentTime()
+	err = database.DeleteExpiredTwoFactorChallenges(currentTime)
+	if err != nil {
+		return err
+	}
+	err = database.InsertTwoFactorChallenge(HashToken(token), userId, remember, secret, currentTime.Add(twoFactorChallengeLifetime))
+	if err != nil {
+		return err
+	}
+	http.SetCookie(response, &http.Cookie{
+		Name:     twoFactorCookieName,
+		Value:    token,
+		Path:     "/admin/login/",
+		MaxAge:   int(twoFactorChallengeLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// GetTwoFactorChallenge returns the unexpired two-factor challenge belonging to the cookie in the request.
+func GetTwoFactorChallenge(request *http.Request) (*structure.TwoFactorChallenge, error) {
+	cookie, err := request.Cookie(twoFactorCookieName)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := database.RetrieveTwoFactorChallenge(HashToken(cookie.Value))
+	if err != nil {
+		return nil, err
+	}
+	if !challenge.ExpiresAt.After(date.GetCurrentTime()) {
+		return nil, errTwoFactorChallengeExpired
+	}
+	return challenge, nil
+}
+
+// ClearTwoFactorChallenge removes the challenge of the request so that it can't be used for another login.
+func ClearTwoFactorChallenge(response http.ResponseWriter, request *http.Request) error {
+	http.SetCookie(response, &http.Cookie{
+		Name:     twoFactorCookieName,
+		Value:    "",
+		Path:     "/admin/login/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if cookie, err := request.Cookie(twoFactorCookieName); err == nil {
+		return database.DeleteTwoFactorChallengeByTokenHash(HashToken(cookie.Value))
+	}
+	return nil
+}
+
+// VerifyTwoFactorCode checks a code from the authenticator app or one of the recovery codes of a user with enabled two-factor authentication.
+// Every code is only accepted once.
+func VerifyTwoFactorCode(userId int64, code string) (bool, error) {
+	twoFactor, err := database.RetrieveTwoFactor(userId)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !twoFactor.Enabled {
+		return false, nil
+	}
+	if step, valid := totp.Validate(twoFactor.Secret, code, date.GetCurrentTime(), twoFactor.LastStep); valid {
+		// Another request may have used the same code in the meantime
+		return database.UpdateTwoFactorLastStep(userId, step)
+	}
+	return database.UpdateRecoveryCodeUsed(userId, hashRecoveryCode(code), date.GetCurrentTime())
+}
+
+// EnableTwoFactor turns on two-factor authentication with the given secret if the code matches it.
+func EnableTwoFactor(userId int64, secret string, code string) (bool, error) {
+	step, valid := totp.Validate(secret, code, date.GetCurrentTime(), 0)
+	if !valid {
+		return false, nil
+	}
+	err := database.InsertTwoFactor(userId, secret, date.GetCurrentTime())
+	if err != nil {
+		return false, err
+	}
+	err = database.UpdateTwoFactorEnabled(userId, true, step)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of the user with new ones. The codes are only returned here, the database just keeps their hashes.
+func GenerateRecoveryCodes(userId int64) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for index, _ := range codes {
+		code := make([]byte, 5)
+		_, err := rand.Read(code)
+		if err != nil {
+			return nil, err
+		}
+		encodedCode := hex.EncodeToString(code)
+		codes[index] = encodedCode[:5] + "-" + encodedCode[5:]
+		hashes[index] = hashRecoveryCode(codes[index])
+	}
+	err := database.InsertRecoveryCodes(userId, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Recovery codes are accepted regardless of case, spaces and dashes
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return HashToken(code)
+}
@@ -3,6 +3,14 @@
 //register the modules
 var adminApp = angular.module('adminApp', ['ngRoute', 'frapontillo.bootstrap-switch', 'ui.bootstrap', 'infinite-scroll']);
 
+//$http sends the csrf token from the XSRF-TOKEN cookie by itself, requests made with jQuery (e.g. image uploads) need it too
+$(document).ajaxSend(function(event, xhr, settings) {
+  var token = (document.cookie.match(/(?:^|;\s*)XSRF-TOKEN=([^;]*)/) || [])[1];
+  if (token) {
+    xhr.setRequestHeader('X-XSRF-TOKEN', decodeURIComponent(token));
+  }
+});
+
 adminApp.config(function($routeProvider) {
   $routeProvider.
     when('/', {
@@ -21,6 +29,18 @@ adminApp.config(function($routeProvider) {
       templateUrl: 'settings.html',
       controller: 'SettingsCtrl'
     }).
+    when('/tags/', {
+      templateUrl: 'tags.html',
+      controller: 'TagsCtrl'
+    }).
+    when('/users/', {
+      templateUrl: 'users.html',
+      controller: 'UsersCtrl'
+    }).
+    when('/audit/', {
+      templateUrl: 'audit.html',
+      controller: 'AuditCtrl'
+    }).
     otherwise({
           redirectTo: '/'
   });
@@ -39,6 +59,23 @@ adminApp.factory('sharingService', function(){
   }
 });
 
+//role ids as used by the server
+var roles = [{Id: 1, Name: 'Administrator'}, {Id: 2, Name: 'Editor'}, {Id: 3, Name: 'Author'}, {Id: 4, Name: 'Owner'}];
+
+//builds the navbar html and marks the item of the calling controller as active
+function navbarHtml(active) {
+  var items = [{url: '#/', label: 'Content'}, {url: '#/create/', label: 'New Post'}, {url: '#/settings/', label: 'Settings'}, {url: '#/tags/', label: 'Tags'}, {url: '#/users/', label: 'Users'}, {url: '#/audit/', label: 'Audit Log'}];
+  var html = '<ul class="nav navbar-nav">';
+  for (var i = 0; i < items.length; i++) {
+    if (items[i].label == active) {
+      html += '<li class="active"><a href="' + items[i].url + '">' + items[i].label + '<span class="sr-only">(current)</span></a></li>';
+    } else {
+      html += '<li><a href="' + items[i].url + '">' + items[i].label + '</a></li>';
+    }
+  }
+  return html + '<li><a href="logout/" class="logout">( Log Out )</a></li></ul>';
+}
+
 //directive to handle visual selection of images
 adminApp.directive('imgSelectionDirective', function() {
     return {
@@ -52,6 +89,24 @@ adminApp.directive('imgSelectionDirective', function() {
     }
 });
 
+//directive to render an otpauth:// uri as QR code for authenticator apps
+adminApp.directive('qrCode', function() {
+  return {
+    restrict: 'A',
+    link: function(scope, elem, attrs) {
+      attrs.$observe('qrCode', function(value) {
+        elem.empty();
+        if (value) {
+          var qr = qrcode(0, 'M');
+          qr.addData(value);
+          qr.make();
+          elem.html(qr.createImgTag(4));
+        }
+      });
+    }
+  };
+});
+
 //directive to add/remove the blog url to/from the navigation url fields
 adminApp.directive('evaluateUrl', function() {
   return {
@@ -74,8 +129,10 @@ adminApp.directive('evaluateUrl', function() {
 
 //factory to load items in infinite-scroll
 adminApp.factory('infiniteScrollFactory', function($http) {
-  var infiniteScrollFactory = function(url) {
+  var infiniteScrollFactory = function(url, query) {
     this.url = url;
+    //optional query string that is appended after the page number
+    this.query = query || '';
     this.items = [];
     this.busy = false;
     this.after = 1;
@@ -84,7 +141,7 @@ adminApp.factory('infiniteScrollFactory', function($http) {
   infiniteScrollFactory.prototype.nextPage = function() {
     if (this.busy) return;
     this.busy = true;
-    var url = this.url + this.after;
+    var url = this.url + this.after + this.query;
     $http.get(url).success(function(data) {
       var items = data;
       for (var i = 0; i < items.length; i++) {
@@ -99,20 +156,61 @@ adminApp.factory('infiniteScrollFactory', function($http) {
 
 adminApp.controller('ContentCtrl', function ($scope, $http, $sce, $location, infiniteScrollFactory, sharingService){
   //change the navbar according to controller
-  $scope.navbarHtml = $sce.trustAsHtml('<ul class="nav navbar-nav"><li class="active"><a href="#/">Content<span class="sr-only">(current)</span></a></li><li><a href="#/create/">New Post</a></li><li><a href="#/settings/">Settings</a></li><li><a href="logout/" class="logout">( Log Out )</a></li></ul>');
+  $scope.navbarHtml = $sce.trustAsHtml(navbarHtml('Content'));
   $scope.infiniteScrollFactory = new infiniteScrollFactory('/admin/api/posts/');
+  $scope.searchQuery = '';
+  $scope.showTrash = false;
+  $scope.search = function() {
+    $scope.showTrash = false;
+    if ($scope.searchQuery.trim() == '') {
+      $scope.infiniteScrollFactory = new infiniteScrollFactory('/admin/api/posts/');
+    } else {
+      $scope.infiniteScrollFactory = new infiniteScrollFactory('/admin/api/posts/', '?q=' + encodeURIComponent($scope.searchQuery));
+    }
+    $scope.infiniteScrollFactory.nextPage();
+  };
+  $scope.clearSearch = function() {
+    $scope.searchQuery = '';
+    $scope.search();
+  };
+  //snippets are escaped by the server and only contain <mark> tags
+  $scope.trustSnippet = function(snippet) {
+    return $sce.trustAsHtml(snippet);
+  };
   $scope.openPost = function(postId) {
     $location.url('/edit/' + postId);
   };
+  var removePost = function(postId) {
+    for (var i = 0; i < $scope.infiniteScrollFactory.items.length; i++) {
+      if($scope.infiniteScrollFactory.items[i].Id == postId) {
+        $scope.infiniteScrollFactory.items.splice(i, 1);
+      }
+    }
+  };
   $scope.deletePost = function(postId, postTitle) {
-    if (confirm('Are you sure you want to delete the post "' + postTitle + '"?')) {
+    //posts are moved to the trash first, deleting them from the trash removes them for good
+    var question = $scope.showTrash ? 'Are you sure you want to delete the post "' + postTitle + '" permanently? This can\'t be undone.' : 'Move the post "' + postTitle + '" to the trash?';
+    if (confirm(question)) {
       $http.delete('/admin/api/post/' + postId).success(function(data) {
-        //delete post from array
-        for (var i = 0; i < $scope.infiniteScrollFactory.items.length; i++) {
-          if($scope.infiniteScrollFactory.items[i].Id == postId) {
-            $scope.infiniteScrollFactory.items.splice(i, 1);
-          }
-        }
+        removePost(postId);
+      });
+    }
+  };
+  $scope.toggleTrash = function() {
+    $scope.showTrash = !$scope.showTrash;
+    $scope.searchQuery = '';
+    $scope.infiniteScrollFactory = new infiniteScrollFactory($scope.showTrash ? '/admin/api/trash/' : '/admin/api/posts/');
+    $scope.infiniteScrollFactory.nextPage();
+  };
+  $scope.restorePost = function(postId) {
+    $http.post('/admin/api/post/' + postId + '/restore').success(function(data) {
+      removePost(postId);
+    });
+  };
+  $scope.emptyTrash = function() {
+    if (confirm('Are you sure you want to delete all posts in the trash permanently? This can\'t be undone.')) {
+      $http.delete('/admin/api/trash').success(function(data) {
+        $scope.infiniteScrollFactory.items = [];
       });
     }
   };
@@ -120,7 +218,7 @@ adminApp.controller('ContentCtrl', function ($scope, $http, $sce, $location, inf
 
 adminApp.controller('SettingsCtrl', function ($scope, $http, $timeout, $sce, $location, sharingService){
   //change the navbar according to controller
-  $scope.navbarHtml = $sce.trustAsHtml('<ul class="nav navbar-nav"><li><a href="#/">Content</a></li><li><a href="#/create/">New Post</a></li><li class="active"><a href="#/settings/">Settings<span class="sr-only">(current)</span></a></li><li><a href="logout/" class="logout">( Log Out )</a></li></ul>');
+  $scope.navbarHtml = $sce.trustAsHtml(navbarHtml('Settings'));
   $scope.shared = sharingService.shared;
   //variable to hold the field prefix
   $scope.prefix = '';
@@ -152,8 +250,120 @@ adminApp.controller('SettingsCtrl', function ($scope, $http, $timeout, $sce, $lo
       $http.get('/admin/api/user/' + $scope.authenticatedUser.Id).success(function(data) {
         $scope.shared.user = data;
       });
+      if ($scope.canManageSettings()) {
+        $http.get('/admin/api/apikeys').success(function(data) {
+          $scope.apiKeys = data;
+        });
+        $http.get('/admin/api/redirects').success(function(data) {
+          $scope.redirects = data;
+        });
+      }
+      $scope.loadSessions();
+      $scope.loadTwoFactor();
+      $scope.loadApiTokens();
+    });
+  };
+  $scope.loadApiTokens = function() {
+    $http.get('/admin/api/tokens').success(function(data) {
+      $scope.apiTokens = data;
+    });
+  };
+  $scope.addApiToken = function() {
+    $http.post('/admin/api/tokens', $scope.newApiToken).success(function(data) {
+      $scope.createdApiToken = data.Token;
+      $scope.newApiToken = {Name: '', Scope: 'read'};
+      $scope.loadApiTokens();
+    }).error(function(data) {
+      alert(data);
+    });
+  };
+  $scope.deleteApiToken = function(apiToken) {
+    if (confirm('Are you sure you want to revoke the token "' + apiToken.Name + '"? Scripts using it will stop working.')) {
+      $http.delete('/admin/api/token/' + apiToken.Id).success(function(data) {
+        $scope.apiTokens.splice($scope.apiTokens.indexOf(apiToken), 1);
+      });
+    }
+  };
+  $scope.loadTwoFactor = function() {
+    $http.get('/admin/api/twofactor').success(function(data) {
+      $scope.twoFactor = data;
+    });
+  };
+  $scope.setupTwoFactor = function() {
+    $http.post('/admin/api/twofactor/setup').success(function(data) {
+      $scope.twoFactorSetup = data;
+      $scope.twoFactorCode = {Code: ''};
+    }).error(function(data) {
+      alert(data);
+    });
+  };
+  $scope.enableTwoFactor = function() {
+    $http.post('/admin/api/twofactor/enable', $scope.twoFactorCode).success(function(data) {
+      $scope.twoFactorSetup = null;
+      $scope.recoveryCodes = data.RecoveryCodes;
+      $scope.twoFactorCode = {Code: ''};
+      $scope.loadTwoFactor();
+    }).error(function(data) {
+      alert(data);
     });
   };
+  $scope.disableTwoFactor = function() {
+    $http.post('/admin/api/twofactor/disable', $scope.twoFactorCode).success(function(data) {
+      $scope.recoveryCodes = [];
+      $scope.twoFactorCode = {Code: ''};
+      $scope.loadTwoFactor();
+    }).error(function(data) {
+      alert(data);
+    });
+  };
+  $scope.renewRecoveryCodes = function() {
+    $http.post('/admin/api/twofactor/recoverycodes', $scope.twoFactorCode).success(function(data) {
+      $scope.recoveryCodes = data.RecoveryCodes;
+      $scope.twoFactorCode = {Code: ''};
+      $scope.loadTwoFactor();
+    }).error(function(data) {
+      alert(data);
+    });
+  };
+  $scope.changeTwoFactorRequirement = function() {
+    $http.patch('/admin/api/twofactor/requirement', {Required: $scope.twoFactor.Required}).error(function(data) {
+      alert(data);
+      $scope.loadTwoFactor();
+    });
+  };
+  //only the owner may make two-factor authentication mandatory
+  $scope.canRequireTwoFactor = function() {
+    return $scope.authenticatedUser != null && $scope.authenticatedUser.Role == 4;
+  };
+  $scope.loadSessions = function() {
+    $http.get('/admin/api/sessions').success(function(data) {
+      $scope.sessions = data;
+    });
+  };
+  $scope.revokeSession = function(session) {
+    $http.delete('/admin/api/session/' + session.Id).success(function(data) {
+      $scope.sessions.splice($scope.sessions.indexOf(session), 1);
+    }).error(function(data) {
+      alert(data);
+    });
+  };
+  $scope.revokeOtherSessions = function() {
+    $http.delete('/admin/api/sessions').success(function(data) {
+      $scope.loadSessions();
+    });
+  };
+  $scope.sessions = [];
+  $scope.twoFactor = {Enabled: false, Required: false, RecoveryCodesLeft: 0};
+  $scope.twoFactorSetup = null;
+  $scope.twoFactorCode = {Code: ''};
+  $scope.recoveryCodes = [];
+  $scope.apiKeys = [];
+  $scope.apiTokens = [];
+  $scope.newApiToken = {Name: '', Scope: 'read'};
+  $scope.createdApiToken = '';
+  $scope.newApiKey = {Name: ''};
+  $scope.redirects = [];
+  $scope.newRedirect = {From: '', To: '', Match: 'exact'};
   $scope.loadData();
   $scope.deleteNavItem = function(index) {
     $scope.shared.blog.NavigationItems.splice(index, 1);
@@ -165,8 +375,47 @@ adminApp.controller('SettingsCtrl', function ($scope, $http, $timeout, $sce, $lo
     }
     $scope.shared.blog['NavigationItems'].push({label: 'Home', url: url});
   };
+  $scope.addApiKey = function() {
+    $http.post('/admin/api/apikeys', $scope.newApiKey).success(function(data) {
+      $scope.apiKeys.push(data);
+      $scope.newApiKey = {Name: ''};
+    }).error(function(data) {
+      alert(data);
+    });
+  };
+  $scope.deleteApiKey = function(apiKey) {
+    if (confirm('Are you sure you want to revoke the key "' + apiKey.Name + '"? Integrations using it will stop working.')) {
+      $http.delete('/admin/api/apikey/' + apiKey.Id).success(function(data) {
+        $scope.apiKeys.splice($scope.apiKeys.indexOf(apiKey), 1);
+      });
+    }
+  };
+  $scope.addRedirect = function() {
+    $http.post('/admin/api/redirects', $scope.newRedirect).success(function(data) {
+      //existing redirects might have been changed to point to the new target
+      $http.get('/admin/api/redirects').success(function(data) {
+        $scope.redirects = data;
+      });
+      $scope.newRedirect = {From: '', To: '', Match: 'exact'};
+    }).error(function(data) {
+      alert(data);
+    });
+  };
+  $scope.deleteRedirect = function(redirect) {
+    if (confirm('Are you sure you want to delete the redirect from "' + redirect.From + '"?')) {
+      $http.delete('/admin/api/redirect/' + redirect.Id).success(function(data) {
+        $scope.redirects.splice($scope.redirects.indexOf(redirect), 1);
+      });
+    }
+  };
+  //only owners and administrators may change the blog settings
+  $scope.canManageSettings = function() {
+    return $scope.authenticatedUser != null && ($scope.authenticatedUser.Role == 1 || $scope.authenticatedUser.Role == 4);
+  };
   $scope.save = function() {
-    $http.patch('/admin/api/blog', $scope.shared.blog);
+    if ($scope.canManageSettings()) {
+      $http.patch('/admin/api/blog', $scope.shared.blog);
+    }
     $http.patch('/admin/api/user', $scope.shared.user).success(function(data) {
       $location.url('/');
     });
@@ -177,7 +426,7 @@ adminApp.controller('CreateCtrl', function ($scope, $http, $sce, $location, shar
   //create markdown converter
   var converter = new showdown.Converter({extensions: ['footnotes'], ghCodeBlocks: true, simplifiedAutoLink: true, strikethrough: true, tables: true});
   //change the navbar according to controller
-  $scope.navbarHtml = $sce.trustAsHtml('<ul class="nav navbar-nav"><li><a href="#/">Content</a></li><li class="active"><a href="#/create/">New Post<span class="sr-only">(current)</span></a></li><li><a href="#/settings/">Settings</a></li><li><a href="logout/" class="logout">( Log Out )</a></li></ul>');
+  $scope.navbarHtml = $sce.trustAsHtml(navbarHtml('New Post'));
   $scope.shared = sharingService.shared;
   $scope.shared.post = {Title: 'New Post', Slug: '', Markdown: 'Write something!', IsPublished: false, Image: '', Tags: ''}
   $scope.change = function() {
@@ -190,15 +439,18 @@ adminApp.controller('CreateCtrl', function ($scope, $http, $sce, $location, shar
     $('#post-save-button').attr('disabled', 'true');
     $http.post('/admin/api/post', $scope.shared.post).success(function(data) {
       $location.url('/');
+    }).error(function(data) {
+      alert(data);
+      $('#post-save-button').removeAttr('disabled');
     });
   };
 });
 
-adminApp.controller('EditCtrl', function ($scope, $routeParams, $http, $sce, $location, sharingService){
+adminApp.controller('EditCtrl', function ($scope, $routeParams, $http, $sce, $location, $interval, sharingService){
   //create markdown converter
   var converter = new showdown.Converter({extensions: ['footnotes'], ghCodeBlocks: true, simplifiedAutoLink: true, strikethrough: true, tables: true});
   //change the navbar according to controller
-  $scope.navbarHtml = $sce.trustAsHtml('<ul class="nav navbar-nav"><li><a href="#/">Content</a></li><li><a href="#/create/">New Post</a></li><li><a href="#/settings/">Settings</a></li><li><a href="logout/" class="logout">( Log Out )</a></li></ul>');
+  $scope.navbarHtml = $sce.trustAsHtml(navbarHtml(''));
   $scope.shared = sharingService.shared;
   $scope.shared.post = {}
   $scope.change = function() {
@@ -206,18 +458,309 @@ adminApp.controller('EditCtrl', function ($scope, $routeParams, $http, $sce, $lo
     //resize the markdown textarea
     $('.textarea-autosize').val($scope.shared.post.Markdown).trigger('input');
   };
-  $http.get('/admin/api/post/' + $routeParams.Id).success(function(data) {
+  //the fields that are kept in autosaves
+  var editableFields = ['Title', 'Markdown', 'Tags', 'MetaDescription', 'Image', 'IsFeatured', 'IsPage'];
+  var lastSaved = '';
+  var snapshot = function() {
+    var fields = {};
+    for (var i = 0; i < editableFields.length; i++) {
+      fields[editableFields[i]] = $scope.shared.post[editableFields[i]];
+    }
+    return angular.toJson(fields);
+  };
+  var loadPost = function(data) {
+    //the datetime input of scheduled posts needs a date object
+    if (data.PublishAt != null) {
+      data.PublishAt = new Date(data.PublishAt);
+    }
     $scope.shared.post = data;
+    lastSaved = snapshot();
     $scope.change();
+  };
+  $http.get('/admin/api/post/' + $routeParams.Id).success(function(data) {
+    loadPost(data);
+    //offer to restore changes that were never saved
+    $http.get('/admin/api/post/' + $routeParams.Id + '/autosave').success(function(autosave) {
+      var message = 'There are unsaved changes to this post from ' + new Date(autosave.Date).toLocaleString() + '.';
+      if (autosave.IsOutdated) {
+        message += ' The post has been saved since then, restoring them will overwrite the newer version.';
+      }
+      if (confirm(message + ' Do you want to restore them?')) {
+        for (var i = 0; i < editableFields.length; i++) {
+          $scope.shared.post[editableFields[i]] = autosave[editableFields[i]];
+        }
+        $scope.change();
+      } else {
+        $http.delete('/admin/api/post/' + $routeParams.Id + '/autosave');
+      }
+    });
+  });
+  //store changes in progress every 30 seconds
+  var autosaveTimer = $interval(function() {
+    if ($scope.shared.post.Id == null || snapshot() == lastSaved) {
+      return;
+    }
+    var changes = snapshot();
+    $http.post('/admin/api/post/' + $scope.shared.post.Id + '/autosave', $scope.shared.post).success(function(data) {
+      lastSaved = changes;
+    });
+  }, 30000);
+  $scope.$on('$destroy', function() {
+    $interval.cancel(autosaveTimer);
   });
   $scope.save = function() {
     $('#post-save-button').attr('disabled', 'true');
     $http.patch('/admin/api/post', $scope.shared.post).success(function(data) {
+      $scope.shared.post.UpdatedAt = data.UpdatedAt;
+      lastSaved = snapshot();
+      $('#post-save-button').removeAttr('disabled');
+    }).error(function(data, status) {
       $('#post-save-button').removeAttr('disabled');
+      if (status == 409 && data.Id != null) {
+        //somebody else saved the post in the meantime
+        if (confirm('Somebody else saved this post since you opened it. Do you want to overwrite their changes with yours? Cancel loads their version and keeps yours as unsaved changes.')) {
+          $scope.shared.post.UpdatedAt = data.UpdatedAt;
+          $scope.save();
+        } else {
+          $http.post('/admin/api/post/' + data.Id + '/autosave', $scope.shared.post);
+          loadPost(data);
+        }
+        return;
+      }
+      alert(data);
     });
   };
 });
 
+adminApp.controller('UsersCtrl', function ($scope, $http, $sce, sharingService){
+  //change the navbar according to controller
+  $scope.navbarHtml = $sce.trustAsHtml(navbarHtml('Users'));
+  $scope.roles = roles;
+  $scope.newInvite = {Email: '', Role: 3};
+  $scope.newUser = {Name: '', Email: '', Password: '', Role: 3};
+  $scope.inviteUrl = '';
+  $scope.error = '';
+  $scope.roleName = function(roleId) {
+    for (var i = 0; i < roles.length; i++) {
+      if (roles[i].Id == roleId) {
+        return roles[i].Name;
+      }
+    }
+    return '';
+  };
+  var showError = function(data) {
+    $scope.error = data;
+  };
+  //only owners and administrators may review lockouts
+  $scope.canReviewLockouts = function() {
+    return $scope.authenticatedUser != null && ($scope.authenticatedUser.Role == 1 || $scope.authenticatedUser.Role == 4);
+  };
+  $scope.lockouts = [];
+  $scope.loadData = function() {
+    $http.get('/admin/api/userid').success(function(data) {
+      $scope.authenticatedUser = data;
+      if ($scope.canReviewLockouts()) {
+        $http.get('/admin/api/lockouts').success(function(data) {
+          $scope.lockouts = data;
+        });
+      }
+    });
+    $http.get('/admin/api/users').success(function(data) {
+      $scope.users = data;
+    }).error(showError);
+    $http.get('/admin/api/invites').success(function(data) {
+      $scope.invites = data;
+    });
+  };
+  $scope.loadData();
+  $scope.invite = function() {
+    $scope.error = '';
+    $http.post('/admin/api/invite', $scope.newInvite).success(function(data) {
+      $scope.inviteUrl = data.Url;
+      $scope.newInvite = {Email: '', Role: 3};
+      $scope.loadData();
+    }).error(showError);
+  };
+  $scope.createUser = function() {
+    $scope.error = '';
+    $http.post('/admin/api/users', $scope.newUser).success(function(data) {
+      $scope.newUser = {Name: '', Email: '', Password: '', Role: 3};
+      $scope.loadData();
+    }).error(showError);
+  };
+  $scope.changeRole = function(user) {
+    $scope.error = '';
+    $http.patch('/admin/api/user/' + user.Id + '/role', {Role: parseInt(user.Role)}).error(function(data) {
+      showError(data);
+      $scope.loadData();
+    });
+  };
+  $scope.toggleStatus = function(user) {
+    $scope.error = '';
+    var status = user.Status == 'inactive' ? 'active' : 'inactive';
+    $http.patch('/admin/api/user/' + user.Id + '/status', {Status: status}).success(function(data) {
+      user.Status = status;
+    }).error(showError);
+  };
+  $scope.deleteUser = function(user) {
+    if (confirm('Are you sure you want to delete the user "' + user.Name + '"? Their posts will be transferred to the owner of the blog.')) {
+      $http.delete('/admin/api/user/' + user.Id).success(function(data) {
+        $scope.loadData();
+      }).error(showError);
+    }
+  };
+  $scope.resetTwoFactor = function(user) {
+    if (confirm('Are you sure you want to reset the two-factor authentication of "' + user.Name + '"? Use this if they lost their device and their recovery codes.')) {
+      $http.delete('/admin/api/user/' + user.Id + '/twofactor').success(function(data) {
+        alert(data);
+      }).error(showError);
+    }
+  };
+  $scope.revokeInvite = function(invite) {
+    if (confirm('Are you sure you want to revoke the invitation for "' + invite.Email + '"?')) {
+      $http.delete('/admin/api/invite/' + invite.Id).success(function(data) {
+        $scope.loadData();
+      }).error(showError);
+    }
+  };
+});
+
+adminApp.controller('TagsCtrl', function ($scope, $http, $sce){
+  //change the navbar according to controller
+  $scope.navbarHtml = $sce.trustAsHtml(navbarHtml('Tags'));
+  $scope.tags = [];
+  $scope.editedTag = {};
+  $scope.error = '';
+  var showError = function(data) {
+    $scope.error = data;
+  };
+  //owners, administrators and editors may change tags
+  $scope.canManageTags = function() {
+    return $scope.authenticatedUser != null && $scope.authenticatedUser.Role != 3;
+  };
+  $scope.loadData = function() {
+    $http.get('/admin/api/tags').success(function(data) {
+      $scope.tags = data;
+    }).error(showError);
+  };
+  $http.get('/admin/api/userid').success(function(data) {
+    $scope.authenticatedUser = data;
+  });
+  $scope.loadData();
+  $scope.edit = function(tag) {
+    $scope.error = '';
+    $scope.editedTag = angular.copy(tag);
+    //top-level tags have the parent id 0, which the parent select shows as "None"
+    if (!$scope.editedTag.ParentId) {
+      $scope.editedTag.ParentId = null;
+    }
+  };
+  $scope.cancel = function() {
+    $scope.error = '';
+    $scope.editedTag = {};
+  };
+  $scope.save = function() {
+    $scope.error = '';
+    var request = $scope.editedTag.Id ? $http.patch('/admin/api/tag/' + $scope.editedTag.Id, $scope.editedTag) : $http.post('/admin/api/tags', $scope.editedTag);
+    request.success(function(data) {
+      $scope.editedTag = {};
+      $scope.loadData();
+    }).error(showError);
+  };
+  //names of the parents and of the tag, e.g. programming/go
+  $scope.tagPath = function(tag) {
+    var names = [tag.Name];
+    var seen = {};
+    var parentId = tag.ParentId;
+    while (parentId && !seen[parentId]) {
+      seen[parentId] = true;
+      var parent = $scope.tags.filter(function(other) {
+        return other.Id == parentId;
+      })[0];
+      if (!parent) {
+        break;
+      }
+      names.unshift(parent.Name);
+      parentId = parent.ParentId;
+    }
+    return names.join('/');
+  };
+  //the tags a tag can be merged into or placed below
+  $scope.otherTags = function(tag) {
+    return $scope.tags.filter(function(other) {
+      return other.Id != tag.Id;
+    });
+  };
+  $scope.merge = function(tag) {
+    $scope.error = '';
+    var into = tag.MergeInto;
+    if (into && confirm('Are you sure you want to merge "' + tag.Name + '" into "' + into.Name + '"? All posts tagged "' + tag.Name + '" will be tagged "' + into.Name + '" instead.')) {
+      $http.post('/admin/api/tag/' + tag.Id + '/merge', {Into: into.Id}).success(function(data) {
+        $scope.editedTag = {};
+        $scope.loadData();
+      }).error(showError);
+    }
+  };
+  $scope.delete = function(tag) {
+    $scope.error = '';
+    if (confirm('Are you sure you want to delete the tag "' + tag.Name + '"? It will be removed from ' + tag.PostCount + (tag.PostCount == 1 ? ' post.' : ' posts.'))) {
+      $http.delete('/admin/api/tag/' + tag.Id).success(function(data) {
+        $scope.editedTag = {};
+        $scope.loadData();
+      }).error(showError);
+    }
+  };
+  $scope.deleteUnused = function() {
+    $scope.error = '';
+    if (confirm('Are you sure you want to delete all tags without posts?')) {
+      $http.delete('/admin/api/tags/unused').success(function(data) {
+        $scope.loadData();
+      }).error(showError);
+    }
+  };
+});
+
+adminApp.controller('AuditCtrl', function ($scope, $http, $sce){
+  //change the navbar according to controller
+  $scope.navbarHtml = $sce.trustAsHtml(navbarHtml('Audit Log'));
+  $scope.query = {Filter: '', Since: '', Until: ''};
+  $scope.entries = [];
+  $scope.total = 0;
+  $scope.error = '';
+  var page = 1;
+  //since and until are entered as local dates and sent as RFC 3339
+  var toRfc3339 = function(value) {
+    var parsed = new Date(value);
+    return isNaN(parsed.getTime()) ? '' : parsed.toISOString();
+  };
+  var loadPage = function() {
+    var params = {filter: $scope.query.Filter};
+    if ($scope.query.Since != '') {
+      params.since = toRfc3339($scope.query.Since);
+    }
+    if ($scope.query.Until != '') {
+      params.until = toRfc3339($scope.query.Until);
+    }
+    $http.get('/admin/api/audit/' + page, {params: params}).success(function(data) {
+      $scope.entries = $scope.entries.concat(data.Entries || []);
+      $scope.total = data.Total;
+    }).error(function(data) {
+      $scope.error = data;
+    });
+  };
+  $scope.search = function() {
+    $scope.error = '';
+    $scope.entries = [];
+    page = 1;
+    loadPage();
+  };
+  $scope.loadMore = function() {
+    page++;
+    loadPage();
+  };
+  $scope.search();
+});
+
 //modal for post options and help
 adminApp.controller('EmptyModalCtrl', function ($scope, $modal, $http, sharingService) {
   $scope.shared = sharingService.shared;
@@ -231,6 +774,18 @@ adminApp.controller('EmptyModalCtrl', function ($scope, $modal, $http, sharingSe
         controller: 'EmptyModalInstanceCtrl',
         size: size
       });
+    } else if (callingFrom == 'post-revisions') {
+      var modalInstance = $modal.open({
+        templateUrl: 'revisions-modal.tpl',
+        controller: 'RevisionsModalInstanceCtrl',
+        size: size
+      });
+    } else if (callingFrom == 'post-preview') {
+      var modalInstance = $modal.open({
+        templateUrl: 'preview-modal.tpl',
+        controller: 'PreviewModalInstanceCtrl',
+        size: size
+      });
     } else if (callingFrom == 'post-help') {
       var modalInstance = $modal.open({
         templateUrl: 'post-help-modal.tpl',
@@ -295,6 +850,67 @@ adminApp.controller('EmptyModalInstanceCtrl', function ($scope, $http, $modalIns
   };
 });
 
+//revisions modal window instance
+adminApp.controller('RevisionsModalInstanceCtrl', function ($scope, $http, $route, $modalInstance, sharingService) {
+  $scope.shared = sharingService.shared;
+  $scope.selection = {from: null, to: null};
+  $scope.diff = null;
+  $http.get('/admin/api/post/' + $scope.shared.post.Id + '/revisions').success(function(data) {
+    $scope.revisions = data;
+    //compare the latest revision with the one before it
+    if (data.length > 1) {
+      $scope.selection = {from: data[1].Id, to: data[0].Id};
+      $scope.showDiff();
+    }
+  });
+  $scope.showDiff = function() {
+    if ($scope.selection.from == null || $scope.selection.to == null) {
+      return;
+    }
+    $http.get('/admin/api/post/' + $scope.shared.post.Id + '/diff/' + $scope.selection.from + '/' + $scope.selection.to).success(function(data) {
+      $scope.diff = data;
+    });
+  };
+  $scope.restore = function(revision) {
+    if (confirm('Are you sure you want to restore the revision from ' + new Date(revision.Date).toLocaleString() + '? Unsaved changes will be lost.')) {
+      $http.post('/admin/api/post/' + $scope.shared.post.Id + '/restore/' + revision.Id).success(function(data) {
+        $modalInstance.close();
+        //reload the post in the editor
+        $route.reload();
+      });
+    }
+  };
+  $scope.ok = function () {
+    $modalInstance.close();
+  };
+});
+
+//preview link modal window instance
+adminApp.controller('PreviewModalInstanceCtrl', function ($scope, $http, $modalInstance, sharingService) {
+  $scope.shared = sharingService.shared;
+  $scope.expiry = {hours: '168'};
+  $scope.preview = null;
+  $http.get('/admin/api/post/' + $scope.shared.post.Id + '/preview').success(function(data) {
+    $scope.preview = data;
+  });
+  $scope.create = function() {
+    $http.post('/admin/api/post/' + $scope.shared.post.Id + '/preview', {ExpiresIn: parseInt($scope.expiry.hours)}).success(function(data) {
+      $scope.preview = data;
+    });
+  };
+  $scope.revoke = function() {
+    if (confirm('Are you sure you want to revoke the preview link? Everybody you shared it with will lose access.')) {
+      $http.delete('/admin/api/post/' + $scope.shared.post.Id + '/preview').success(function(data) {
+        $scope.preview.Enabled = false;
+        $scope.preview.ExpiresAt = null;
+      });
+    }
+  };
+  $scope.ok = function () {
+    $modalInstance.close();
+  };
+});
+
 //image modal window instance
 adminApp.controller('ImageModalInstanceCtrl', function ($scope, $http, $modalInstance, sharingService) {
   $scope.shared = sharingService.shared;
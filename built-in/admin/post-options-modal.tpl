@@ -10,18 +10,99 @@
                     <input spellcheck="true" type="text" class="form-control" id="post-slug" ng-model="shared.post.Slug" value="{{shared.post.Slug}}">
                 </div>
             </div>
+            <div class="form-group">
+                <label for="post-meta-title" class="col-sm-2 control-label">Meta Title</label>
+                <div class="col-sm-4">
+                    <input spellcheck="true" type="text" class="form-control" id="post-meta-title" ng-model="shared.post.MetaTitle" value="{{shared.post.MetaTitle}}">
+                </div>
+            </div>
             <div class="form-group">
                 <label for="post-meta-description" class="col-sm-2 control-label">Meta Description</label>
                 <div class="col-sm-4">
                     <input spellcheck="true" type="text" class="form-control" id="post-meta-description" ng-model="shared.post.MetaDescription" value="{{shared.post.MetaDescription}}">
                 </div>
             </div>
+            <div class="form-group">
+                <label for="post-custom-excerpt" class="col-sm-2 control-label">Custom Excerpt</label>
+                <div class="col-sm-8">
+                    <textarea spellcheck="true" class="form-control" id="post-custom-excerpt" rows="3" ng-model="shared.post.CustomExcerpt"></textarea>
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-canonical-url" class="col-sm-2 control-label">Canonical URL</label>
+                <div class="col-sm-8">
+                    <input spellcheck="false" type="text" class="form-control" id="post-canonical-url" ng-model="shared.post.CanonicalUrl" value="{{shared.post.CanonicalUrl}}">
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-og-title" class="col-sm-2 control-label">Facebook Title</label>
+                <div class="col-sm-4">
+                    <input spellcheck="true" type="text" class="form-control" id="post-og-title" ng-model="shared.post.OgTitle" value="{{shared.post.OgTitle}}">
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-og-description" class="col-sm-2 control-label">Facebook Description</label>
+                <div class="col-sm-4">
+                    <input spellcheck="true" type="text" class="form-control" id="post-og-description" ng-model="shared.post.OgDescription" value="{{shared.post.OgDescription}}">
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-og-image" class="col-sm-2 control-label">Facebook Image</label>
+                <div class="col-sm-8">
+                    <input spellcheck="false" type="text" class="form-control" id="post-og-image" ng-model="shared.post.OgImage" value="{{shared.post.OgImage}}">
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-twitter-title" class="col-sm-2 control-label">Twitter Title</label>
+                <div class="col-sm-4">
+                    <input spellcheck="true" type="text" class="form-control" id="post-twitter-title" ng-model="shared.post.TwitterTitle" value="{{shared.post.TwitterTitle}}">
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-twitter-description" class="col-sm-2 control-label">Twitter Description</label>
+                <div class="col-sm-4">
+                    <input spellcheck="true" type="text" class="form-control" id="post-twitter-description" ng-model="shared.post.TwitterDescription" value="{{shared.post.TwitterDescription}}">
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-twitter-image" class="col-sm-2 control-label">Twitter Image</label>
+                <div class="col-sm-8">
+                    <input spellcheck="false" type="text" class="form-control" id="post-twitter-image" ng-model="shared.post.TwitterImage" value="{{shared.post.TwitterImage}}">
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-codeinjection-head" class="col-sm-2 control-label">Post Header Code</label>
+                <div class="col-sm-8">
+                    <textarea spellcheck="false" class="form-control" id="post-codeinjection-head" rows="3" ng-model="shared.post.CodeInjectionHead"></textarea>
+                </div>
+            </div>
+            <div class="form-group">
+                <label for="post-codeinjection-foot" class="col-sm-2 control-label">Post Footer Code</label>
+                <div class="col-sm-8">
+                    <textarea spellcheck="false" class="form-control" id="post-codeinjection-foot" rows="3" ng-model="shared.post.CodeInjectionFoot"></textarea>
+                </div>
+            </div>
             <div class="form-group">
                 <label for="post-cover" class="col-sm-2 control-label">Cover</label>
                 <div class="col-sm-10">
                     <a ng-controller="ImageModalCtrl" ng-click="open('lg', 'post-cover')"><img class="img-settings img-thumbnail img-settings" id="post-cover" src="{{shared.post.Image}}" alt="{{shared.post.Image}}" ng-if="shared.post.Image!=''" /><img class="img-settings img-thumbnail img-settings" id="post-cover" src="/public/images/no-image.png" alt="No image" ng-if="shared.post.Image==''" /></a> <a class="text-danger" id="post-cover-delete" ng-controller="EmptyModalCtrl" ng-click="deleteCover()"><span class="glyphicon glyphicon-remove" aria-hidden="true"></span> Remove</a>
                 </div>
             </div>
+            <div class="form-group">
+                <div class="col-sm-offset-1 col-sm-10">
+                  <div class="checkbox">
+                    <label>
+                        <input bs-switch ng-model="shared.post.IsScheduled" type="checkbox" class="post-checkbox" data-label-text="Schedule" data-label-width="85" data-off-text="NO" data-on-text="YES" data-on-color="success" data-off-color="danger" data-size="normal">
+                    </label>
+                  </div>
+                </div>
+            </div>
+            <div class="form-group" ng-if="shared.post.IsScheduled">
+                <label for="post-publish-at" class="col-sm-2 control-label">Publish At</label>
+                <div class="col-sm-4">
+                    <input type="datetime-local" class="form-control" id="post-publish-at" ng-model="shared.post.PublishAt">
+                </div>
+            </div>
             <div class="form-group">
                 <div class="col-sm-offset-1 col-sm-10">
                   <div class="checkbox">
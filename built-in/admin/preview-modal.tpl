@@ -0,0 +1,30 @@
+<div class="modal-header">
+    <h3 class="modal-title">Preview Link</h3>
+</div>
+<div class="modal-body">
+    <div class="container-fluid">
+        <p>Anybody with the preview link can read the saved version of this post, even if it isn't published yet. Search engines are asked not to index it.</p>
+        <div class="form-group" ng-if="preview.Enabled">
+            <input type="text" class="form-control" value="{{preview.Url}}" readonly onclick="this.select()">
+            <p class="help-block" ng-if="preview.ExpiresAt">Expires {{preview.ExpiresAt | date:'medium'}}</p>
+            <p class="help-block" ng-if="!preview.ExpiresAt">Never expires</p>
+        </div>
+        <form class="form-inline" role="form">
+            <div class="form-group">
+                <label for="preview-expiry">Expires after</label>
+                <select class="form-control" id="preview-expiry" ng-model="expiry.hours">
+                    <option value="1">1 hour</option>
+                    <option value="24">1 day</option>
+                    <option value="168">1 week</option>
+                    <option value="720">30 days</option>
+                    <option value="0">Never</option>
+                </select>
+            </div>
+            <button type="button" class="btn btn-default" ng-click="create()">{{preview.Enabled ? 'Renew Link' : 'Create Link'}}</button>
+            <button type="button" class="btn btn-danger" ng-click="revoke()" ng-if="preview.Enabled">Revoke Link</button>
+        </form>
+    </div>
+</div>
+<div class="modal-footer">
+    <button class="btn btn-primary" ng-click="ok()">OK</button>
+</div>
@@ -0,0 +1,27 @@
+<div class="modal-header">
+    <h3 class="modal-title">Revisions</h3>
+</div>
+<div class="modal-body">
+    <div class="container-fluid">
+        <table class="table table-striped">
+            <tbody>
+                <tr ng-repeat="revision in revisions">
+                    <td>
+                        <input type="radio" name="revision-from" ng-value="revision.Id" ng-model="selection.from" ng-change="showDiff()"> From
+                        <input type="radio" name="revision-to" ng-value="revision.Id" ng-model="selection.to" ng-change="showDiff()"> To
+                    </td>
+                    <td>
+                        <h5>{{revision.Title}} <small>{{revision.Date | date:'medium'}}</small></h5>
+                    </td>
+                    <td>
+                        <a ng-click="restore(revision)" ng-if="!$first"><h5><span class="glyphicon glyphicon-repeat" aria-hidden="true"></span> Restore</h5></a>
+                    </td>
+                </tr>
+            </tbody>
+        </table>
+        <pre class="revision-diff" ng-if="diff != null"><div ng-repeat="line in diff.Title" ng-class="{'diff-insert': line.Operation == 1, 'diff-delete': line.Operation == 2}">{{line.Operation == 1 ? '+' : (line.Operation == 2 ? '-' : ' ')}} {{line.Text}}</div><div>&nbsp;</div><div ng-repeat="line in diff.Markdown" ng-class="{'diff-insert': line.Operation == 1, 'diff-delete': line.Operation == 2}">{{line.Operation == 1 ? '+' : (line.Operation == 2 ? '-' : ' ')}} {{line.Text}}</div></pre>
+    </div>
+</div>
+<div class="modal-footer">
+    <button class="btn btn-primary" ng-click="ok()">OK</button>
+</div>
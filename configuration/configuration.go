@@ -13,15 +13,62 @@ import (
 
 // Configuration: settings that are neccesary for server configuration
 type Configuration struct {
-	HttpHostAndPort  string
-	HttpsHostAndPort string
-	HttpsUsage       string
-	Url              string
-	HttpsUrl         string
-	UseLetsEncrypt   bool
-	CompressImages   bool
+	HttpHostAndPort    string
+	HttpsHostAndPort   string
+	HttpsUsage         string
+	Url                string
+	HttpsUrl           string
+	UseLetsEncrypt     bool
+	CompressImages     bool
+	MaxPostRevisions   int      // Number of revisions that are kept for each post
+	TrashRetention     int      // Days a deleted post stays in the trash before it's purged. A negative value keeps deleted posts until they're purged by hand.
+	SessionLifetime    int      // Hours a login stays valid
+	RememberMeLifetime int      // Days a login stays valid if "remember me" was checked
+	LoginAttempts      int      // Failed logins after which an account is locked
+	LoginAttemptsPerIp int      // Failed logins after which an ip address is locked
+	LoginLockout       int      // Minutes of the first lockout. Every further lockout lasts twice as long.
+	LoginAllowList     []string // Ip addresses and networks (e.g. 192.168.1.0/24) that are never locked out
+	FeedItems          int      // Number of posts on each page of the rss, atom and json feeds
+	FeedContent        string   // "full" puts the whole post into the feeds, "excerpt" only a short summary
+	IncludeChildTags   bool     // Tag pages and tag feeds also list the posts of the tags below the tag (e.g. /tag/programming/ lists the posts tagged programming/go)
+	Mail               MailConfiguration
 }
 
+// MailConfiguration: how the blog sends emails (e.g. password reset links)
+type MailConfiguration struct {
+	Transport    string // "smtp", "file" (append to FilePath) or "log"
+	From         string
+	SmtpHost     string
+	SmtpPort     int
+	SmtpUser     string // Leave empty if the smtp server doesn't require authentication
+	SmtpPassword string
+	FilePath     string
+}
+
+// Used if MaxPostRevisions is not set in the config file
+const defaultMaxPostRevisions = 25
+
+// Used if TrashRetention is not set in the config file
+const defaultTrashRetention = 30
+
+// Used if the session lifetimes are not set in the config file
+const defaultSessionLifetime = 12
+const defaultRememberMeLifetime = 30
+
+// Used if the login limits are not set in the config file
+const defaultLoginAttempts = 5
+const defaultLoginAttemptsPerIp = 20
+const defaultLoginLockout = 15
+
+// Used if the feed settings are not set in the config file
+const defaultFeedItems = 15
+const defaultFeedContent = "full"
+
+// Used if the mail settings are not set in the config file. Emails are written to the log until a mail server is configured.
+const defaultMailTransport = "log"
+const defaultMailFrom = "journey@localhost"
+const defaultSmtpPort = 587
+
 func NewConfiguration() *Configuration {
 	var config Configuration
 	err := config.load()
@@ -90,6 +137,64 @@ func (c *Configuration) load() error {
 		c.HttpsUrl = c.HttpsUrl[0 : len(c.HttpsUrl)-1]
 		configWasChanged = true
 	}
+	// Make sure a revision limit is set
+	if c.MaxPostRevisions < 1 {
+		c.MaxPostRevisions = defaultMaxPostRevisions
+		configWasChanged = true
+	}
+	// Make sure a trash retention period is set
+	if c.TrashRetention == 0 {
+		c.TrashRetention = defaultTrashRetention
+		configWasChanged = true
+	}
+	// Make sure session lifetimes are set
+	if c.SessionLifetime < 1 {
+		c.SessionLifetime = defaultSessionLifetime
+		configWasChanged = true
+	}
+	if c.RememberMeLifetime < 1 {
+		c.RememberMeLifetime = defaultRememberMeLifetime
+		configWasChanged = true
+	}
+	// Make sure login limits are set
+	if c.LoginAttempts < 1 {
+		c.LoginAttempts = defaultLoginAttempts
+		configWasChanged = true
+	}
+	if c.LoginAttemptsPerIp < 1 {
+		c.LoginAttemptsPerIp = defaultLoginAttemptsPerIp
+		configWasChanged = true
+	}
+	if c.LoginLockout < 1 {
+		c.LoginLockout = defaultLoginLockout
+		configWasChanged = true
+	}
+	if c.LoginAllowList == nil {
+		c.LoginAllowList = []string{}
+		configWasChanged = true
+	}
+	// Make sure feed settings are set
+	if c.FeedItems < 1 {
+		c.FeedItems = defaultFeedItems
+		configWasChanged = true
+	}
+	if c.FeedContent != "full" && c.FeedContent != "excerpt" {
+		c.FeedContent = defaultFeedContent
+		configWasChanged = true
+	}
+	// Make sure mail settings are set
+	if c.Mail.Transport == "" {
+		c.Mail.Transport = defaultMailTransport
+		configWasChanged = true
+	}
+	if c.Mail.From == "" {
+		c.Mail.From = defaultMailFrom
+		configWasChanged = true
+	}
+	if c.Mail.SmtpPort == 0 {
+		c.Mail.SmtpPort = defaultSmtpPort
+		configWasChanged = true
+	}
 	// Check if all fields are filled out
 	cReflected := reflect.ValueOf(*c)
 	for i := 0; i < cReflected.NumField(); i++ {
@@ -110,7 +215,7 @@ func (c *Configuration) load() error {
 
 func (c *Configuration) create() error {
 	// TODO: Change default port
-	c = &Configuration{HttpHostAndPort: ":8084", HttpsHostAndPort: ":8085", HttpsUsage: "None", Url: "127.0.0.1:8084", HttpsUrl: "127.0.0.1:8085", CompressImages: false}
+	c = &Configuration{HttpHostAndPort: ":8084", HttpsHostAndPort: ":8085", HttpsUsage: "None", Url: "127.0.0.1:8084", HttpsUrl: "127.0.0.1:8085", CompressImages: false, MaxPostRevisions: defaultMaxPostRevisions, TrashRetention: defaultTrashRetention, SessionLifetime: defaultSessionLifetime, RememberMeLifetime: defaultRememberMeLifetime, LoginAttempts: defaultLoginAttempts, LoginAttemptsPerIp: defaultLoginAttemptsPerIp, LoginLockout: defaultLoginLockout, LoginAllowList: []string{}, FeedItems: defaultFeedItems, FeedContent: defaultFeedContent, IncludeChildTags: false, Mail: MailConfiguration{Transport: defaultMailTransport, From: defaultMailFrom, SmtpPort: defaultSmtpPort}}
 	err := c.save()
 	if err != nil {
 		log.Println("Error: couldn't create " + filenames.ConfigFilename)
@@ -2,9 +2,16 @@ package conversion
 
 import (
 	"bytes"
+	"html"
 	"regexp"
 )
 
+// Markers that enclose matches in search snippets. They can't be part of a post and survive html escaping.
+const (
+	SnippetMatchStart = "\x02"
+	SnippetMatchEnd   = "\x03"
+)
+
 var tagChecker = regexp.MustCompile("<.*?>")
 var whitespaceChecker = regexp.MustCompile("\\s{2,}")
 
@@ -15,3 +22,11 @@ func StripTagsFromHtml(input []byte) []byte {
 	output = whitespaceChecker.ReplaceAll(output, []byte(" "))
 	return output
 }
+
+// HighlightSnippet escapes a search snippet and replaces the match markers with <mark> tags.
+func HighlightSnippet(snippet []byte) []byte {
+	output := []byte(html.EscapeString(string(snippet)))
+	output = bytes.Replace(output, []byte(SnippetMatchStart), []byte("<mark>"), -1)
+	output = bytes.Replace(output, []byte(SnippetMatchEnd), []byte("</mark>"), -1)
+	return output
+}
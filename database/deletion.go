@@ -1,7 +1,42 @@
 package database
 
+import (
+	"time"
+)
+
 const stmtDeletePostTagsByPostId = "DELETE FROM posts_tags WHERE post_id = ?"
 const stmtDeletePostById = "DELETE FROM posts WHERE id = ?"
+const stmtDeleteRevisionsByPostId = "DELETE FROM post_revisions WHERE post_id = ?"
+const stmtDeleteOldRevisionsByPostId = "DELETE FROM post_revisions WHERE post_id = ? AND id NOT IN (SELECT id FROM post_revisions WHERE post_id = ? ORDER BY id DESC LIMIT ?)"
+const stmtDeleteTagById = "DELETE FROM tags WHERE id = ?"
+const stmtDeletePostTagsByTagId = "DELETE FROM posts_tags WHERE tag_id = ?"
+const stmtDeleteUnusedTags = "DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM posts_tags) AND id NOT IN (SELECT parent_id FROM tags WHERE parent_id IS NOT NULL)"
+const stmtReassignChildTags = "UPDATE tags SET parent_id = ? WHERE parent_id = ?"
+const stmtReassignChildTagsToParent = "UPDATE tags SET parent_id = (SELECT parent_id FROM tags WHERE id = ?) WHERE parent_id = ?"
+const stmtReassignPostTags = "UPDATE posts_tags SET tag_id = ? WHERE tag_id = ? AND post_id NOT IN (SELECT post_id FROM posts_tags WHERE tag_id = ?)"
+const stmtDeleteUserById = "DELETE FROM users WHERE id = ?"
+const stmtDeleteRolesUsersByUserId = "DELETE FROM roles_users WHERE user_id = ?"
+const stmtReassignPostsByAuthorId = "UPDATE posts SET author_id = ? WHERE author_id = ?"
+const stmtDeleteInviteById = "DELETE FROM invites WHERE id = ?"
+const stmtDeleteApiKeyById = "DELETE FROM api_keys WHERE id = ?"
+const stmtDeleteSessionById = "DELETE FROM sessions WHERE id = ?"
+const stmtDeleteSessionByTokenHash = "DELETE FROM sessions WHERE token_hash = ?"
+const stmtDeleteSessionsByUserId = "DELETE FROM sessions WHERE user_id = ? AND id != ?"
+const stmtDeleteLoginAttemptsBefore = "DELETE FROM login_attempts WHERE created_at < ?"
+const stmtDeleteTwoFactorByUserId = "DELETE FROM two_factor WHERE user_id = ?"
+const stmtDeleteRecoveryCodesByUserId = "DELETE FROM recovery_codes WHERE user_id = ?"
+const stmtDeleteTwoFactorChallengeByTokenHash = "DELETE FROM two_factor_challenges WHERE token_hash = ?"
+const stmtDeleteExpiredTwoFactorChallenges = "DELETE FROM two_factor_challenges WHERE expires_at <= ?"
+const stmtDeletePasswordResetsByUserId = "DELETE FROM password_resets WHERE user_id = ?"
+const stmtDeleteApiTokenById = "DELETE FROM api_tokens WHERE id = ?"
+const stmtDeleteApiTokensByUserId = "DELETE FROM api_tokens WHERE user_id = ?"
+const stmtDeletePostPreviewByPostId = "DELETE FROM post_previews WHERE post_id = ?"
+const stmtDeleteAutosave = "DELETE FROM post_autosaves WHERE post_id = ? AND user_id = ?"
+const stmtDeleteAutosavesByPostId = "DELETE FROM post_autosaves WHERE post_id = ?"
+const stmtDeleteRedirectById = "DELETE FROM redirects WHERE id = ?"
+const stmtDeleteRedirectByFromPath = "DELETE FROM redirects WHERE from_path = ? AND match_type = ?"
+const stmtDeleteRedirectLoops = "DELETE FROM redirects WHERE from_path = to_path"
+const stmtDeleteExpiredSessions = "DELETE FROM sessions WHERE expires_at <= ?"
 
 func DeletePostTagsForPostId(post_id int64) error {
 	writeDB, err := readDB.Begin()
@@ -28,5 +63,391 @@ func DeletePostById(id int64) error {
 		writeDB.Rollback()
 		return err
 	}
+	_, err = writeDB.Exec(stmtDeletePostTagsByPostId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostPreviewByPostId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteAutosavesByPostId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRevisionsByPostId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// DeleteTagById removes the tag from all posts and deletes it. Its child tags move up to the parent of the tag.
+func DeleteTagById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtReassignChildTagsToParent, id, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostTagsByTagId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteTagById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// DeleteTagByIdAndReassignPosts moves the posts and child tags of the tag to another tag and deletes it. Posts that have both tags keep only the other one.
+func DeleteTagByIdAndReassignPosts(id int64, new_tag_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtReassignChildTags, new_tag_id, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtReassignPostTags, new_tag_id, id, new_tag_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostTagsByTagId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteTagById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// DeleteUnusedTags deletes all tags that no post (including drafts and posts in the trash) uses and returns how many were deleted.
+// Parent tags are kept as long as they have child tags, so they're deleted once all of their child tags are gone.
+func DeleteUnusedTags() (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	var count int64
+	for {
+		result, err := writeDB.Exec(stmtDeleteUnusedTags)
+		if err != nil {
+			writeDB.Rollback()
+			return 0, err
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			writeDB.Rollback()
+			return 0, err
+		}
+		if deleted == 0 {
+			break
+		}
+		count += deleted
+	}
+	return count, writeDB.Commit()
+}
+
+func DeleteAutosave(post_id int64, user_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteAutosave, post_id, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// DeleteOldRevisionsForPostId removes all but the newest keep revisions of the post.
+func DeleteOldRevisionsForPostId(post_id int64, keep int) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteOldRevisionsByPostId, post_id, post_id, keep)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// DeleteUserById removes the user and its role. All posts of the user are handed over to the user with the id newAuthorId.
+func DeleteUserById(id int64, newAuthorId int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtReassignPostsByAuthorId, newAuthorId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRolesUsersByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSessionsByUserId, id, 0)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteTwoFactorByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRecoveryCodesByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePasswordResetsByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteApiTokensByUserId, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteUserById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteInviteById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteInviteById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteApiKeyById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteApiKeyById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteSessionById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSessionById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteSessionByTokenHash(tokenHash string) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSessionByTokenHash, tokenHash)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteExpiredSessions(now time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteExpiredSessions, now)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// DeleteSessionsByUserId deletes all sessions of the user except the session with the id exceptId (0 deletes all sessions).
+func DeleteSessionsByUserId(user_id int64, exceptId int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteSessionsByUserId, user_id, exceptId)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteLoginAttemptsBefore(before time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteLoginAttemptsBefore, before)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// DeleteTwoFactorByUserId turns off two-factor authentication for the user and removes the recovery codes.
+func DeleteTwoFactorByUserId(user_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteTwoFactorByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRecoveryCodesByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteTwoFactorChallengeByTokenHash(tokenHash string) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteTwoFactorChallengeByTokenHash, tokenHash)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteExpiredTwoFactorChallenges(now time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteExpiredTwoFactorChallenges, now)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteApiTokenById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteApiTokenById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeleteRedirectById(id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRedirectById, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// DeleteRedirectByFromPath removes the redirect with the given path and match type (e.g. because there is content at that path again).
+func DeleteRedirectByFromPath(from_path string, matchType string) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRedirectByFromPath, from_path, matchType)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func DeletePostPreviewByPostId(post_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePostPreviewByPostId, post_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
 	return writeDB.Commit()
 }
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+
+	"journey/filter"
+)
+
+// Sql conditions for the filter keys supported by the content api. Each ? is replaced by the list of values of the condition.
+// Internal tags (names starting with #) can't be used to filter posts. The conditions aren't format strings, so % isn't escaped.
+var postFilterColumns = map[string]string{
+	"id":       "posts.id IN (?)",
+	"slug":     "posts.slug IN (?)",
+	"featured": "posts.featured IN (?)",
+	"tag":      "posts.id IN (SELECT posts_tags.post_id FROM posts_tags, tags WHERE posts_tags.tag_id = tags.id AND tags.slug IN (?) AND CAST(tags.name AS TEXT) NOT LIKE '#%')",
+	"tags":     "posts.id IN (SELECT posts_tags.post_id FROM posts_tags, tags WHERE posts_tags.tag_id = tags.id AND tags.slug IN (?) AND CAST(tags.name AS TEXT) NOT LIKE '#%')",
+	"author":   "posts.author_id IN (SELECT users.id FROM users WHERE users.slug IN (?))",
+	"authors":  "posts.author_id IN (SELECT users.id FROM users WHERE users.slug IN (?))",
+}
+var tagFilterColumns = map[string]string{
+	"id":   "tags.id IN (?)",
+	"slug": "tags.slug IN (?)",
+}
+var userFilterColumns = map[string]string{
+	"id":   "users.id IN (?)",
+	"slug": "users.slug IN (?)",
+}
+
+// Filter keys of the audit log in the admin api
+var auditFilterColumns = map[string]string{
+	"actor":     "audit_log.user_name IN (?)",
+	"action":    "audit_log.action IN (?)",
+	"target":    "audit_log.target_type IN (?)",
+	"target_id": "audit_log.target_id IN (?)",
+	"ip":        "audit_log.ip_address IN (?)",
+}
+
+// filterClause translates the conditions of a content api filter into an sql expression (starting with AND) and its arguments.
+func filterClause(conditions []filter.Condition, columns map[string]string) (string, []interface{}, error) {
+	var buffer strings.Builder
+	arguments := make([]interface{}, 0)
+	for _, condition := range conditions {
+		column, ok := columns[condition.Key]
+		if !ok {
+			return "", nil, filter.ErrUnsupportedKey
+		}
+		for _, value := range condition.Values {
+			argument, err := filterArgument(condition.Key, value)
+			if err != nil {
+				return "", nil, err
+			}
+			arguments = append(arguments, argument)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(condition.Values)), ", ")
+		buffer.WriteString(" AND ")
+		if condition.Negate {
+			buffer.WriteString("NOT ")
+		}
+		buffer.WriteString(strings.Replace(column, "?", placeholders, 1))
+	}
+	return buffer.String(), arguments, nil
+}
+
+func filterArgument(key string, value string) (interface{}, error) {
+	switch key {
+	case "id", "target_id":
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, filter.ErrInvalidFilter
+		}
+		return id, nil
+	case "featured":
+		featured, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, filter.ErrInvalidFilter
+		}
+		return featured, nil
+	}
+	return value, nil
+}
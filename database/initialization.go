@@ -31,13 +31,24 @@ var stmtInitialization = `CREATE TABLE IF NOT EXISTS
 		language			varchar(6) NOT NULL DEFAULT 'en_US',
 		meta_title			varchar(150),
 		meta_description	varchar(200),
+		custom_excerpt		text,
+		canonical_url		text,
+		og_title			varchar(300),
+		og_description		varchar(500),
+		og_image			text,
+		twitter_title		varchar(300),
+		twitter_description	varchar(500),
+		twitter_image		text,
+		codeinjection_head	text,
+		codeinjection_foot	text,
 		author_id			integer NOT NULL,
 		created_at			datetime NOT NULL,
 		created_by			integer NOT NULL,
 		updated_at			datetime,
 		updated_by			integer,
 		published_at		datetime,
-		published_by		integer
+		published_by		integer,
+		deleted_at			datetime
 	);
 	CREATE TABLE IF NOT EXISTS
 	users (
@@ -70,6 +81,7 @@ var stmtInitialization = `CREATE TABLE IF NOT EXISTS
 		name				varchar(150) NOT NULL,
 		slug				varchar(150) NOT NULL,
 		description			varchar(200),
+		image				text,
 		parent_id			integer,
 		meta_title			varchar(150),
 		meta_description	varchar(200),
@@ -125,8 +137,207 @@ var stmtInitialization = `CREATE TABLE IF NOT EXISTS
 		role_id	integer NOT NULL,
 		user_id	integer NOT NULL
 	);
+	CREATE TABLE IF NOT EXISTS
+	invites (
+		id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		uuid		varchar(36) NOT NULL,
+		token_hash	varchar(64) NOT NULL,
+		email		varchar(254) NOT NULL,
+		role_id		integer NOT NULL,
+		status		varchar(150) NOT NULL DEFAULT 'pending',
+		expires_at	datetime NOT NULL,
+		created_at	datetime NOT NULL,
+		created_by	integer NOT NULL,
+		updated_at	datetime,
+		updated_by	integer
+	);
+	CREATE TABLE IF NOT EXISTS
+	post_revisions (
+		id					integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		post_id				integer NOT NULL,
+		title				varchar(150) NOT NULL,
+		markdown			text,
+		tags				text,
+		meta_description	varchar(200),
+		image				text,
+		featured			tinyint NOT NULL DEFAULT '0',
+		page				tinyint NOT NULL DEFAULT '0',
+		created_at			datetime NOT NULL,
+		created_by			integer NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS post_revisions_post_id ON post_revisions (post_id);
+	CREATE TABLE IF NOT EXISTS
+	api_keys (
+		id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		uuid		varchar(36) NOT NULL,
+		name		varchar(150) NOT NULL,
+		secret		varchar(64) NOT NULL UNIQUE,
+		created_at	datetime NOT NULL,
+		created_by	integer NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS
+	sessions (
+		id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		token_hash		varchar(64) NOT NULL UNIQUE,
+		user_id			integer NOT NULL,
+		remember		tinyint NOT NULL DEFAULT '0',
+		user_agent		text,
+		ip_address		varchar(45),
+		created_at		datetime NOT NULL,
+		last_seen_at	datetime NOT NULL,
+		expires_at		datetime NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS sessions_user_id ON sessions (user_id);
+	CREATE TABLE IF NOT EXISTS
+	login_attempts (
+		id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		user_name	varchar(150) NOT NULL,
+		ip_address	varchar(45) NOT NULL,
+		successful	tinyint NOT NULL DEFAULT '0',
+		created_at	datetime NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS login_attempts_user_name ON login_attempts (user_name);
+	CREATE INDEX IF NOT EXISTS login_attempts_ip_address ON login_attempts (ip_address);
+	CREATE TABLE IF NOT EXISTS
+	lockouts (
+		id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		user_name		varchar(150),
+		ip_address		varchar(45),
+		failures		integer NOT NULL,
+		locked_until	datetime NOT NULL,
+		created_at		datetime NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS
+	two_factor (
+		user_id		integer NOT NULL PRIMARY KEY,
+		secret		varchar(64) NOT NULL,
+		enabled		tinyint NOT NULL DEFAULT '0',
+		last_step	integer NOT NULL DEFAULT '0',
+		created_at	datetime NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS
+	recovery_codes (
+		id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		user_id		integer NOT NULL,
+		code_hash	varchar(64) NOT NULL,
+		used_at		datetime
+	);
+	CREATE TABLE IF NOT EXISTS
+	two_factor_challenges (
+		id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		token_hash	varchar(64) NOT NULL UNIQUE,
+		user_id		integer NOT NULL,
+		remember	tinyint NOT NULL DEFAULT '0',
+		secret		varchar(64) NOT NULL DEFAULT '',
+		expires_at	datetime NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS
+	password_resets (
+		id			integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		token_hash	varchar(64) NOT NULL UNIQUE,
+		user_id		integer NOT NULL,
+		created_at	datetime NOT NULL,
+		expires_at	datetime NOT NULL,
+		used_at		datetime
+	);
+	CREATE TABLE IF NOT EXISTS
+	api_tokens (
+		id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		user_id			integer NOT NULL,
+		name			varchar(150) NOT NULL,
+		token_hash		varchar(64) NOT NULL UNIQUE,
+		scope			varchar(150) NOT NULL DEFAULT 'read',
+		created_at		datetime NOT NULL,
+		last_used_at	datetime
+	);
+	CREATE TABLE IF NOT EXISTS
+	audit_log (
+		id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		user_id			integer NOT NULL DEFAULT '0',
+		user_name		varchar(150) NOT NULL DEFAULT '',
+		action			varchar(150) NOT NULL,
+		target_type		varchar(150) NOT NULL DEFAULT '',
+		target_id		integer NOT NULL DEFAULT '0',
+		target_name		varchar(150) NOT NULL DEFAULT '',
+		summary_before	text NOT NULL DEFAULT '',
+		summary_after	text NOT NULL DEFAULT '',
+		ip_address		varchar(45) NOT NULL DEFAULT '',
+		created_at		datetime NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS audit_log_created_at ON audit_log (created_at);
+	CREATE TABLE IF NOT EXISTS
+	redirects (
+		id				integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+		from_path		text NOT NULL,
+		to_path			text NOT NULL,
+		match_type		varchar(10) NOT NULL DEFAULT 'exact',
+		automatic		tinyint NOT NULL DEFAULT '0',
+		created_at		datetime NOT NULL,
+		created_by		integer NOT NULL
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS redirects_from_path ON redirects (from_path, match_type);
+	CREATE TABLE IF NOT EXISTS
+	post_previews (
+		post_id			integer NOT NULL PRIMARY KEY,
+		expires_at		datetime,
+		created_at		datetime NOT NULL,
+		created_by		integer NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS
+	post_autosaves (
+		post_id				integer NOT NULL,
+		user_id				integer NOT NULL,
+		title				varchar(150) NOT NULL,
+		markdown			text,
+		tags				text,
+		meta_description	varchar(200),
+		image				text,
+		featured			tinyint NOT NULL DEFAULT '0',
+		page				tinyint NOT NULL DEFAULT '0',
+		base_version		datetime,
+		created_at			datetime NOT NULL,
+		PRIMARY KEY (post_id, user_id)
+	);
 	`
 
+// Full-text search index over the title and markdown of all posts. Triggers keep it in sync with the posts table.
+const stmtInitializationSearch = `CREATE VIRTUAL TABLE IF NOT EXISTS
+	posts_fts USING fts5(title, markdown, content='posts', content_rowid='id');
+	CREATE TRIGGER IF NOT EXISTS posts_fts_insert AFTER INSERT ON posts BEGIN
+		INSERT INTO posts_fts (rowid, title, markdown) VALUES (new.id, new.title, new.markdown);
+	END;
+	CREATE TRIGGER IF NOT EXISTS posts_fts_delete AFTER DELETE ON posts BEGIN
+		INSERT INTO posts_fts (posts_fts, rowid, title, markdown) VALUES ('delete', old.id, old.title, old.markdown);
+	END;
+	CREATE TRIGGER IF NOT EXISTS posts_fts_update AFTER UPDATE OF title, markdown ON posts BEGIN
+		INSERT INTO posts_fts (posts_fts, rowid, title, markdown) VALUES ('delete', old.id, old.title, old.markdown);
+		INSERT INTO posts_fts (rowid, title, markdown) VALUES (new.id, new.title, new.markdown);
+	END;
+	`
+const stmtRebuildSearchIndex = "INSERT INTO posts_fts (posts_fts) VALUES ('rebuild')"
+const stmtRetrieveTableCount = "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+const stmtRetrieveColumnCount = "SELECT count(*) FROM pragma_table_info(?) WHERE name = ?"
+
+// Columns that were added to existing tables in later versions. Databases created before (or converted from Ghost) get them on startup.
+var addedColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"posts", "deleted_at", "datetime"},
+	{"posts", "custom_excerpt", "text"},
+	{"posts", "canonical_url", "text"},
+	{"posts", "og_title", "varchar(300)"},
+	{"posts", "og_description", "varchar(500)"},
+	{"posts", "og_image", "text"},
+	{"posts", "twitter_title", "varchar(300)"},
+	{"posts", "twitter_description", "varchar(500)"},
+	{"posts", "twitter_image", "text"},
+	{"posts", "codeinjection_head", "text"},
+	{"posts", "codeinjection_foot", "text"},
+	{"tags", "image", "text"},
+}
+
 func Initialize() error {
 	// If journey.db does not exist, look for a Ghost database to convert
 	if !helpers.FileExists(filenames.DatabaseFilename) {
@@ -150,6 +361,14 @@ func Initialize() error {
 	if err != nil {
 		return err
 	}
+	err = addMissingColumns()
+	if err != nil {
+		return err
+	}
+	err = initializeSearch()
+	if err != nil {
+		return err
+	}
 	err = checkBlogSettings()
 	if err != nil {
 		return err
@@ -157,6 +376,46 @@ func Initialize() error {
 	return nil
 }
 
+// Function to add the columns of newer versions to the tables of an older database.
+func addMissingColumns() error {
+	for _, added := range addedColumns {
+		var count int
+		row := readDB.QueryRow(stmtRetrieveColumnCount, added.table, added.column)
+		err := row.Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			_, err = readDB.Exec("ALTER TABLE " + added.table + " ADD COLUMN " + added.column + " " + added.definition)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Function to create the full-text search index. If the index is new, all existing posts are added to it.
+func initializeSearch() error {
+	var count int
+	row := readDB.QueryRow(stmtRetrieveTableCount, "posts_fts")
+	err := row.Scan(&count)
+	if err != nil {
+		return err
+	}
+	_, err = readDB.Exec(stmtInitializationSearch)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		_, err = readDB.Exec(stmtRebuildSearchIndex)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Function to check and insert any missing blog settings into the database (settings could be missing if migrating from Ghost).
 func checkBlogSettings() error {
 	tempBlog := structure.Blog{}
@@ -231,6 +490,37 @@ func checkBlogSettings() error {
 			return err
 		}
 	}
+	// Check for two-factor requirement
+	var requireTwoFactor []byte
+	row = readDB.QueryRow(stmtRetrieveBlog, "requireTwoFactor")
+	err = row.Scan(&requireTwoFactor)
+	if err != nil {
+		// Insert two-factor requirement
+		err = insertSettingString("requireTwoFactor", "false", "blog", date.GetCurrentTime(), 1)
+		if err != nil {
+			return err
+		}
+	}
+	// Check for code injection
+	var codeInjection []byte
+	row = readDB.QueryRow(stmtRetrieveBlog, "codeinjection_head")
+	err = row.Scan(&codeInjection)
+	if err != nil {
+		// Insert code injection for the head
+		err = insertSettingString("codeinjection_head", "", "blog", date.GetCurrentTime(), 1)
+		if err != nil {
+			return err
+		}
+	}
+	row = readDB.QueryRow(stmtRetrieveBlog, "codeinjection_foot")
+	err = row.Scan(&codeInjection)
+	if err != nil {
+		// Insert code injection for the foot
+		err = insertSettingString("codeinjection_foot", "", "blog", date.GetCurrentTime(), 1)
+		if err != nil {
+			return err
+		}
+	}
 	// Check for navigation
 	var navigation []byte
 	row = readDB.QueryRow(stmtRetrieveBlog, "navigation")
@@ -4,30 +4,44 @@ import (
 	"database/sql"
 	"time"
 
+	"journey/structure"
+
 	uuid "github.com/satori/go.uuid"
 )
 
 const stmtInsertPost = "INSERT INTO posts (id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, created_at, created_by, updated_at, updated_by, published_at, published_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 const stmtInsertUser = "INSERT INTO users (id, uuid, name, slug, password, email, image, cover, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 const stmtInsertRoleUser = "INSERT INTO roles_users (id, role_id, user_id) VALUES (?, ?, ?)"
-const stmtInsertTag = "INSERT INTO tags (id, uuid, name, slug, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertTag = "INSERT INTO tags (id, uuid, name, slug, parent_id, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, NULLIF(?, 0), ?, ?, ?, ?)"
 const stmtInsertPostTag = "INSERT INTO posts_tags (id, post_id, tag_id) VALUES (?, ?, ?)"
+const stmtInsertSession = "INSERT INTO sessions (id, token_hash, user_id, remember, user_agent, ip_address, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertLoginAttempt = "INSERT INTO login_attempts (id, user_name, ip_address, successful, created_at) VALUES (?, ?, ?, ?, ?)"
+const stmtInsertLockout = "INSERT INTO lockouts (id, user_name, ip_address, failures, locked_until, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+const stmtInsertTwoFactor = "INSERT OR REPLACE INTO two_factor (user_id, secret, enabled, last_step, created_at) VALUES (?, ?, 0, 0, ?)"
+const stmtInsertRecoveryCode = "INSERT INTO recovery_codes (id, user_id, code_hash, used_at) VALUES (?, ?, ?, NULL)"
+const stmtInsertTwoFactorChallenge = "INSERT INTO two_factor_challenges (id, token_hash, user_id, remember, secret, expires_at) VALUES (?, ?, ?, ?, ?, ?)"
+const stmtInsertPasswordReset = "INSERT INTO password_resets (id, token_hash, user_id, created_at, expires_at, used_at) VALUES (?, ?, ?, ?, ?, NULL)"
+const stmtInsertApiToken = "INSERT INTO api_tokens (id, user_id, name, token_hash, scope, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, NULL)"
+const stmtInsertRedirect = "INSERT INTO redirects (id, from_path, to_path, match_type, automatic, created_at, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertPostPreview = "INSERT OR REPLACE INTO post_previews (post_id, expires_at, created_at, created_by) VALUES (?, ?, ?, ?)"
+const stmtInsertAutosave = "INSERT OR REPLACE INTO post_autosaves (post_id, user_id, title, markdown, tags, meta_description, image, featured, page, base_version, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertAuditEntry = "INSERT INTO audit_log (id, user_id, user_name, action, target_type, target_id, target_name, summary_before, summary_after, ip_address, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertApiKey = "INSERT INTO api_keys (id, uuid, name, secret, created_at, created_by) VALUES (?, ?, ?, ?, ?, ?)"
+const stmtInsertInvite = "INSERT INTO invites (id, uuid, token_hash, email, role_id, status, expires_at, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+const stmtInsertRevision = "INSERT INTO post_revisions (id, post_id, title, markdown, tags, meta_description, image, featured, page, created_at, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 const stmtInsertSetting = "INSERT INTO settings (id, uuid, key, value, type, created_at, created_by, updated_at, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
 
-func InsertPost(title []byte, slug string, markdown []byte, html []byte, featured bool, isPage bool, published bool, meta_description []byte, image []byte, created_at time.Time, created_by int64) (int64, error) {
+func InsertPost(title []byte, slug string, markdown []byte, html []byte, featured bool, isPage bool, published bool, scheduled bool, meta_description []byte, image []byte, published_at time.Time, created_at time.Time, created_by int64) (int64, error) {
 
-	status := "draft"
-	if published {
-		status = "published"
-	}
+	status := postStatus(published, scheduled)
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
 	var result sql.Result
-	if published {
-		result, err = writeDB.Exec(stmtInsertPost, nil, uuid.NewV4().String(), title, slug, markdown, html, featured, isPage, status, meta_description, image, created_by, created_at, created_by, created_at, created_by, created_at, created_by)
+	if published || scheduled {
+		result, err = writeDB.Exec(stmtInsertPost, nil, uuid.NewV4().String(), title, slug, markdown, html, featured, isPage, status, meta_description, image, created_by, created_at, created_by, created_at, created_by, published_at, created_by)
 	} else {
 		result, err = writeDB.Exec(stmtInsertPost, nil, uuid.NewV4().String(), title, slug, markdown, html, featured, isPage, status, meta_description, image, created_by, created_at, created_by, created_at, created_by, nil, nil)
 	}
@@ -62,6 +76,42 @@ func InsertUser(name []byte, slug string, password string, email []byte, image [
 	return userId, writeDB.Commit()
 }
 
+// InsertInvitedUser marks the invite as accepted and creates the user with the role of the invite. Returns false if the invite isn't pending anymore,
+// so every invite creates one user at most.
+func InsertInvitedUser(invite_id int64, role_id int, name []byte, slug string, password string, email []byte, image []byte, cover []byte, created_at time.Time, created_by int64) (int64, bool, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, false, err
+	}
+	result, err := writeDB.Exec(stmtUpdateInviteAccepted, created_at, created_by, invite_id)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil || rowsAffected != 1 {
+		writeDB.Rollback()
+		return 0, false, err
+	}
+	result, err = writeDB.Exec(stmtInsertUser, nil, uuid.NewV4().String(), name, slug, password, email, image, cover, created_at, created_by, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, false, err
+	}
+	userId, err := result.LastInsertId()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, false, err
+	}
+	_, err = writeDB.Exec(stmtInsertRoleUser, nil, role_id, userId)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, false, err
+	}
+	return userId, true, writeDB.Commit()
+}
+
 func InsertRoleUser(role_id int, user_id int64) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
@@ -76,13 +126,13 @@ func InsertRoleUser(role_id int, user_id int64) error {
 	return writeDB.Commit()
 }
 
-func InsertTag(name []byte, slug string, created_at time.Time, created_by int64) (int64, error) {
+func InsertTag(name []byte, slug string, parent_id int64, created_at time.Time, created_by int64) (int64, error) {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
 	}
-	result, err := writeDB.Exec(stmtInsertTag, nil, uuid.NewV4().String(), name, slug, created_at, created_by, created_at, created_by)
+	result, err := writeDB.Exec(stmtInsertTag, nil, uuid.NewV4().String(), name, slug, parent_id, created_at, created_by, created_at, created_by)
 	if err != nil {
 		writeDB.Rollback()
 		return 0, err
@@ -109,6 +159,215 @@ func InsertPostTag(post_id int64, tag_id int64) error {
 	return writeDB.Commit()
 }
 
+func InsertInvite(tokenHash string, email []byte, role_id int, expires_at time.Time, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	result, err := writeDB.Exec(stmtInsertInvite, nil, uuid.NewV4().String(), tokenHash, email, role_id, "pending", expires_at, created_at, created_by, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	inviteId, err := result.LastInsertId()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return inviteId, writeDB.Commit()
+}
+
+func InsertSession(tokenHash string, user_id int64, remember bool, userAgent string, ipAddress string, created_at time.Time, expires_at time.Time) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	result, err := writeDB.Exec(stmtInsertSession, nil, tokenHash, user_id, remember, userAgent, ipAddress, created_at, created_at, expires_at)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	sessionId, err := result.LastInsertId()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return sessionId, writeDB.Commit()
+}
+
+func InsertLoginAttempt(userName string, ipAddress string, successful bool, created_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertLoginAttempt, nil, userName, ipAddress, successful, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// InsertLockout records that an account (userName) or an ip address (ipAddress) was locked. The other one is empty.
+func InsertLockout(userName string, ipAddress string, failures int64, locked_until time.Time, created_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertLockout, nil, userName, ipAddress, failures, locked_until, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// InsertTwoFactor stores a new (not yet enabled) two-factor secret for the user. An existing secret is replaced.
+func InsertTwoFactor(user_id int64, secret string, created_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertTwoFactor, user_id, secret, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// InsertRecoveryCodes replaces all recovery codes of the user.
+func InsertRecoveryCodes(user_id int64, codeHashes []string) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeleteRecoveryCodesByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		_, err = writeDB.Exec(stmtInsertRecoveryCode, nil, user_id, codeHash)
+		if err != nil {
+			writeDB.Rollback()
+			return err
+		}
+	}
+	return writeDB.Commit()
+}
+
+func InsertTwoFactorChallenge(tokenHash string, user_id int64, remember bool, secret string, expires_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertTwoFactorChallenge, nil, tokenHash, user_id, remember, secret, expires_at)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// InsertPasswordReset stores a new reset token for the user. Older tokens of the user become invalid.
+func InsertPasswordReset(tokenHash string, user_id int64, created_at time.Time, expires_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtDeletePasswordResetsByUserId, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertPasswordReset, nil, tokenHash, user_id, created_at, expires_at)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func InsertApiToken(user_id int64, name []byte, tokenHash string, scope string, created_at time.Time) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	result, err := writeDB.Exec(stmtInsertApiToken, nil, user_id, name, tokenHash, scope, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	apiTokenId, err := result.LastInsertId()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return apiTokenId, writeDB.Commit()
+}
+
+func InsertAuditEntry(user_id int64, userName string, action string, targetType string, target_id int64, targetName string, summaryBefore string, summaryAfter string, ipAddress string, created_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertAuditEntry, nil, user_id, userName, action, targetType, target_id, targetName, summaryBefore, summaryAfter, ipAddress, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func InsertApiKey(name []byte, secret string, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	result, err := writeDB.Exec(stmtInsertApiKey, nil, uuid.NewV4().String(), name, secret, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	apiKeyId, err := result.LastInsertId()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return apiKeyId, writeDB.Commit()
+}
+
+func InsertRevision(post_id int64, title []byte, markdown []byte, tags []byte, meta_description []byte, image []byte, featured bool, isPage bool, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	result, err := writeDB.Exec(stmtInsertRevision, nil, post_id, title, markdown, tags, meta_description, image, featured, isPage, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	revisionId, err := result.LastInsertId()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return revisionId, writeDB.Commit()
+}
+
 func insertSettingString(key string, value string, setting_type string, created_at time.Time, created_by int64) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
@@ -136,3 +395,74 @@ func insertSettingInt64(key string, value int64, setting_type string, created_at
 	}
 	return writeDB.Commit()
 }
+
+// InsertRedirect replaces the redirect with the same path and match type. Exact redirects that pointed to from_path are changed to point
+// to to_path, so that chains of redirects collapse to a single hop. Redirects that would point to themselves afterwards are removed.
+func InsertRedirect(from_path string, to_path string, matchType string, automatic bool, created_at time.Time, created_by int64) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	_, err = writeDB.Exec(stmtDeleteRedirectByFromPath, from_path, matchType)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	// Redirects to the old path go straight to the new one. A prefix redirect also covers redirects to paths below the old path.
+	if matchType == structure.RedirectMatchPrefix {
+		_, err = writeDB.Exec(stmtUpdateRedirectTargetsByPrefix, to_path, from_path, from_path, from_path)
+	} else {
+		_, err = writeDB.Exec(stmtUpdateRedirectTargets, to_path, from_path)
+	}
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	_, err = writeDB.Exec(stmtDeleteRedirectLoops)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	result, err := writeDB.Exec(stmtInsertRedirect, nil, from_path, to_path, matchType, automatic, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	redirectId, err := result.LastInsertId()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return redirectId, writeDB.Commit()
+}
+
+// InsertPostPreview enables the preview link of the post. An existing preview link of the post is replaced.
+func InsertPostPreview(post_id int64, expires_at *time.Time, created_at time.Time, created_by int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertPostPreview, post_id, expires_at, created_at, created_by)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// InsertAutosave stores the unsaved changes of the user to the post. An older autosave of the same user and post is replaced.
+func InsertAutosave(post_id int64, user_id int64, title []byte, markdown []byte, tags []byte, meta_description []byte, image []byte, featured bool, isPage bool, base_version *time.Time, created_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtInsertAutosave, post_id, user_id, title, markdown, tags, meta_description, image, featured, isPage, base_version, created_at)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
@@ -3,30 +3,93 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"journey/conversion"
+	"journey/filter"
 	"journey/structure"
 	"time"
 )
 
-const stmtRetrievePostsCount = "SELECT count(*) FROM posts WHERE page = 0 AND status = 'published'"
-const stmtRetrievePostsCountByUser = "SELECT count(*) FROM posts WHERE page = 0 AND status = 'published' AND author_id = ?"
-const stmtRetrievePostsCountByTag = "SELECT count(*) FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = ? AND page = 0 AND status = 'published'"
-const stmtRetrievePostsForIndex = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts WHERE page = 0 AND status = 'published' ORDER BY published_at DESC LIMIT ? OFFSET ?"
-const stmtRetrievePostsForApi = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts ORDER BY id DESC LIMIT ? OFFSET ?"
-const stmtRetrievePostsByUser = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts WHERE page = 0 AND status = 'published' AND author_id = ? ORDER BY published_at DESC LIMIT ? OFFSET ?"
-const stmtRetrievePostsByTag = "SELECT posts.id, posts.uuid, posts.title, posts.slug, posts.markdown, posts.html, posts.featured, posts.page, posts.status, posts.meta_description, posts.image, posts.author_id, posts.published_at FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = ? AND page = 0 AND status = 'published' ORDER BY posts.published_at DESC LIMIT ? OFFSET ?"
-const stmtRetrievePostById = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts WHERE id = ?"
-const stmtRetrievePostBySlug = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, image, author_id, published_at FROM posts WHERE slug = ? COLLATE NOCASE"
-const stmtRetrieveUserById = "SELECT id, name, slug, email, image, cover, bio, website, location FROM users WHERE id = ?"
-const stmtRetrieveUserBySlug = "SELECT id, name, slug, email, image, cover, bio, website, location FROM users WHERE slug = ? COLLATE NOCASE"
-const stmtRetrieveUserByName = "SELECT id, name, slug, email, image, cover, bio, website, location FROM users WHERE name = ? "
+const stmtRetrievePostsCount = "SELECT count(*) FROM posts WHERE page = 0 AND status = 'published' AND deleted_at IS NULL"
+const stmtRetrievePostsCountByUser = "SELECT count(*) FROM posts WHERE page = 0 AND status = 'published' AND author_id = ? AND deleted_at IS NULL"
+const stmtRetrievePostsCountByTag = "SELECT count(*) FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = ? AND page = 0 AND status = 'published' AND deleted_at IS NULL"
+const stmtRetrievePostsForIndex = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE page = 0 AND status = 'published' AND deleted_at IS NULL ORDER BY published_at DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsForApi = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE deleted_at IS NULL ORDER BY id DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsForApiByUser = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE author_id = ? AND deleted_at IS NULL ORDER BY id DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsForApiByTag = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE id IN (SELECT posts_tags.post_id FROM posts_tags, tags WHERE posts_tags.tag_id = tags.id AND tags.slug = ? COLLATE NOCASE) AND deleted_at IS NULL AND (? = 0 OR author_id = ?) ORDER BY id DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsByUser = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE page = 0 AND status = 'published' AND author_id = ? AND deleted_at IS NULL ORDER BY published_at DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsByTag = "SELECT posts.id, posts.uuid, posts.title, posts.slug, posts.markdown, posts.html, posts.featured, posts.page, posts.status, posts.meta_description, posts.meta_title, posts.custom_excerpt, posts.canonical_url, posts.og_title, posts.og_description, posts.og_image, posts.twitter_title, posts.twitter_description, posts.twitter_image, posts.codeinjection_head, posts.codeinjection_foot, posts.image, posts.author_id, posts.published_at, posts.deleted_at, posts.updated_at FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = ? AND page = 0 AND status = 'published' AND posts.deleted_at IS NULL ORDER BY posts.published_at DESC LIMIT ? OFFSET ?"
+const stmtWithTagTree = "WITH RECURSIVE tag_tree(id) AS (SELECT ? UNION SELECT tags.id FROM tags, tag_tree WHERE tags.parent_id = tag_tree.id AND CAST(tags.name AS TEXT) NOT LIKE '#%') " // The tag and all public tags below it
+const stmtRetrievePostsByTagTree = stmtWithTagTree + "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE id IN (SELECT post_id FROM posts_tags WHERE tag_id IN (SELECT id FROM tag_tree)) AND page = 0 AND status = 'published' AND deleted_at IS NULL ORDER BY published_at DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsCountByTagTree = stmtWithTagTree + "SELECT count(*) FROM posts WHERE id IN (SELECT post_id FROM posts_tags WHERE tag_id IN (SELECT id FROM tag_tree)) AND page = 0 AND status = 'published' AND deleted_at IS NULL"
+const stmtRetrievePostById = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE id = ?"
+const stmtRetrievePostByUuid = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE uuid = ?"
+const stmtRetrievePostBySlug = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE slug = ? COLLATE NOCASE"
+const stmtRetrieveUserById = "SELECT id, name, slug, email, image, cover, bio, website, location, status, last_login, IFNULL((SELECT role_id FROM roles_users WHERE roles_users.user_id = users.id ORDER BY roles_users.id DESC LIMIT 1), 3) FROM users WHERE id = ?"
+const stmtRetrieveUserBySlug = "SELECT id, name, slug, email, image, cover, bio, website, location, status, last_login, IFNULL((SELECT role_id FROM roles_users WHERE roles_users.user_id = users.id ORDER BY roles_users.id DESC LIMIT 1), 3) FROM users WHERE slug = ? COLLATE NOCASE"
+const stmtRetrieveUserByEmail = "SELECT id, name, slug, email, image, cover, bio, website, location, status, last_login, IFNULL((SELECT role_id FROM roles_users WHERE roles_users.user_id = users.id ORDER BY roles_users.id DESC LIMIT 1), 3) FROM users WHERE email = ? COLLATE NOCASE"
+const stmtRetrieveUserByName = "SELECT id, name, slug, email, image, cover, bio, website, location, status, last_login, IFNULL((SELECT role_id FROM roles_users WHERE roles_users.user_id = users.id ORDER BY roles_users.id DESC LIMIT 1), 3) FROM users WHERE name = ? "
 const stmtRetrieveTags = "SELECT tag_id FROM posts_tags WHERE post_id = ?"
-const stmtRetrieveTagById = "SELECT id, name, slug FROM tags WHERE id = ?"
-const stmtRetrieveTagBySlug = "SELECT id, name, slug FROM tags WHERE slug = ? COLLATE NOCASE"
+const stmtRetrieveTagById = "SELECT id, name, slug, description, image, meta_title, meta_description, IFNULL(parent_id, 0) FROM tags WHERE id = ?"
+const stmtRetrieveTagBySlug = "SELECT id, name, slug, description, image, meta_title, meta_description, IFNULL(parent_id, 0) FROM tags WHERE slug = ? COLLATE NOCASE"
+const stmtRetrieveAllPostsCountByTag = "SELECT count(*) FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = ? AND deleted_at IS NULL"
+const stmtRetrieveTagsForAdmin = "SELECT id, name, slug, description, image, meta_title, meta_description, IFNULL(parent_id, 0), (SELECT count(*) FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = tags.id AND posts.deleted_at IS NULL) FROM tags ORDER BY name COLLATE NOCASE"
+const stmtRetrieveChildTags = "SELECT id, name, slug, description, image, meta_title, meta_description, IFNULL(parent_id, 0) FROM tags WHERE parent_id = ? ORDER BY name COLLATE NOCASE"
+const stmtRetrieveTagIdByNameAndParent = "SELECT id FROM tags WHERE name = ? COLLATE NOCASE AND IFNULL(parent_id, 0) = ? ORDER BY id LIMIT 1"
 const stmtRetrieveTagIdBySlug = "SELECT id FROM tags WHERE slug = ? COLLATE NOCASE"
 const stmtRetrieveHashedPasswordByName = "SELECT password FROM users WHERE name = ?"
 const stmtRetrieveUsersCount = "SELECT count(*) FROM users"
+const stmtRetrieveOwnerId = "SELECT user_id FROM roles_users WHERE role_id = 4 ORDER BY id ASC LIMIT 1"
+const stmtRetrieveInviteByTokenHash = "SELECT id, email, role_id, status, expires_at, created_at, created_by FROM invites WHERE token_hash = ?"
+const stmtRetrieveInviteById = "SELECT id, email, role_id, status, expires_at, created_at, created_by FROM invites WHERE id = ?"
+const stmtRetrievePendingInvites = "SELECT id, email, role_id, status, expires_at, created_at, created_by FROM invites WHERE status = 'pending' ORDER BY created_at DESC"
 const stmtRetrieveBlog = "SELECT value FROM settings WHERE key = ?"
+const stmtRetrieveRevisionsByPostId = "SELECT id, post_id, title, markdown, tags, meta_description, image, featured, page, created_at, created_by FROM post_revisions WHERE post_id = ? ORDER BY id DESC"
+const stmtRetrieveRevisionById = "SELECT id, post_id, title, markdown, tags, meta_description, image, featured, page, created_at, created_by FROM post_revisions WHERE id = ?"
+const stmtRetrieveRevisionsCountByPostId = "SELECT count(*) FROM post_revisions WHERE post_id = ?"
+const stmtRetrieveSearchResults = "SELECT posts_fts.rowid, snippet(posts_fts, 1, ?, ?, '...', 32) FROM posts_fts JOIN posts ON posts.id = posts_fts.rowid WHERE posts_fts MATCH ? AND posts.deleted_at IS NULL AND (? = 0 OR posts.status = 'published') AND (? = 0 OR posts.author_id = ?) ORDER BY bm25(posts_fts, 10.0, 1.0) LIMIT ? OFFSET ?"
+const stmtRetrieveSearchResultsCount = "SELECT count(*) FROM posts_fts JOIN posts ON posts.id = posts_fts.rowid WHERE posts_fts MATCH ? AND posts.deleted_at IS NULL AND (? = 0 OR posts.status = 'published') AND (? = 0 OR posts.author_id = ?)"
+const stmtRetrieveSitemapPosts = "SELECT slug, image, updated_at FROM posts WHERE page = ? AND status = 'published' AND deleted_at IS NULL ORDER BY published_at DESC"
+const stmtRetrieveSitemapTags = "SELECT tags.slug, NULL, posts.updated_at FROM tags, posts_tags, posts WHERE posts_tags.tag_id = tags.id AND posts_tags.post_id = posts.id AND CAST(tags.name AS TEXT) NOT LIKE '#%' AND posts.page = 0 AND posts.status = 'published' AND posts.deleted_at IS NULL GROUP BY tags.id HAVING posts.updated_at = max(posts.updated_at) ORDER BY tags.slug"
+const stmtRetrieveSitemapAuthors = "SELECT users.slug, NULL, posts.updated_at FROM users, posts WHERE posts.author_id = users.id AND posts.page = 0 AND posts.status = 'published' AND posts.deleted_at IS NULL GROUP BY users.id HAVING posts.updated_at = max(posts.updated_at) ORDER BY users.slug"
+const stmtRetrieveNextScheduledPostDate = "SELECT published_at FROM posts WHERE status = 'scheduled' AND deleted_at IS NULL ORDER BY published_at ASC LIMIT 1"
+const stmtRetrievePostsForContentApi = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE page = ? AND status = 'published' AND deleted_at IS NULL%s ORDER BY published_at DESC LIMIT ? OFFSET ?"
+const stmtRetrievePostsCountForContentApi = "SELECT count(*) FROM posts WHERE page = ? AND status = 'published' AND deleted_at IS NULL%s"
+const stmtRetrieveTagsForContentApi = "SELECT tags.id, tags.name, tags.slug, tags.description, tags.image, tags.meta_title, tags.meta_description, IFNULL(tags.parent_id, 0), (SELECT count(*) FROM posts, posts_tags WHERE posts_tags.post_id = posts.id AND posts_tags.tag_id = tags.id AND posts.page = 0 AND posts.status = 'published' AND posts.deleted_at IS NULL) FROM tags WHERE CAST(tags.name AS TEXT) NOT LIKE '#%%'%s ORDER BY tags.name COLLATE NOCASE LIMIT ? OFFSET ?"
+const stmtRetrieveTagsCountForContentApi = "SELECT count(*) FROM tags WHERE CAST(tags.name AS TEXT) NOT LIKE '#%%'%s"
+const stmtRetrieveAuthorsForContentApi = "SELECT id, name, slug, email, image, cover, bio, website, location, status, last_login, IFNULL((SELECT role_id FROM roles_users WHERE roles_users.user_id = users.id ORDER BY roles_users.id DESC LIMIT 1), 3), (SELECT count(*) FROM posts WHERE posts.author_id = users.id AND posts.page = 0 AND posts.status = 'published' AND posts.deleted_at IS NULL) AS post_count FROM users WHERE post_count > 0%s ORDER BY users.name COLLATE NOCASE LIMIT ? OFFSET ?"
+const stmtRetrieveAuthorsCountForContentApi = "SELECT count(*) FROM users WHERE EXISTS (SELECT 1 FROM posts WHERE posts.author_id = users.id AND posts.page = 0 AND posts.status = 'published' AND posts.deleted_at IS NULL)%s"
+const stmtRetrieveSessionByTokenHash = "SELECT sessions.id, sessions.user_id, users.name, sessions.remember, sessions.user_agent, sessions.ip_address, sessions.created_at, sessions.last_seen_at, sessions.expires_at FROM sessions JOIN users ON users.id = sessions.user_id WHERE sessions.token_hash = ?"
+const stmtRetrieveSessionById = "SELECT sessions.id, sessions.user_id, users.name, sessions.remember, sessions.user_agent, sessions.ip_address, sessions.created_at, sessions.last_seen_at, sessions.expires_at FROM sessions JOIN users ON users.id = sessions.user_id WHERE sessions.id = ?"
+const stmtRetrieveSessionsByUserId = "SELECT sessions.id, sessions.user_id, users.name, sessions.remember, sessions.user_agent, sessions.ip_address, sessions.created_at, sessions.last_seen_at, sessions.expires_at FROM sessions JOIN users ON users.id = sessions.user_id WHERE sessions.user_id = ? AND sessions.expires_at > ? ORDER BY sessions.last_seen_at DESC"
+const stmtRetrieveAllSessions = "SELECT sessions.id, sessions.user_id, users.name, sessions.remember, sessions.user_agent, sessions.ip_address, sessions.created_at, sessions.last_seen_at, sessions.expires_at FROM sessions JOIN users ON users.id = sessions.user_id WHERE sessions.expires_at > ? ORDER BY sessions.last_seen_at DESC"
+const stmtRetrieveLastSuccessfulLoginByName = "SELECT created_at FROM login_attempts WHERE user_name = ? AND successful = 1 ORDER BY created_at DESC LIMIT 1"
+const stmtRetrieveFailedLoginsCountByName = "SELECT count(*) FROM login_attempts WHERE user_name = ? AND successful = 0 AND created_at > ?"
+const stmtRetrieveFailedLoginsCountByIp = "SELECT count(*) FROM login_attempts WHERE ip_address = ? AND successful = 0 AND created_at > ?"
+const stmtRetrieveLastFailedLoginByName = "SELECT created_at FROM login_attempts WHERE user_name = ? AND successful = 0 ORDER BY created_at DESC LIMIT 1"
+const stmtRetrieveLastFailedLoginByIp = "SELECT created_at FROM login_attempts WHERE ip_address = ? AND successful = 0 ORDER BY created_at DESC LIMIT 1"
+const stmtRetrieveLockouts = "SELECT id, user_name, ip_address, failures, locked_until, created_at FROM lockouts ORDER BY id DESC LIMIT ?"
+const stmtRetrieveTwoFactorByUserId = "SELECT user_id, secret, enabled, last_step, created_at FROM two_factor WHERE user_id = ?"
+const stmtRetrieveUnusedRecoveryCodesCount = "SELECT count(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL"
+const stmtRetrieveTwoFactorChallengeByTokenHash = "SELECT id, user_id, remember, secret, expires_at FROM two_factor_challenges WHERE token_hash = ?"
+const stmtRetrievePasswordResetByTokenHash = "SELECT id, user_id, created_at, expires_at, used_at FROM password_resets WHERE token_hash = ?"
+const stmtRetrieveLastPasswordResetByUserId = "SELECT created_at FROM password_resets WHERE user_id = ? ORDER BY created_at DESC LIMIT 1"
+const stmtRetrieveApiTokenByTokenHash = "SELECT api_tokens.id, api_tokens.user_id, users.name, api_tokens.name, api_tokens.scope, api_tokens.created_at, api_tokens.last_used_at FROM api_tokens JOIN users ON users.id = api_tokens.user_id WHERE api_tokens.token_hash = ?"
+const stmtRetrieveApiTokenById = "SELECT api_tokens.id, api_tokens.user_id, users.name, api_tokens.name, api_tokens.scope, api_tokens.created_at, api_tokens.last_used_at FROM api_tokens JOIN users ON users.id = api_tokens.user_id WHERE api_tokens.id = ?"
+const stmtRetrieveApiTokensByUserId = "SELECT api_tokens.id, api_tokens.user_id, users.name, api_tokens.name, api_tokens.scope, api_tokens.created_at, api_tokens.last_used_at FROM api_tokens JOIN users ON users.id = api_tokens.user_id WHERE api_tokens.user_id = ? ORDER BY api_tokens.created_at DESC"
+const stmtRetrieveAuditEntries = "SELECT audit_log.id, audit_log.user_id, audit_log.user_name, audit_log.action, audit_log.target_type, audit_log.target_id, audit_log.target_name, audit_log.summary_before, audit_log.summary_after, audit_log.ip_address, audit_log.created_at FROM audit_log WHERE audit_log.created_at >= ? AND audit_log.created_at < ?%s ORDER BY audit_log.id DESC LIMIT ? OFFSET ?"
+const stmtRetrieveAuditEntriesCount = "SELECT count(*) FROM audit_log WHERE audit_log.created_at >= ? AND audit_log.created_at < ?%s"
+const stmtRetrieveRedirects = "SELECT id, from_path, to_path, match_type, automatic, created_at, created_by FROM redirects ORDER BY automatic ASC, from_path ASC"
+const stmtRetrieveRedirectByFromPath = "SELECT id, from_path, to_path, match_type, automatic, created_at, created_by FROM redirects WHERE from_path = ? AND match_type = ?"
+const stmtRetrieveRedirectByPrefix = "SELECT id, from_path, to_path, match_type, automatic, created_at, created_by FROM redirects WHERE match_type = 'prefix' AND substr(?, 1, length(from_path)) = from_path ORDER BY length(from_path) DESC LIMIT 1"
+const stmtRetrievePostPreviewByPostId = "SELECT post_id, expires_at, created_at, created_by FROM post_previews WHERE post_id = ?"
+const stmtRetrieveAutosave = "SELECT post_id, user_id, title, markdown, tags, meta_description, image, featured, page, base_version, created_at FROM post_autosaves WHERE post_id = ? AND user_id = ?"
+const stmtRetrieveApiKeys = "SELECT id, name, secret, created_at, created_by FROM api_keys ORDER BY id ASC"
+const stmtRetrieveApiKeyBySecret = "SELECT id, name, secret, created_at, created_by FROM api_keys WHERE secret = ?"
 const stmtRetrievePostCreationDateById = "SELECT created_at FROM posts WHERE id = ?"
+const stmtRetrieveTrashedPosts = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT ? OFFSET ?"
+const stmtRetrieveTrashedPostsByUser = "SELECT id, uuid, title, slug, markdown, html, featured, page, status, meta_description, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, image, author_id, published_at, deleted_at, updated_at FROM posts WHERE deleted_at IS NOT NULL AND author_id = ? ORDER BY deleted_at DESC LIMIT ? OFFSET ?"
+const stmtRetrieveTrashedPostIdsBefore = "SELECT id FROM posts WHERE deleted_at IS NOT NULL AND deleted_at < ?"
 
 func RetrievePostById(id int64) (*structure.Post, error) {
 	// Retrieve post
@@ -34,6 +97,11 @@ func RetrievePostById(id int64) (*structure.Post, error) {
 	return extractPost(row)
 }
 
+func RetrievePostByUuid(uuid string) (*structure.Post, error) {
+	row := readDB.QueryRow(stmtRetrievePostByUuid, uuid)
+	return extractPost(row)
+}
+
 func RetrievePostBySlug(slug string) (*structure.Post, error) {
 	// Retrieve post
 	row := readDB.QueryRow(stmtRetrievePostBySlug, slug)
@@ -68,6 +136,20 @@ func RetrievePostsByTag(tag_id int64, limit int64, offset int64) ([]structure.Po
 	return *posts, nil
 }
 
+// RetrievePostsByTagTree returns the published posts of the tag and of all tags below it. Posts with several of these tags are only returned once.
+func RetrievePostsByTagTree(tag_id int64, limit int64, offset int64) ([]structure.Post, error) {
+	rows, err := readDB.Query(stmtRetrievePostsByTagTree, tag_id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
 func RetrievePostsForIndex(limit int64, offset int64) ([]structure.Post, error) {
 	// Retrieve posts
 	rows, err := readDB.Query(stmtRetrievePostsForIndex, limit, offset)
@@ -96,13 +178,117 @@ func RetrievePostsForApi(limit int64, offset int64) ([]structure.Post, error) {
 	return *posts, nil
 }
 
+// RetrieveTrashedPosts returns the posts in the trash, most recently deleted first.
+func RetrieveTrashedPosts(limit int64, offset int64) ([]structure.Post, error) {
+	rows, err := readDB.Query(stmtRetrieveTrashedPosts, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+func RetrieveTrashedPostsByUser(user_id int64, limit int64, offset int64) ([]structure.Post, error) {
+	rows, err := readDB.Query(stmtRetrieveTrashedPostsByUser, user_id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+// RetrieveTrashedPostIdsBefore returns the ids of all posts that were moved to the trash before the given date.
+func RetrieveTrashedPostIdsBefore(deleted_at time.Time) ([]int64, error) {
+	postIds := make([]int64, 0)
+	rows, err := readDB.Query(stmtRetrieveTrashedPostIdsBefore, deleted_at)
+	if err != nil {
+		return postIds, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postId int64
+		err := rows.Scan(&postId)
+		if err != nil {
+			return postIds, err
+		}
+		postIds = append(postIds, postId)
+	}
+	return postIds, rows.Err()
+}
+
+func RetrievePostsForApiByUser(user_id int64, limit int64, offset int64) ([]structure.Post, error) {
+	// Retrieve posts
+	rows, err := readDB.Query(stmtRetrievePostsForApiByUser, user_id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+// RetrievePostsForApiByTag returns the posts with the tag (including drafts and internal tags), newest first. An author id other than 0 only returns the posts of the author.
+func RetrievePostsForApiByTag(tag_slug string, author_id int64, limit int64, offset int64) ([]structure.Post, error) {
+	rows, err := readDB.Query(stmtRetrievePostsForApiByTag, tag_slug, author_id, author_id, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+// RetrievePostsForContentApi returns the published posts (or pages) that match the filter conditions, newest first. A limit of -1 returns all posts.
+func RetrievePostsForContentApi(isPage bool, conditions []filter.Condition, limit int64, offset int64) ([]structure.Post, error) {
+	clause, arguments, err := filterClause(conditions, postFilterColumns)
+	if err != nil {
+		return nil, err
+	}
+	arguments = append([]interface{}{isPage}, arguments...)
+	arguments = append(arguments, limit, offset)
+	// Retrieve posts
+	rows, err := readDB.Query(fmt.Sprintf(stmtRetrievePostsForContentApi, clause), arguments...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts, err := extractPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return *posts, nil
+}
+
+func RetrieveNumberOfPostsForContentApi(isPage bool, conditions []filter.Condition) (int64, error) {
+	clause, arguments, err := filterClause(conditions, postFilterColumns)
+	if err != nil {
+		return 0, err
+	}
+	arguments = append([]interface{}{isPage}, arguments...)
+	return retrieveCount(fmt.Sprintf(stmtRetrievePostsCountForContentApi, clause), arguments...)
+}
+
 func extractPosts(rows *sql.Rows) (*[]structure.Post, error) {
 	posts := make([]structure.Post, 0)
 	for rows.Next() {
 		post := structure.Post{}
 		var userId int64
 		var status string
-		err := rows.Scan(&post.Id, &post.Uuid, &post.Title, &post.Slug, &post.Markdown, &post.Html, &post.IsFeatured, &post.IsPage, &status, &post.MetaDescription, &post.Image, &userId, &post.Date)
+		err := rows.Scan(&post.Id, &post.Uuid, &post.Title, &post.Slug, &post.Markdown, &post.Html, &post.IsFeatured, &post.IsPage, &status, &post.MetaDescription, &post.MetaTitle, &post.CustomExcerpt, &post.CanonicalUrl, &post.OgTitle, &post.OgDescription, &post.OgImage, &post.TwitterTitle, &post.TwitterDescription, &post.TwitterImage, &post.CodeInjectionHead, &post.CodeInjectionFoot, &post.Image, &userId, &post.Date, &post.DeletedAt, &post.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -114,11 +300,8 @@ func extractPosts(rows *sql.Rows) (*[]structure.Post, error) {
 			}
 		}
 		// Evaluate status
-		if status == "published" {
-			post.IsPublished = true
-		} else {
-			post.IsPublished = false
-		}
+		post.IsPublished = status == "published"
+		post.IsScheduled = status == "scheduled"
 		// Retrieve user
 		post.Author, err = RetrieveUser(userId)
 		if err != nil {
@@ -138,7 +321,7 @@ func extractPost(row *sql.Row) (*structure.Post, error) {
 	post := structure.Post{}
 	var userId int64
 	var status string
-	err := row.Scan(&post.Id, &post.Uuid, &post.Title, &post.Slug, &post.Markdown, &post.Html, &post.IsFeatured, &post.IsPage, &status, &post.MetaDescription, &post.Image, &userId, &post.Date)
+	err := row.Scan(&post.Id, &post.Uuid, &post.Title, &post.Slug, &post.Markdown, &post.Html, &post.IsFeatured, &post.IsPage, &status, &post.MetaDescription, &post.MetaTitle, &post.CustomExcerpt, &post.CanonicalUrl, &post.OgTitle, &post.OgDescription, &post.OgImage, &post.TwitterTitle, &post.TwitterDescription, &post.TwitterImage, &post.CodeInjectionHead, &post.CodeInjectionFoot, &post.Image, &userId, &post.Date, &post.DeletedAt, &post.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -150,11 +333,8 @@ func extractPost(row *sql.Row) (*structure.Post, error) {
 		}
 	}
 	// Evaluate status
-	if status == "published" {
-		post.IsPublished = true
-	} else {
-		post.IsPublished = false
-	}
+	post.IsPublished = status == "published"
+	post.IsScheduled = status == "scheduled"
 	// Retrieve user
 	post.Author, err = RetrieveUser(userId)
 	if err != nil {
@@ -201,6 +381,55 @@ func RetrieveNumberOfPostsByTag(tag_id int64) (int64, error) {
 	return count, nil
 }
 
+// RetrieveNumberOfPostsByTagTree counts the published posts of the tag and of all tags below it.
+func RetrieveNumberOfPostsByTagTree(tag_id int64) (int64, error) {
+	return retrieveCount(stmtRetrievePostsCountByTagTree, tag_id)
+}
+
+// RetrieveSitemapPosts returns the published posts (or pages) with the fields the sitemap needs, newest first.
+func RetrieveSitemapPosts(isPage bool) ([]structure.SitemapEntry, error) {
+	return retrieveSitemapEntries(stmtRetrieveSitemapPosts, isPage)
+}
+
+// RetrieveSitemapTags returns the tags that have published posts. Their update date is the one of their newest updated post.
+func RetrieveSitemapTags() ([]structure.SitemapEntry, error) {
+	return retrieveSitemapEntries(stmtRetrieveSitemapTags)
+}
+
+// RetrieveSitemapAuthors returns the users that have published posts. Their update date is the one of their newest updated post.
+func RetrieveSitemapAuthors() ([]structure.SitemapEntry, error) {
+	return retrieveSitemapEntries(stmtRetrieveSitemapAuthors)
+}
+
+func retrieveSitemapEntries(query string, arguments ...interface{}) ([]structure.SitemapEntry, error) {
+	entries := make([]structure.SitemapEntry, 0)
+	rows, err := readDB.Query(query, arguments...)
+	if err != nil {
+		return entries, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry := structure.SitemapEntry{}
+		err = rows.Scan(&entry.Slug, &entry.Image, &entry.UpdatedAt)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// RetrieveNextScheduledPostDate returns the publication date of the scheduled post that is due next or sql.ErrNoRows if no post is scheduled.
+func RetrieveNextScheduledPostDate() (*time.Time, error) {
+	var publicationDate time.Time
+	row := readDB.QueryRow(stmtRetrieveNextScheduledPostDate)
+	err := row.Scan(&publicationDate)
+	if err != nil {
+		return nil, err
+	}
+	return &publicationDate, nil
+}
+
 func retrievePostCreationDateById(post_id int64) (*time.Time, error) {
 	var creationDate time.Time
 	// Retrieve number of posts
@@ -216,7 +445,7 @@ func RetrieveUser(id int64) (*structure.User, error) {
 	user := structure.User{}
 	// Retrieve user
 	row := readDB.QueryRow(stmtRetrieveUserById, id)
-	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location)
+	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.LastLogin, &user.Role)
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +456,7 @@ func RetrieveUserBySlug(slug string) (*structure.User, error) {
 	user := structure.User{}
 	// Retrieve user
 	row := readDB.QueryRow(stmtRetrieveUserBySlug, slug)
-	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location)
+	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.LastLogin, &user.Role)
 	if err != nil {
 		return nil, err
 	}
@@ -238,13 +467,143 @@ func RetrieveUserByName(name []byte) (*structure.User, error) {
 	user := structure.User{}
 	// Retrieve user
 	row := readDB.QueryRow(stmtRetrieveUserByName, name)
-	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location)
+	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.LastLogin, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func RetrieveUserByEmail(email []byte) (*structure.User, error) {
+	user := structure.User{}
+	// Retrieve user
+	row := readDB.QueryRow(stmtRetrieveUserByEmail, email)
+	err := row.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.LastLogin, &user.Role)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+func RetrieveOwnerId() (int64, error) {
+	var id int64
+	row := readDB.QueryRow(stmtRetrieveOwnerId)
+	err := row.Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func RetrieveInviteByTokenHash(tokenHash string) (*structure.Invite, error) {
+	invite := structure.Invite{}
+	row := readDB.QueryRow(stmtRetrieveInviteByTokenHash, tokenHash)
+	err := row.Scan(&invite.Id, &invite.Email, &invite.Role, &invite.Status, &invite.ExpiresAt, &invite.CreatedAt, &invite.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func RetrieveInviteById(id int64) (*structure.Invite, error) {
+	invite := structure.Invite{}
+	row := readDB.QueryRow(stmtRetrieveInviteById, id)
+	err := row.Scan(&invite.Id, &invite.Email, &invite.Role, &invite.Status, &invite.ExpiresAt, &invite.CreatedAt, &invite.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func RetrievePendingInvites() ([]structure.Invite, error) {
+	invites := make([]structure.Invite, 0)
+	rows, err := readDB.Query(stmtRetrievePendingInvites)
+	if err != nil {
+		return invites, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var invite structure.Invite
+		err = rows.Scan(&invite.Id, &invite.Email, &invite.Role, &invite.Status, &invite.ExpiresAt, &invite.CreatedAt, &invite.CreatedBy)
+		if err != nil {
+			return invites, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, nil
+}
+
+// RetrieveSearchResults returns the ids of the posts matching the full-text query (best match first) and a snippet of the markdown for each post.
+// Matches in the snippets are enclosed in conversion.SnippetMatchStart and conversion.SnippetMatchEnd. An authorId of 0 returns posts of all authors.
+func RetrieveSearchResults(match string, publishedOnly bool, authorId int64, limit int64, offset int64) ([]int64, [][]byte, error) {
+	postIds := make([]int64, 0)
+	snippets := make([][]byte, 0)
+	rows, err := readDB.Query(stmtRetrieveSearchResults, conversion.SnippetMatchStart, conversion.SnippetMatchEnd, match, publishedOnly, authorId, authorId, limit, offset)
+	if err != nil {
+		return postIds, snippets, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postId int64
+		var snippet []byte
+		err = rows.Scan(&postId, &snippet)
+		if err != nil {
+			return postIds, snippets, err
+		}
+		postIds = append(postIds, postId)
+		snippets = append(snippets, snippet)
+	}
+	return postIds, snippets, nil
+}
+
+func RetrieveNumberOfSearchResults(match string, publishedOnly bool, authorId int64) (int64, error) {
+	var count int64
+	row := readDB.QueryRow(stmtRetrieveSearchResultsCount, match, publishedOnly, authorId, authorId)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func RetrieveRevisionsByPostId(postId int64) ([]structure.Revision, error) {
+	revisions := make([]structure.Revision, 0)
+	rows, err := readDB.Query(stmtRetrieveRevisionsByPostId, postId)
+	if err != nil {
+		return revisions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var revision structure.Revision
+		err = rows.Scan(&revision.Id, &revision.PostId, &revision.Title, &revision.Markdown, &revision.Tags, &revision.MetaDescription, &revision.Image, &revision.IsFeatured, &revision.IsPage, &revision.Date, &revision.CreatedBy)
+		if err != nil {
+			return revisions, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func RetrieveRevisionById(id int64) (*structure.Revision, error) {
+	revision := structure.Revision{}
+	row := readDB.QueryRow(stmtRetrieveRevisionById, id)
+	err := row.Scan(&revision.Id, &revision.PostId, &revision.Title, &revision.Markdown, &revision.Tags, &revision.MetaDescription, &revision.Image, &revision.IsFeatured, &revision.IsPage, &revision.Date, &revision.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func RetrieveNumberOfRevisionsByPostId(postId int64) (int64, error) {
+	var count int64
+	row := readDB.QueryRow(stmtRetrieveRevisionsCountByPostId, postId)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func RetrieveTags(postId int64) ([]structure.Tag, error) {
 	tags := make([]structure.Tag, 0)
 	// Retrieve tags
@@ -268,11 +627,380 @@ func RetrieveTags(postId int64) ([]structure.Tag, error) {
 	return tags, nil
 }
 
+// RetrieveTagsForContentApi returns the public tags that match the filter conditions and the number of published posts of each tag.
+func RetrieveTagsForContentApi(conditions []filter.Condition, limit int64, offset int64) ([]structure.Tag, []int64, error) {
+	clause, arguments, err := filterClause(conditions, tagFilterColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+	arguments = append(arguments, limit, offset)
+	rows, err := readDB.Query(fmt.Sprintf(stmtRetrieveTagsForContentApi, clause), arguments...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	tags := make([]structure.Tag, 0)
+	postCounts := make([]int64, 0)
+	for rows.Next() {
+		var tag structure.Tag
+		var postCount int64
+		err = rows.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.Description, &tag.Image, &tag.MetaTitle, &tag.MetaDescription, &tag.ParentId, &postCount)
+		if err != nil {
+			return nil, nil, err
+		}
+		tags = append(tags, tag)
+		postCounts = append(postCounts, postCount)
+	}
+	return tags, postCounts, nil
+}
+
+// RetrieveNumberOfAllPostsByTag counts the posts and pages of the tag, including drafts and scheduled posts.
+func RetrieveNumberOfAllPostsByTag(tag_id int64) (int64, error) {
+	return retrieveCount(stmtRetrieveAllPostsCountByTag, tag_id)
+}
+
+// RetrieveTagsForAdmin returns all tags and the number of posts of each tag. Drafts count, posts in the trash don't.
+func RetrieveTagsForAdmin() ([]structure.Tag, []int64, error) {
+	rows, err := readDB.Query(stmtRetrieveTagsForAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	tags := make([]structure.Tag, 0)
+	postCounts := make([]int64, 0)
+	for rows.Next() {
+		var tag structure.Tag
+		var postCount int64
+		err = rows.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.Description, &tag.Image, &tag.MetaTitle, &tag.MetaDescription, &tag.ParentId, &postCount)
+		if err != nil {
+			return nil, nil, err
+		}
+		tags = append(tags, tag)
+		postCounts = append(postCounts, postCount)
+	}
+	return tags, postCounts, nil
+}
+
+func RetrieveNumberOfTagsForContentApi(conditions []filter.Condition) (int64, error) {
+	clause, arguments, err := filterClause(conditions, tagFilterColumns)
+	if err != nil {
+		return 0, err
+	}
+	return retrieveCount(fmt.Sprintf(stmtRetrieveTagsCountForContentApi, clause), arguments...)
+}
+
+// RetrieveAuthorsForContentApi returns the users with published posts that match the filter conditions and the number of published posts of each user.
+func RetrieveAuthorsForContentApi(conditions []filter.Condition, limit int64, offset int64) ([]structure.User, []int64, error) {
+	clause, arguments, err := filterClause(conditions, userFilterColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+	arguments = append(arguments, limit, offset)
+	rows, err := readDB.Query(fmt.Sprintf(stmtRetrieveAuthorsForContentApi, clause), arguments...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	users := make([]structure.User, 0)
+	postCounts := make([]int64, 0)
+	for rows.Next() {
+		var user structure.User
+		var postCount int64
+		err = rows.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.LastLogin, &user.Role, &postCount)
+		if err != nil {
+			return nil, nil, err
+		}
+		users = append(users, user)
+		postCounts = append(postCounts, postCount)
+	}
+	return users, postCounts, nil
+}
+
+func RetrieveNumberOfAuthorsForContentApi(conditions []filter.Condition) (int64, error) {
+	clause, arguments, err := filterClause(conditions, userFilterColumns)
+	if err != nil {
+		return 0, err
+	}
+	return retrieveCount(fmt.Sprintf(stmtRetrieveAuthorsCountForContentApi, clause), arguments...)
+}
+
+func retrieveCount(query string, arguments ...interface{}) (int64, error) {
+	var count int64
+	row := readDB.QueryRow(query, arguments...)
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func RetrieveSessionByTokenHash(tokenHash string) (*structure.Session, error) {
+	row := readDB.QueryRow(stmtRetrieveSessionByTokenHash, tokenHash)
+	return extractSession(row)
+}
+
+func RetrieveSessionById(id int64) (*structure.Session, error) {
+	row := readDB.QueryRow(stmtRetrieveSessionById, id)
+	return extractSession(row)
+}
+
+// RetrieveSessionsByUserId returns the sessions of the user that haven't expired yet (most recently used first).
+func RetrieveSessionsByUserId(user_id int64, now time.Time) ([]structure.Session, error) {
+	rows, err := readDB.Query(stmtRetrieveSessionsByUserId, user_id, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractSessions(rows)
+}
+
+// RetrieveAllSessions returns the sessions of all users that haven't expired yet (most recently used first).
+func RetrieveAllSessions(now time.Time) ([]structure.Session, error) {
+	rows, err := readDB.Query(stmtRetrieveAllSessions, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractSessions(rows)
+}
+
+func extractSessions(rows *sql.Rows) ([]structure.Session, error) {
+	sessions := make([]structure.Session, 0)
+	for rows.Next() {
+		var session structure.Session
+		err := rows.Scan(&session.Id, &session.UserId, &session.UserName, &session.Remember, &session.UserAgent, &session.IpAddress, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func extractSession(row *sql.Row) (*structure.Session, error) {
+	var session structure.Session
+	err := row.Scan(&session.Id, &session.UserId, &session.UserName, &session.Remember, &session.UserAgent, &session.IpAddress, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RetrieveFailedLoginsByName returns the number of failed logins for the user name since the given date and the date of the last failed login.
+func RetrieveFailedLoginsByName(userName string, since time.Time) (int64, *time.Time, error) {
+	return retrieveFailedLogins(stmtRetrieveFailedLoginsCountByName, stmtRetrieveLastFailedLoginByName, userName, since)
+}
+
+// RetrieveFailedLoginsByIp returns the number of failed logins from the ip address since the given date and the date of the last failed login.
+func RetrieveFailedLoginsByIp(ipAddress string, since time.Time) (int64, *time.Time, error) {
+	return retrieveFailedLogins(stmtRetrieveFailedLoginsCountByIp, stmtRetrieveLastFailedLoginByIp, ipAddress, since)
+}
+
+func retrieveFailedLogins(countStatement string, lastStatement string, key string, since time.Time) (int64, *time.Time, error) {
+	count, err := retrieveCount(countStatement, key, since)
+	if err != nil || count == 0 {
+		return 0, nil, err
+	}
+	var lastFailure time.Time
+	row := readDB.QueryRow(lastStatement, key)
+	err = row.Scan(&lastFailure)
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, &lastFailure, nil
+}
+
+func RetrieveLastSuccessfulLoginByName(userName string) (*time.Time, error) {
+	return retrieveDate(stmtRetrieveLastSuccessfulLoginByName, userName)
+}
+
+func retrieveDate(statement string, arguments ...interface{}) (*time.Time, error) {
+	var date time.Time
+	row := readDB.QueryRow(statement, arguments...)
+	err := row.Scan(&date)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// RetrieveLockouts returns the most recent lockouts (newest first).
+func RetrieveLockouts(limit int64) ([]structure.Lockout, error) {
+	lockouts := make([]structure.Lockout, 0)
+	rows, err := readDB.Query(stmtRetrieveLockouts, limit)
+	if err != nil {
+		return lockouts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var lockout structure.Lockout
+		err = rows.Scan(&lockout.Id, &lockout.UserName, &lockout.IpAddress, &lockout.Failures, &lockout.LockedUntil, &lockout.CreatedAt)
+		if err != nil {
+			return lockouts, err
+		}
+		lockouts = append(lockouts, lockout)
+	}
+	return lockouts, nil
+}
+
+func RetrieveTwoFactor(user_id int64) (*structure.TwoFactor, error) {
+	var twoFactor structure.TwoFactor
+	row := readDB.QueryRow(stmtRetrieveTwoFactorByUserId, user_id)
+	err := row.Scan(&twoFactor.UserId, &twoFactor.Secret, &twoFactor.Enabled, &twoFactor.LastStep, &twoFactor.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func RetrieveNumberOfUnusedRecoveryCodes(user_id int64) (int64, error) {
+	return retrieveCount(stmtRetrieveUnusedRecoveryCodesCount, user_id)
+}
+
+func RetrieveTwoFactorChallenge(tokenHash string) (*structure.TwoFactorChallenge, error) {
+	var challenge structure.TwoFactorChallenge
+	row := readDB.QueryRow(stmtRetrieveTwoFactorChallengeByTokenHash, tokenHash)
+	err := row.Scan(&challenge.Id, &challenge.UserId, &challenge.Remember, &challenge.Secret, &challenge.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// RetrieveRequireTwoFactor returns true if all users have to use two-factor authentication.
+func RetrieveRequireTwoFactor() (bool, error) {
+	var requireTwoFactor string
+	row := readDB.QueryRow(stmtRetrieveBlog, "requireTwoFactor")
+	err := row.Scan(&requireTwoFactor)
+	if err != nil {
+		return false, err
+	}
+	return requireTwoFactor == "true", nil
+}
+
+func RetrievePasswordResetByTokenHash(tokenHash string) (*structure.PasswordReset, error) {
+	var passwordReset structure.PasswordReset
+	row := readDB.QueryRow(stmtRetrievePasswordResetByTokenHash, tokenHash)
+	err := row.Scan(&passwordReset.Id, &passwordReset.UserId, &passwordReset.CreatedAt, &passwordReset.ExpiresAt, &passwordReset.UsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &passwordReset, nil
+}
+
+// RetrieveLastPasswordResetDate returns when the user last requested a password reset.
+func RetrieveLastPasswordResetDate(user_id int64) (*time.Time, error) {
+	return retrieveDate(stmtRetrieveLastPasswordResetByUserId, user_id)
+}
+
+func RetrieveApiTokenByTokenHash(tokenHash string) (*structure.ApiToken, error) {
+	row := readDB.QueryRow(stmtRetrieveApiTokenByTokenHash, tokenHash)
+	return extractApiToken(row)
+}
+
+func RetrieveApiTokenById(id int64) (*structure.ApiToken, error) {
+	row := readDB.QueryRow(stmtRetrieveApiTokenById, id)
+	return extractApiToken(row)
+}
+
+func RetrieveApiTokensByUserId(user_id int64) ([]structure.ApiToken, error) {
+	apiTokens := make([]structure.ApiToken, 0)
+	rows, err := readDB.Query(stmtRetrieveApiTokensByUserId, user_id)
+	if err != nil {
+		return apiTokens, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var apiToken structure.ApiToken
+		err := rows.Scan(&apiToken.Id, &apiToken.UserId, &apiToken.UserName, &apiToken.Name, &apiToken.Scope, &apiToken.CreatedAt, &apiToken.LastUsedAt)
+		if err != nil {
+			return apiTokens, err
+		}
+		apiTokens = append(apiTokens, apiToken)
+	}
+	return apiTokens, nil
+}
+
+func extractApiToken(row *sql.Row) (*structure.ApiToken, error) {
+	var apiToken structure.ApiToken
+	err := row.Scan(&apiToken.Id, &apiToken.UserId, &apiToken.UserName, &apiToken.Name, &apiToken.Scope, &apiToken.CreatedAt, &apiToken.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &apiToken, nil
+}
+
+// RetrieveAuditEntries returns the entries of the audit log between since and until that match the filter (newest first).
+func RetrieveAuditEntries(conditions []filter.Condition, since time.Time, until time.Time, limit int64, offset int64) ([]structure.AuditEntry, error) {
+	clause, arguments, err := filterClause(conditions, auditFilterColumns)
+	if err != nil {
+		return nil, err
+	}
+	arguments = append([]interface{}{since, until}, arguments...)
+	arguments = append(arguments, limit, offset)
+	auditEntries := make([]structure.AuditEntry, 0)
+	rows, err := readDB.Query(fmt.Sprintf(stmtRetrieveAuditEntries, clause), arguments...)
+	if err != nil {
+		return auditEntries, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e structure.AuditEntry
+		err := rows.Scan(&e.Id, &e.UserId, &e.UserName, &e.Action, &e.TargetType, &e.TargetId, &e.TargetName, &e.SummaryBefore, &e.SummaryAfter, &e.IpAddress, &e.CreatedAt)
+		if err != nil {
+			return auditEntries, err
+		}
+		auditEntries = append(auditEntries, e)
+	}
+	return auditEntries, nil
+}
+
+func RetrieveNumberOfAuditEntries(conditions []filter.Condition, since time.Time, until time.Time) (int64, error) {
+	clause, arguments, err := filterClause(conditions, auditFilterColumns)
+	if err != nil {
+		return 0, err
+	}
+	arguments = append([]interface{}{since, until}, arguments...)
+	return retrieveCount(fmt.Sprintf(stmtRetrieveAuditEntriesCount, clause), arguments...)
+}
+
+func RetrieveApiKeys() ([]structure.ApiKey, error) {
+	apiKeys := make([]structure.ApiKey, 0)
+	rows, err := readDB.Query(stmtRetrieveApiKeys)
+	if err != nil {
+		return apiKeys, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var apiKey structure.ApiKey
+		err = rows.Scan(&apiKey.Id, &apiKey.Name, &apiKey.Secret, &apiKey.CreatedAt, &apiKey.CreatedBy)
+		if err != nil {
+			return apiKeys, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	return apiKeys, nil
+}
+
+func RetrieveApiKeyBySecret(secret string) (*structure.ApiKey, error) {
+	var apiKey structure.ApiKey
+	row := readDB.QueryRow(stmtRetrieveApiKeyBySecret, secret)
+	err := row.Scan(&apiKey.Id, &apiKey.Name, &apiKey.Secret, &apiKey.CreatedAt, &apiKey.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
 func RetrieveTag(tagId int64) (*structure.Tag, error) {
 	tag := structure.Tag{}
 	// Retrieve tag
 	row := readDB.QueryRow(stmtRetrieveTagById, tagId)
-	err := row.Scan(&tag.Id, &tag.Name, &tag.Slug)
+	err := row.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.Description, &tag.Image, &tag.MetaTitle, &tag.MetaDescription, &tag.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	err = retrieveTagParents(&tag)
 	if err != nil {
 		return nil, err
 	}
@@ -283,13 +1011,67 @@ func RetrieveTagBySlug(slug string) (*structure.Tag, error) {
 	tag := structure.Tag{}
 	// Retrieve tag
 	row := readDB.QueryRow(stmtRetrieveTagBySlug, slug)
-	err := row.Scan(&tag.Id, &tag.Name, &tag.Slug)
+	err := row.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.Description, &tag.Image, &tag.MetaTitle, &tag.MetaDescription, &tag.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	err = retrieveTagParents(&tag)
 	if err != nil {
 		return nil, err
 	}
 	return &tag, nil
 }
 
+// retrieveTagParents fills in the parent of the tag, the parent of the parent and so on.
+func retrieveTagParents(tag *structure.Tag) error {
+	seen := map[int64]bool{tag.Id: true}
+	for child := tag; child.ParentId != 0 && !seen[child.ParentId]; child = child.Parent {
+		parent := structure.Tag{}
+		row := readDB.QueryRow(stmtRetrieveTagById, child.ParentId)
+		err := row.Scan(&parent.Id, &parent.Name, &parent.Slug, &parent.Description, &parent.Image, &parent.MetaTitle, &parent.MetaDescription, &parent.ParentId)
+		if err == sql.ErrNoRows {
+			// The parent was deleted
+			child.ParentId = 0
+			return nil
+		} else if err != nil {
+			return err
+		}
+		seen[parent.Id] = true
+		child.Parent = &parent
+	}
+	return nil
+}
+
+// RetrieveChildTags returns the tags directly below the tag, ordered by name.
+func RetrieveChildTags(tagId int64) ([]structure.Tag, error) {
+	rows, err := readDB.Query(stmtRetrieveChildTags, tagId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := make([]structure.Tag, 0)
+	for rows.Next() {
+		var tag structure.Tag
+		err = rows.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.Description, &tag.Image, &tag.MetaTitle, &tag.MetaDescription, &tag.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// RetrieveTagIdByNameAndParent returns the id of the tag with the name below the parent tag (0 for top-level tags).
+func RetrieveTagIdByNameAndParent(name []byte, parent_id int64) (int64, error) {
+	var id int64
+	row := readDB.QueryRow(stmtRetrieveTagIdByNameAndParent, name, parent_id)
+	err := row.Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
 func RetrieveTagIdBySlug(slug string) (int64, error) {
 	var id int64
 	row := readDB.QueryRow(stmtRetrieveTagIdBySlug, slug)
@@ -365,6 +1147,17 @@ func RetrieveBlog() (*structure.Blog, error) {
 	if err != nil {
 		return &tempBlog, err
 	}
+	// Code injection
+	row = readDB.QueryRow(stmtRetrieveBlog, "codeinjection_head")
+	err = row.Scan(&tempBlog.CodeInjectionHead)
+	if err != nil {
+		return &tempBlog, err
+	}
+	row = readDB.QueryRow(stmtRetrieveBlog, "codeinjection_foot")
+	err = row.Scan(&tempBlog.CodeInjectionFoot)
+	if err != nil {
+		return &tempBlog, err
+	}
 	return &tempBlog, err
 }
 
@@ -408,14 +1201,14 @@ func RetrieveAllTags() ([]structure.Tag, error) {
 
 func RetrieveAllUsers() ([]structure.User, error) {
 	users := make([]structure.User, 0)
-	rows, err := readDB.Query("SELECT id, name, slug, email, image, cover, bio, website, location FROM users")
+	rows, err := readDB.Query("SELECT id, name, slug, email, image, cover, bio, website, location, status, last_login, IFNULL((SELECT role_id FROM roles_users WHERE roles_users.user_id = users.id ORDER BY roles_users.id DESC LIMIT 1), 3) FROM users")
 	if err != nil {
 		return users, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var user structure.User
-		err = rows.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location)
+		err = rows.Scan(&user.Id, &user.Name, &user.Slug, &user.Email, &user.Image, &user.Cover, &user.Bio, &user.Website, &user.Location, &user.Status, &user.LastLogin, &user.Role)
 		if err != nil {
 			return users, err
 		}
@@ -432,3 +1225,69 @@ func makeNavigation(navigation []byte) ([]structure.Navigation, error) {
 	}
 	return navigationItems, nil
 }
+
+// RetrieveRedirects returns all redirects, manual ones first.
+func RetrieveRedirects() ([]structure.Redirect, error) {
+	redirects := make([]structure.Redirect, 0)
+	rows, err := readDB.Query(stmtRetrieveRedirects)
+	if err != nil {
+		return redirects, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		redirect := structure.Redirect{}
+		err := rows.Scan(&redirect.Id, &redirect.From, &redirect.To, &redirect.Match, &redirect.Automatic, &redirect.CreatedAt, &redirect.CreatedBy)
+		if err != nil {
+			return redirects, err
+		}
+		redirects = append(redirects, redirect)
+	}
+	return redirects, rows.Err()
+}
+
+func RetrieveRedirectByFromPath(from_path string, matchType string) (*structure.Redirect, error) {
+	row := readDB.QueryRow(stmtRetrieveRedirectByFromPath, from_path, matchType)
+	return extractRedirect(row)
+}
+
+// RetrieveRedirectForPath returns the redirect that applies to the path. An exact redirect wins over prefix redirects,
+// and the longest matching prefix wins over shorter ones.
+func RetrieveRedirectForPath(path string) (*structure.Redirect, error) {
+	redirect, err := RetrieveRedirectByFromPath(path, structure.RedirectMatchExact)
+	if err == nil {
+		return redirect, nil
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+	row := readDB.QueryRow(stmtRetrieveRedirectByPrefix, path)
+	return extractRedirect(row)
+}
+
+func extractRedirect(row *sql.Row) (*structure.Redirect, error) {
+	redirect := structure.Redirect{}
+	err := row.Scan(&redirect.Id, &redirect.From, &redirect.To, &redirect.Match, &redirect.Automatic, &redirect.CreatedAt, &redirect.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &redirect, nil
+}
+
+func RetrievePostPreview(post_id int64) (*structure.PostPreview, error) {
+	preview := structure.PostPreview{}
+	row := readDB.QueryRow(stmtRetrievePostPreviewByPostId, post_id)
+	err := row.Scan(&preview.PostId, &preview.ExpiresAt, &preview.CreatedAt, &preview.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+func RetrieveAutosave(post_id int64, user_id int64) (*structure.Autosave, error) {
+	autosave := structure.Autosave{}
+	row := readDB.QueryRow(stmtRetrieveAutosave, post_id, user_id)
+	err := row.Scan(&autosave.PostId, &autosave.UserId, &autosave.Title, &autosave.Markdown, &autosave.Tags, &autosave.MetaDescription, &autosave.Image, &autosave.IsFeatured, &autosave.IsPage, &autosave.BaseVersion, &autosave.Date)
+	if err != nil {
+		return nil, err
+	}
+	return &autosave, nil
+}
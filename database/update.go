@@ -1,36 +1,93 @@
 package database
 
 import (
+	"database/sql"
+	"strconv"
 	"time"
 )
 
-const stmtUpdatePost = "UPDATE posts SET title = ?, slug = ?, markdown = ?, html = ?, featured = ?, page = ?, status = ?, meta_description = ?, image = ?, updated_at = ?, updated_by = ? WHERE id = ?"
-const stmtUpdatePostPublished = "UPDATE posts SET title = ?, slug = ?, markdown = ?, html = ?, featured = ?, page = ?, status = ?, meta_description = ?, image = ?, updated_at = ?, updated_by = ?, published_at = ?, published_by = ? WHERE id = ?"
+const stmtUpdatePost = "UPDATE posts SET title = ?, slug = ?, markdown = ?, html = ?, featured = ?, page = ?, status = ?, meta_description = ?, image = ?, updated_at = ?, updated_by = ? WHERE id = ? AND (? IS NULL OR updated_at = ?)"
+const stmtUpdatePostPublished = "UPDATE posts SET title = ?, slug = ?, markdown = ?, html = ?, featured = ?, page = ?, status = ?, meta_description = ?, image = ?, updated_at = ?, updated_by = ?, published_at = ?, published_by = ? WHERE id = ? AND (? IS NULL OR updated_at = ?)"
+const stmtUpdateScheduledPostsPublished = "UPDATE posts SET status = 'published', updated_at = ? WHERE status = 'scheduled' AND published_at <= ? AND deleted_at IS NULL"
+const stmtUpdatePostMetadata = "UPDATE posts SET meta_title = ?, custom_excerpt = ?, canonical_url = ?, og_title = ?, og_description = ?, og_image = ?, twitter_title = ?, twitter_description = ?, twitter_image = ?, codeinjection_head = ?, codeinjection_foot = ? WHERE id = ?"
+const stmtUpdatePostDeleted = "UPDATE posts SET deleted_at = ?, updated_at = ?, updated_by = ? WHERE id = ?"
 const stmtUpdateSettings = "UPDATE settings SET value = ?, updated_at = ?, updated_by = ? WHERE key = ?"
 const stmtUpdateUser = "UPDATE users SET name = ?, slug = ?, email = ?, image = ?, cover = ?, bio = ?, website = ?, location = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+const stmtUpdateTag = "UPDATE tags SET name = ?, slug = ?, description = ?, image = ?, meta_title = ?, meta_description = ?, parent_id = NULLIF(?, 0), updated_at = ?, updated_by = ? WHERE id = ?"
 const stmtUpdateLastLogin = "UPDATE users SET last_login = ? WHERE id = ?"
 const stmtUpdateUserPassword = "UPDATE users SET password = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+const stmtUpdateUserStatus = "UPDATE users SET status = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+const stmtUpdateRoleUser = "UPDATE roles_users SET role_id = ? WHERE user_id = ?"
+const stmtUpdateSessionLastSeen = "UPDATE sessions SET last_seen_at = ? WHERE id = ?"
+const stmtUpdateTwoFactorEnabled = "UPDATE two_factor SET enabled = ?, last_step = ? WHERE user_id = ?"
+const stmtUpdateTwoFactorLastStep = "UPDATE two_factor SET last_step = ? WHERE user_id = ? AND last_step < ?"
+const stmtUpdateRecoveryCodeUsed = "UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL"
+const stmtUpdatePasswordResetUsed = "UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL"
+const stmtUpdateApiTokenLastUsed = "UPDATE api_tokens SET last_used_at = ? WHERE id = ?"
+const stmtUpdateRedirectTargets = "UPDATE redirects SET to_path = ? WHERE to_path = ? AND match_type = 'exact'"
+const stmtUpdateRedirectTargetsByPrefix = "UPDATE redirects SET to_path = ? || substr(to_path, length(?) + 1) WHERE substr(to_path, 1, length(?)) = ?"
+const stmtUpdateInviteAccepted = "UPDATE invites SET status = 'accepted', updated_at = ?, updated_by = ? WHERE id = ? AND status = 'pending'"
 
-func UpdatePost(id int64, title []byte, slug string, markdown []byte, html []byte, featured bool, isPage bool, published bool, meta_description []byte, image []byte, updated_at time.Time, updated_by int64) error {
+// UpdatePost saves the post. If previous_updated_at isn't nil, the post is only saved if it wasn't updated since then. Returns false if the post was updated in the meantime.
+func UpdatePost(id int64, title []byte, slug string, markdown []byte, html []byte, featured bool, isPage bool, published bool, scheduled bool, meta_description []byte, image []byte, published_at time.Time, updated_at time.Time, updated_by int64, previous_updated_at *time.Time) (bool, error) {
 	currentPost, err := RetrievePostById(id)
 	if err != nil {
-		return err
+		return false, err
+	}
+	status := postStatus(published, scheduled)
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
 	}
-	status := "draft"
-	if published {
-		status = "published"
+	var result sql.Result
+	if scheduled {
+		// Scheduled posts carry their future publication date
+		result, err = writeDB.Exec(stmtUpdatePostPublished, title, slug, markdown, html, featured, isPage, status, meta_description, image, updated_at, updated_by, published_at, updated_by, id, previous_updated_at, previous_updated_at)
+	} else if published && !currentPost.IsPublished {
+		// If the updated post is published for the first time, add publication date and user
+		result, err = writeDB.Exec(stmtUpdatePostPublished, title, slug, markdown, html, featured, isPage, status, meta_description, image, updated_at, updated_by, updated_at, updated_by, id, previous_updated_at, previous_updated_at)
+	} else if !published && currentPost.IsScheduled {
+		// The schedule was cancelled. Remove the publication date again.
+		result, err = writeDB.Exec(stmtUpdatePostPublished, title, slug, markdown, html, featured, isPage, status, meta_description, image, updated_at, updated_by, nil, nil, id, previous_updated_at, previous_updated_at)
+	} else {
+		result, err = writeDB.Exec(stmtUpdatePost, title, slug, markdown, html, featured, isPage, status, meta_description, image, updated_at, updated_by, id, previous_updated_at, previous_updated_at)
+	}
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
 	}
+	return rowsAffected == 1, writeDB.Commit()
+}
+
+// UpdatePostMetadata sets the fields that control how the post is presented to search engines and social networks and the code that is injected into its page.
+func UpdatePostMetadata(id int64, meta_title []byte, custom_excerpt []byte, canonical_url []byte, og_title []byte, og_description []byte, og_image []byte, twitter_title []byte, twitter_description []byte, twitter_image []byte, codeinjection_head []byte, codeinjection_foot []byte) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
 		return err
 	}
-	// If the updated post is published for the first time, add publication date and user
-	if published && !currentPost.IsPublished {
-		_, err = writeDB.Exec(stmtUpdatePostPublished, title, slug, markdown, html, featured, isPage, status, meta_description, image, updated_at, updated_by, updated_at, updated_by, id)
-	} else {
-		_, err = writeDB.Exec(stmtUpdatePost, title, slug, markdown, html, featured, isPage, status, meta_description, image, updated_at, updated_by, id)
+	_, err = writeDB.Exec(stmtUpdatePostMetadata, meta_title, custom_excerpt, canonical_url, og_title, og_description, og_image, twitter_title, twitter_description, twitter_image, codeinjection_head, codeinjection_foot, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
 	}
+	return writeDB.Commit()
+}
+
+// UpdatePostDeleted moves the post to the trash. A nil deleted_at restores it.
+func UpdatePostDeleted(id int64, deleted_at *time.Time, updated_at time.Time, updated_by int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdatePostDeleted, deleted_at, updated_at, updated_by, id)
 	if err != nil {
 		writeDB.Rollback()
 		return err
@@ -38,7 +95,37 @@ func UpdatePost(id int64, title []byte, slug string, markdown []byte, html []byt
 	return writeDB.Commit()
 }
 
-func UpdateSettings(title []byte, description []byte, logo []byte, cover []byte, postsPerPage int64, activeTheme string, navigation []byte, updated_at time.Time, updated_by int64) error {
+// PublishScheduledPosts publishes all scheduled posts whose publication date has been reached and returns how many posts were published.
+func PublishScheduledPosts(now time.Time) (int64, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	result, err := writeDB.Exec(stmtUpdateScheduledPostsPublished, now, now)
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		writeDB.Rollback()
+		return 0, err
+	}
+	return count, writeDB.Commit()
+}
+
+// postStatus returns the value of the status column for a post.
+func postStatus(published bool, scheduled bool) string {
+	if scheduled {
+		return "scheduled"
+	} else if published {
+		return "published"
+	}
+	return "draft"
+}
+
+func UpdateSettings(title []byte, description []byte, logo []byte, cover []byte, postsPerPage int64, activeTheme string, navigation []byte, codeInjectionHead []byte, codeInjectionFoot []byte, updated_at time.Time, updated_by int64) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
 		writeDB.Rollback()
@@ -86,6 +173,17 @@ func UpdateSettings(title []byte, description []byte, logo []byte, cover []byte,
 		writeDB.Rollback()
 		return err
 	}
+	// Code injection
+	_, err = writeDB.Exec(stmtUpdateSettings, codeInjectionHead, updated_at, updated_by, "codeinjection_head")
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateSettings, codeInjectionFoot, updated_at, updated_by, "codeinjection_foot")
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
 	return writeDB.Commit()
 }
 
@@ -117,6 +215,20 @@ func UpdateUser(id int64, name []byte, slug string, email []byte, image []byte,
 	return writeDB.Commit()
 }
 
+func UpdateTag(id int64, name []byte, slug string, description []byte, image []byte, meta_title []byte, meta_description []byte, parent_id int64, updated_at time.Time, updated_by int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateTag, name, slug, description, image, meta_title, meta_description, parent_id, updated_at, updated_by, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
 func UpdateLastLogin(logInDate time.Time, userId int64) error {
 	writeDB, err := readDB.Begin()
 	if err != nil {
@@ -144,3 +256,147 @@ func UpdateUserPassword(id int64, password string, updated_at time.Time, updated
 	}
 	return writeDB.Commit()
 }
+
+func UpdateUserStatus(id int64, status string, updated_at time.Time, updated_by int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateUserStatus, status, updated_at, updated_by, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdateRoleUser(role_id int, user_id int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateRoleUser, role_id, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdateSessionLastSeen(id int64, last_seen_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateSessionLastSeen, last_seen_at, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+func UpdateTwoFactorEnabled(user_id int64, enabled bool, last_step int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateTwoFactorEnabled, enabled, last_step, user_id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// UpdateTwoFactorLastStep stores the time step of the last used code. Returns false if a code of this or a later step has been used already.
+func UpdateTwoFactorLastStep(user_id int64, last_step int64) (bool, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	result, err := writeDB.Exec(stmtUpdateTwoFactorLastStep, last_step, user_id, last_step)
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	return rowsAffected == 1, writeDB.Commit()
+}
+
+// UpdateRecoveryCodeUsed marks the recovery code as used. Returns false if the user has no unused recovery code with this hash.
+func UpdateRecoveryCodeUsed(user_id int64, codeHash string, used_at time.Time) (bool, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	result, err := writeDB.Exec(stmtUpdateRecoveryCodeUsed, used_at, user_id, codeHash)
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	return rowsAffected == 1, writeDB.Commit()
+}
+
+func UpdateRequireTwoFactor(requireTwoFactor bool, updated_at time.Time, updated_by int64) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateSettings, strconv.FormatBool(requireTwoFactor), updated_at, updated_by, "requireTwoFactor")
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
+
+// UpdatePasswordResetUsed marks the reset token as used. Returns false if it has been used already.
+func UpdatePasswordResetUsed(id int64, used_at time.Time) (bool, error) {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	result, err := writeDB.Exec(stmtUpdatePasswordResetUsed, used_at, id)
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		writeDB.Rollback()
+		return false, err
+	}
+	return rowsAffected == 1, writeDB.Commit()
+}
+
+func UpdateApiTokenLastUsed(id int64, last_used_at time.Time) error {
+	writeDB, err := readDB.Begin()
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	_, err = writeDB.Exec(stmtUpdateApiTokenLastUsed, last_used_at, id)
+	if err != nil {
+		writeDB.Rollback()
+		return err
+	}
+	return writeDB.Commit()
+}
@@ -0,0 +1,124 @@
+package diff
+
+import (
+	"strings"
+)
+
+// Operations of a diff line
+const (
+	Equal  = 0
+	Insert = 1
+	Delete = 2
+)
+
+// Line: one line of a diff. Text is the line without the line break.
+type Line struct {
+	Operation int
+	Text      string
+}
+
+// Lines computes a line based diff that turns the text a into the text b.
+func Lines(a string, b string) []Line {
+	return Strings(splitLines(a), splitLines(b))
+}
+
+// Strings computes the shortest edit script that turns a into b.
+func Strings(a []string, b []string) []Line {
+	// Strip the common prefix and suffix. Most edits only touch a small part of a post.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Operation: Equal, Text: text})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Operation: Equal, Text: text})
+	}
+	return lines
+}
+
+// myers implements the O(ND) diff algorithm by Eugene W. Myers.
+func myers(a []string, b []string) []Line {
+	n := len(a)
+	m := len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	// v holds the furthest x for each diagonal k at index offset+k
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace holds the relevant part of v before each round d at index k+d+1
+	trace := make([][]int, 0)
+	var d int
+search:
+	for d = 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+	// Walk back through the trace. This yields the edit script in reverse order.
+	reversed := make([]Line, 0, max)
+	x := n
+	y := m
+	for ; d >= 0; d-- {
+		saved := trace[d]
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && saved[k+d] < saved[k+d+2]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := saved[previousK+d+1]
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			reversed = append(reversed, Line{Operation: Equal, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == previousX {
+				reversed = append(reversed, Line{Operation: Insert, Text: b[y-1]})
+			} else {
+				reversed = append(reversed, Line{Operation: Delete, Text: a[x-1]})
+			}
+		}
+		x = previousX
+		y = previousY
+	}
+	lines := make([]Line, len(reversed))
+	for index, line := range reversed {
+		lines[len(reversed)-1-index] = line
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+}
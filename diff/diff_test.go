@@ -0,0 +1,61 @@
+package diff
+
+import "testing"
+
+var diffTests = []struct {
+	a   string
+	b   string
+	out []Line
+}{
+	{
+		a:   "",
+		b:   "",
+		out: []Line{},
+	},
+	{
+		a:   "one\ntwo",
+		b:   "one\ntwo",
+		out: []Line{{Equal, "one"}, {Equal, "two"}},
+	},
+	{
+		a:   "one\nthree",
+		b:   "one\ntwo\nthree",
+		out: []Line{{Equal, "one"}, {Insert, "two"}, {Equal, "three"}},
+	},
+	{
+		a:   "one\ntwo\nthree",
+		b:   "one\nthree",
+		out: []Line{{Equal, "one"}, {Delete, "two"}, {Equal, "three"}},
+	},
+	{
+		a:   "one\ntwo\nthree",
+		b:   "one\n2\nthree",
+		out: []Line{{Equal, "one"}, {Delete, "two"}, {Insert, "2"}, {Equal, "three"}},
+	},
+	{
+		a:   "a\nb\nc\na\nb\nb\na",
+		b:   "c\nb\na\nb\na\nc",
+		out: []Line{{Delete, "a"}, {Delete, "b"}, {Equal, "c"}, {Insert, "b"}, {Equal, "a"}, {Equal, "b"}, {Delete, "b"}, {Equal, "a"}, {Insert, "c"}},
+	},
+	{
+		a:   "",
+		b:   "new\npost",
+		out: []Line{{Insert, "new"}, {Insert, "post"}},
+	},
+}
+
+func TestLines(t *testing.T) {
+	for _, tt := range diffTests {
+		lines := Lines(tt.a, tt.b)
+		if len(lines) != len(tt.out) {
+			t.Errorf("Lines(%q, %q) = %v, want %v", tt.a, tt.b, lines, tt.out)
+			continue
+		}
+		for index := range lines {
+			if lines[index] != tt.out[index] {
+				t.Errorf("Lines(%q, %q) = %v, want %v", tt.a, tt.b, lines, tt.out)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Package filter parses the filter parameter of the content api. It supports the subset of Ghost's
+// filter syntax that is commonly used by themes: conditions of the form key:value, key:-value (not)
+// and key:[value1,value2] (any of), combined with + (and).
+package filter
+
+import (
+	"errors"
+	"strings"
+)
+
+type Condition struct {
+	Key    string
+	Values []string
+	Negate bool
+}
+
+var ErrInvalidFilter = errors.New("Invalid filter.")
+var ErrUnsupportedKey = errors.New("Unsupported filter key.")
+
+// Parse splits the filter into its conditions. Keys are returned in lower case. An empty filter has no conditions.
+func Parse(filter string) ([]Condition, error) {
+	conditions := make([]Condition, 0)
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return conditions, nil
+	}
+	for _, part := range splitOutsideBrackets(filter, '+') {
+		condition, err := parseCondition(part)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, *condition)
+	}
+	return conditions, nil
+}
+
+func parseCondition(part string) (*Condition, error) {
+	separator := strings.Index(part, ":")
+	if separator < 1 {
+		return nil, ErrInvalidFilter
+	}
+	condition := Condition{Key: strings.ToLower(strings.TrimSpace(part[:separator]))}
+	value := strings.TrimSpace(part[separator+1:])
+	if strings.HasPrefix(value, "-") {
+		condition.Negate = true
+		value = strings.TrimSpace(value[1:])
+	}
+	if strings.HasPrefix(value, "[") {
+		if !strings.HasSuffix(value, "]") {
+			return nil, ErrInvalidFilter
+		}
+		for _, listValue := range strings.Split(value[1:len(value)-1], ",") {
+			listValue = unquote(strings.TrimSpace(listValue))
+			if listValue != "" {
+				condition.Values = append(condition.Values, listValue)
+			}
+		}
+	} else {
+		value = unquote(value)
+		if value != "" {
+			condition.Values = append(condition.Values, value)
+		}
+	}
+	if len(condition.Values) == 0 {
+		return nil, ErrInvalidFilter
+	}
+	return &condition, nil
+}
+
+// splitOutsideBrackets splits the input at every separator that is not part of a [...] list.
+func splitOutsideBrackets(input string, separator rune) []string {
+	parts := make([]string, 0)
+	depth := 0
+	start := 0
+	for index, character := range input {
+		switch character {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case separator:
+			if depth == 0 {
+				parts = append(parts, input[start:index])
+				start = index + 1
+			}
+		}
+	}
+	return append(parts, input[start:])
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
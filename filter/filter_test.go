@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+var parseTests = []struct {
+	in  string
+	out []Condition
+}{
+	{
+		in:  "",
+		out: []Condition{},
+	},
+	{
+		in:  "tag:getting-started",
+		out: []Condition{{Key: "tag", Values: []string{"getting-started"}}},
+	},
+	{
+		in:  "featured:true+Author:-john",
+		out: []Condition{{Key: "featured", Values: []string{"true"}}, {Key: "author", Values: []string{"john"}, Negate: true}},
+	},
+	{
+		in:  "tags:[news, 'release-notes']+slug:\"welcome\"",
+		out: []Condition{{Key: "tags", Values: []string{"news", "release-notes"}}, {Key: "slug", Values: []string{"welcome"}}},
+	},
+	{
+		in:  "id:-[1,2]",
+		out: []Condition{{Key: "id", Values: []string{"1", "2"}, Negate: true}},
+	},
+}
+
+var invalidFilters = []string{"tag", ":news", "tag:", "tags:[news", "tags:[]", "tag:news+"}
+
+func TestParse(t *testing.T) {
+	for _, tt := range parseTests {
+		conditions, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(conditions, tt.out) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, conditions, tt.out)
+		}
+	}
+	for _, in := range invalidFilters {
+		if _, err := Parse(in); err != ErrInvalidFilter {
+			t.Errorf("Parse(%q) returned error %v, want %v", in, err, ErrInvalidFilter)
+		}
+	}
+}
@@ -11,6 +11,8 @@ var (
 	IsInDevMode = false
 	HttpPort    = ""
 	HttpsPort   = ""
+	// User whose password should be reset from the command line
+	ResetPassword = ""
 )
 
 func init() {
@@ -32,5 +34,7 @@ func parseFlags() {
 	flag.StringVar(&HttpPort, "http-port", "", "Use this option to override the HTTP port that was set in the config.json. Example: -http-port=8080")
 	// Check if the http port that was set in the config was overridden by the user
 	flag.StringVar(&HttpsPort, "https-port", "", "Use this option to override the HTTPS port that was set in the config.json. Example: -https-port=8081")
+	// Check if the password of a user should be reset instead of starting the server
+	flag.StringVar(&ResetPassword, "reset-password", "", "Use this option to set a new random password for a user (e.g. if you can't log in anymore) and exit. All sessions of the user are ended. Example: -reset-password=username")
 	flag.Parse()
 }
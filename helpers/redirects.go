@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"strings"
+
+	"journey/structure"
+)
+
+// RedirectTarget returns the url that a request for requestPath is sent to by the redirect. Returns false if the redirect doesn't cover
+// the path or if the target would lead to another host.
+func RedirectTarget(redirect *structure.Redirect, requestPath string) (string, bool) {
+	target := redirect.To
+	switch redirect.Match {
+	case structure.RedirectMatchExact:
+		if requestPath != redirect.From {
+			return "", false
+		}
+	case structure.RedirectMatchPrefix:
+		if !strings.HasPrefix(requestPath, redirect.From) {
+			return "", false
+		}
+		// The request path isn't cleaned, so the rest may start with slashes that would turn the target into a url of another host (e.g. /blog/%2Fevil.com)
+		rest := strings.TrimLeft(strings.TrimPrefix(requestPath, redirect.From), "/\\")
+		if rest != "" {
+			if !strings.HasSuffix(target, "/") {
+				target += "/"
+			}
+			target += rest
+		}
+	default:
+		return "", false
+	}
+	// Browsers treat "//" and "/\" as the start of a url of another host
+	if strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "", false
+	}
+	return target, true
+}
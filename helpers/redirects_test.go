@@ -0,0 +1,134 @@
+package helpers
+
+import (
+	"testing"
+
+	"journey/structure"
+)
+
+var redirectTargetTests = []struct {
+	from  string
+	to    string
+	match string
+	path  string
+	out   string
+	ok    bool
+}{
+	{
+		from:  "/old-post/",
+		to:    "/new-post/",
+		match: structure.RedirectMatchExact,
+		path:  "/old-post/",
+		out:   "/new-post/",
+		ok:    true,
+	},
+	{
+		from:  "/old-post/",
+		to:    "/new-post/",
+		match: structure.RedirectMatchExact,
+		path:  "/old-post/rss/",
+		ok:    false,
+	},
+	{
+		from:  "/old-post/",
+		to:    "https://example.com/post/",
+		match: structure.RedirectMatchExact,
+		path:  "/old-post/",
+		out:   "https://example.com/post/",
+		ok:    true,
+	},
+	{
+		from:  "/tag/old/",
+		to:    "/tag/new/",
+		match: structure.RedirectMatchPrefix,
+		path:  "/tag/old/",
+		out:   "/tag/new/",
+		ok:    true,
+	},
+	{
+		from:  "/tag/old/",
+		to:    "/tag/new/",
+		match: structure.RedirectMatchPrefix,
+		path:  "/tag/old/page/2/",
+		out:   "/tag/new/page/2/",
+		ok:    true,
+	},
+	{
+		from:  "/tag/old/",
+		to:    "/tag/new/",
+		match: structure.RedirectMatchPrefix,
+		path:  "/tag/other/",
+		ok:    false,
+	},
+	{
+		from:  "/blog",
+		to:    "/posts",
+		match: structure.RedirectMatchPrefix,
+		path:  "/blog/first/",
+		out:   "/posts/first/",
+		ok:    true,
+	},
+	{
+		from:  "/blog",
+		to:    "https://example.com",
+		match: structure.RedirectMatchPrefix,
+		path:  "/blog@evil.com",
+		out:   "https://example.com/@evil.com",
+		ok:    true,
+	},
+	{
+		from:  "/blog/",
+		to:    "/",
+		match: structure.RedirectMatchPrefix,
+		path:  "/blog/first/",
+		out:   "/first/",
+		ok:    true,
+	},
+	{
+		from:  "/blog/",
+		to:    "/",
+		match: structure.RedirectMatchPrefix,
+		path:  "/blog//evil.com/x",
+		out:   "/evil.com/x",
+		ok:    true,
+	},
+	{
+		from:  "/blog/",
+		to:    "/",
+		match: structure.RedirectMatchPrefix,
+		path:  "/blog/\\evil.com/x",
+		out:   "/evil.com/x",
+		ok:    true,
+	},
+	{
+		from:  "/blog/",
+		to:    "//evil.com",
+		match: structure.RedirectMatchPrefix,
+		path:  "/blog/x",
+		ok:    false,
+	},
+	{
+		from:  "/old/",
+		to:    "/\\evil.com",
+		match: structure.RedirectMatchExact,
+		path:  "/old/",
+		ok:    false,
+	},
+	{
+		from:  "/old/",
+		to:    "/new/",
+		match: "unknown",
+		path:  "/old/",
+		ok:    false,
+	},
+}
+
+func TestRedirectTarget(t *testing.T) {
+	for _, test := range redirectTargetTests {
+		redirect := structure.Redirect{From: test.from, To: test.to, Match: test.match}
+		actual, ok := RedirectTarget(&redirect, test.path)
+		if ok != test.ok || actual != test.out {
+			t.Errorf("Expected '%s' (%t), received '%s' (%t) for '%s' with %s redirect from '%s' to '%s'", test.out, test.ok, actual, ok, test.path, test.match, test.from, test.to)
+		}
+	}
+}
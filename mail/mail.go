@@ -0,0 +1,108 @@
+// Package mail sends the emails of the blog (e.g. password reset links). Messages go through a Mailer,
+// so that blogs without a mail server can write them to a file or the log instead.
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(message *Message) error
+}
+
+var ErrInvalidAddress = errors.New("Invalid email address.")
+
+// SmtpMailer sends messages through an smtp server. The connection is upgraded with STARTTLS if the server supports it.
+type SmtpMailer struct {
+	Host     string
+	Port     int
+	User     string // No authentication if empty
+	Password string
+	From     string
+}
+
+func (m *SmtpMailer) Send(message *Message) error {
+	if !isValidAddress(message.To) {
+		return ErrInvalidAddress
+	}
+	address := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	var auth smtp.Auth
+	if m.User != "" {
+		auth = smtp.PlainAuth("", m.User, m.Password, m.Host)
+	}
+	return smtp.SendMail(address, auth, m.From, []string{message.To}, format(m.From, message, time.Now()))
+}
+
+// FileMailer appends messages to a file. It is meant for testing and for blogs without a mail server.
+type FileMailer struct {
+	Path string
+	From string
+	lock sync.Mutex
+}
+
+func (m *FileMailer) Send(message *Message) error {
+	if !isValidAddress(message.To) {
+		return ErrInvalidAddress
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	file, err := os.OpenFile(m.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(format(m.From, message, time.Now()), '\n'))
+	return err
+}
+
+// LogMailer writes messages to the log.
+type LogMailer struct {
+	From string
+}
+
+func (m *LogMailer) Send(message *Message) error {
+	if !isValidAddress(message.To) {
+		return ErrInvalidAddress
+	}
+	log.Println("Email:\n" + string(format(m.From, message, time.Now())))
+	return nil
+}
+
+// format returns the message with the headers of a plain text email.
+func format(from string, message *Message, t time.Time) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "From: %s\r\n", from)
+	fmt.Fprintf(&buffer, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", t.Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buffer.WriteString("\r\n")
+	// Lines of the body have to end with CRLF
+	body := strings.Replace(message.Body, "\r\n", "\n", -1)
+	buffer.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+	buffer.WriteString("\r\n")
+	return buffer.Bytes()
+}
+
+// Addresses must not contain line breaks, otherwise they could add headers to the message
+func isValidAddress(address string) bool {
+	return strings.Contains(address, "@") && !strings.ContainsAny(address, "\r\n")
+}
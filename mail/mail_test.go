@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	message := Message{To: "owner@example.com", Subject: "Passwort zurücksetzen", Body: "Line 1\nLine 2"}
+	out := string(format("blog@example.com", &message, time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)))
+	expected := "From: blog@example.com\r\n" +
+		"To: owner@example.com\r\n" +
+		"Subject: =?utf-8?q?Passwort_zur=C3=BCcksetzen?=\r\n" +
+		"Date: Sat, 02 Jan 2016 03:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Line 1\r\nLine 2\r\n"
+	if out != expected {
+		t.Errorf("format() = %q, want %q", out, expected)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journey-mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	mailer := &FileMailer{Path: filepath.Join(directory, "mail.txt"), From: "blog@example.com"}
+	for _, subject := range []string{"First", "Second"} {
+		err = mailer.Send(&Message{To: "owner@example.com", Subject: subject, Body: "Hello"})
+		if err != nil {
+			t.Fatalf("Send() returned error %v", err)
+		}
+	}
+	data, err := ioutil.ReadFile(mailer.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Subject: First\r\n") || !strings.Contains(string(data), "Subject: Second\r\n") {
+		t.Errorf("mail file doesn't contain both messages: %q", data)
+	}
+	err = mailer.Send(&Message{To: "owner@example.com\r\nBcc: other@example.com", Subject: "Injected"})
+	if err != ErrInvalidAddress {
+		t.Errorf("Send() with line break in address returned %v, want %v", err, ErrInvalidAddress)
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -8,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"journey/authentication"
 	"journey/compression"
 	"journey/configuration"
 	"journey/database"
@@ -27,6 +31,32 @@ func httpsRedirect(w http.ResponseWriter, r *http.Request, _ map[string]string)
 	return
 }
 
+// resetPassword sets a new random password for the user and prints it.
+func resetPassword(userName string) error {
+	user, err := database.RetrieveUserByName([]byte(userName))
+	if err == sql.ErrNoRows {
+		return errors.New("There is no user named " + userName + ".")
+	} else if err != nil {
+		return err
+	}
+	token, err := authentication.GenerateToken()
+	if err != nil {
+		return err
+	}
+	password := token[:16]
+	hashedPassword, err := authentication.EncryptPassword(password)
+	if err != nil {
+		return err
+	}
+	err = methods.ChangePassword(user.Id, hashedPassword, user.Id)
+	if err != nil {
+		return err
+	}
+	fmt.Println("The new password of " + userName + " is: " + password)
+	fmt.Println("Please log in and change it in the settings.")
+	return nil
+}
+
 func main() {
 	// Setup
 	var err error
@@ -52,6 +82,14 @@ func main() {
 		return
 	}
 
+	// Reset a password from the command line if requested and exit
+	if flags.ResetPassword != "" {
+		if err = resetPassword(flags.ResetPassword); err != nil {
+			log.Fatal("Error: Couldn't reset password:", err)
+		}
+		return
+	}
+
 	// Global blog data
 	if err = methods.GenerateBlog(); err != nil {
 		log.Fatal("Error: Couldn't generate blog data:", err)
@@ -71,6 +109,9 @@ func main() {
 		log.Println("Plugins loaded.")
 	}
 
+	// Publish scheduled posts in the background
+	methods.StartPostScheduler()
+
 	// Start image cache cleanup routine only if image compression is enabled
 	if configuration.Config.CompressImages {
 		// Clean up cache files older than 7 days, run cleanup every 24 hours
@@ -95,12 +136,14 @@ func main() {
 	case "AdminOnly":
 		httpRouter := httptreemux.New()
 		httpsRouter := httptreemux.New()
-		// Blog and pages as http
+		// Blog, pages and content api as http
 		server.InitializeBlog(httpRouter)
 		server.InitializePages(httpRouter)
-		// Blog and pages as https
+		server.InitializeContentApi(httpRouter)
+		// Blog, pages and content api as https
 		server.InitializeBlog(httpsRouter)
 		server.InitializePages(httpsRouter)
+		server.InitializeContentApi(httpsRouter)
 		// Admin as https and http redirect
 		// Add redirection to http router
 		httpRouter.GET("/admin/", httpsRedirect)
@@ -122,9 +165,10 @@ func main() {
 	case "All":
 		httpsRouter := httptreemux.New()
 		httpRouter := httptreemux.New()
-		// Blog and pages as https
+		// Blog, pages and content api as https
 		server.InitializeBlog(httpsRouter)
 		server.InitializePages(httpsRouter)
+		server.InitializeContentApi(httpsRouter)
 		// Admin as https
 		server.InitializeAdmin(httpsRouter)
 		// Add redirection to http router
@@ -144,9 +188,10 @@ func main() {
 		}
 	default: // This is configuration.HttpsUsage == "None"
 		httpRouter := httptreemux.New()
-		// Blog and pages as http
+		// Blog, pages and content api as http
 		server.InitializeBlog(httpRouter)
 		server.InitializePages(httpRouter)
+		server.InitializeContentApi(httpRouter)
 		// Admin as http
 		server.InitializeAdmin(httpRouter)
 		// Start http server
@@ -6,6 +6,7 @@ package plugins
 import (
 	"journey/structure"
 	"journey/structure/methods"
+	"log"
 
 	lua "github.com/yuin/gopher-lua"
 )
@@ -33,6 +34,9 @@ func convertPost(vm *lua.LState, structurePost *structure.Post) *lua.LTable {
 	post.RawSet(lua.LString("date"), lua.LNumber(structurePost.Date.Unix()))
 	post.RawSet(lua.LString("image"), lua.LString(structurePost.Image))
 	post.RawSet(lua.LString("metadescription"), lua.LString(structurePost.MetaDescription))
+	post.RawSet(lua.LString("metatitle"), lua.LString(structurePost.MetaTitle))
+	post.RawSet(lua.LString("customexcerpt"), lua.LString(structurePost.CustomExcerpt))
+	post.RawSet(lua.LString("canonicalurl"), lua.LString(structurePost.CanonicalUrl))
 	return post
 }
 
@@ -54,15 +58,40 @@ func convertUser(vm *lua.LState, structureUser *structure.User) *lua.LTable {
 func convertTags(vm *lua.LState, structureTags []structure.Tag) *lua.LTable {
 	table := make([]*lua.LTable, 0)
 	for index, _ := range structureTags {
-		tag := vm.NewTable()
-		tag.RawSet(lua.LString("id"), lua.LNumber(structureTags[index].Id))
-		tag.RawSet(lua.LString("name"), lua.LString(structureTags[index].Name))
-		tag.RawSet(lua.LString("slug"), lua.LString(structureTags[index].Slug))
+		tag := convertTag(vm, &structureTags[index])
+		// Parents start with the top-level tag, children are only the tags directly below the tag
+		parents := make([]*lua.LTable, 0)
+		for _, parent := range methods.PublicTags(methods.ParentTags(&structureTags[index])) {
+			parents = append(parents, convertTag(vm, &parent))
+		}
+		tag.RawSet(lua.LString("parents"), makeTable(vm, parents))
+		children := make([]*lua.LTable, 0)
+		structureChildren, err := methods.ChildTags(&structureTags[index])
+		if err != nil {
+			log.Println("Couldn't get child tags for plugin:", err)
+		}
+		structureChildren = methods.PublicTags(structureChildren)
+		for childIndex, _ := range structureChildren {
+			children = append(children, convertTag(vm, &structureChildren[childIndex]))
+		}
+		tag.RawSet(lua.LString("children"), makeTable(vm, children))
 		table = append(table, tag)
 	}
 	return makeTable(vm, table)
 }
 
+func convertTag(vm *lua.LState, structureTag *structure.Tag) *lua.LTable {
+	tag := vm.NewTable()
+	tag.RawSet(lua.LString("id"), lua.LNumber(structureTag.Id))
+	tag.RawSet(lua.LString("name"), lua.LString(structureTag.Name))
+	tag.RawSet(lua.LString("slug"), lua.LString(structureTag.Slug))
+	tag.RawSet(lua.LString("description"), lua.LString(structureTag.Description))
+	tag.RawSet(lua.LString("image"), lua.LString(structureTag.Image))
+	tag.RawSet(lua.LString("parentid"), lua.LNumber(structureTag.ParentId))
+	tag.RawSet(lua.LString("path"), lua.LString(methods.TagPath(structureTag)))
+	return tag
+}
+
 func convertBlog(vm *lua.LState, structureBlog *structure.Blog) *lua.LTable {
 	blog := vm.NewTable()
 	blog.RawSet(lua.LString("url"), lua.LString(structureBlog.Url))
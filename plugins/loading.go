@@ -7,6 +7,7 @@ import (
 	"errors"
 	"journey/filenames"
 	"journey/structure"
+	"journey/structure/methods"
 	"log"
 	"os"
 	"path/filepath"
@@ -133,7 +134,7 @@ func setUpVm(vm *lua.LState, helper *structure.Helper, values *structure.Request
 	// Function to get tags by post
 	vm.SetGlobal("getTagsForPost", vm.NewFunction(func(vm *lua.LState) int {
 		postIndex := vm.ToInt(-1)
-		vm.Push(convertTags(vm, values.Posts[postIndex-1].Tags))
+		vm.Push(convertTags(vm, methods.PublicTags(values.Posts[postIndex-1].Tags)))
 		return 1 // Number of results
 	}))
 	// Function to get blog
@@ -2,9 +2,12 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -27,30 +30,48 @@ import (
 )
 
 type JsonPost struct {
-	Id              int64
-	Title           string
-	Slug            string
-	Markdown        string
-	Html            string
-	IsFeatured      bool
-	IsPage          bool
-	IsPublished     bool
-	Image           string
-	MetaDescription string
-	Date            *time.Time
-	Tags            string
+	Id                 int64
+	Title              string
+	Slug               string
+	Markdown           string
+	Html               string
+	IsFeatured         bool
+	IsPage             bool
+	IsPublished        bool
+	IsScheduled        bool
+	PublishAt          *time.Time // Publication date of a scheduled post
+	Image              string
+	MetaDescription    string
+	MetaTitle          string
+	CustomExcerpt      string
+	CanonicalUrl       string
+	OgTitle            string
+	OgDescription      string
+	OgImage            string
+	TwitterTitle       string
+	TwitterDescription string
+	TwitterImage       string
+	CodeInjectionHead  string
+	CodeInjectionFoot  string
+	Date               *time.Time
+	Tags               string
+	Snippet            string     `json:",omitempty"` // Highlighted excerpt of a search result
+	DeletedAt          *time.Time `json:",omitempty"` // Set while the post is in the trash
+	UpdatedAt          *time.Time // Version of the post. Updates that send an older version are rejected, updates without it overwrite the post.
 }
 
 type JsonBlog struct {
-	Url             string
-	Title           string
-	Description     string
-	Logo            string
-	Cover           string
-	Themes          []string
-	ActiveTheme     string
-	PostsPerPage    int64
-	NavigationItems []structure.Navigation
+	Url               string
+	Title             string
+	Description       string
+	Logo              string
+	Cover             string
+	Themes            []string
+	ActiveTheme       string
+	PostsPerPage      int64
+	NavigationItems   []structure.Navigation
+	CodeInjectionHead string
+	CodeInjectionFoot string
 }
 
 type JsonUser struct {
@@ -65,10 +86,14 @@ type JsonUser struct {
 	Location         string
 	Password         string
 	PasswordRepeated string
+	Role             int
+	Status           string
+	LastLogin        *time.Time
 }
 
 type JsonUserId struct {
-	Id int64
+	Id   int64
+	Role int
 }
 
 type JsonImage struct {
@@ -90,16 +115,77 @@ func postLoginHandler(w http.ResponseWriter, r *http.Request, _ map[string]strin
 	name := r.FormValue("name")
 	password := r.FormValue("password")
 	if name != "" && password != "" {
+		// Don't even check the password if the client has to wait
+		delay, locked, err := authentication.LoginDelay(name, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if delay > 0 {
+			log.Println("Blocked login attempt for user " + name)
+			audit(r, &structure.AuditEntry{UserName: name, Action: "login.blocked"})
+			redirectToLogin(w, r, delay, locked)
+			return
+		}
 		if authentication.LoginIsCorrect(name, password) {
-			logInUser(name, w)
+			remember := r.FormValue("remember") != ""
+			userId, err := getUserId(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			// The login only counts as successful after the second factor has been checked
+			twoFactor, enroll, err := twoFactorRequired(userId)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if twoFactor {
+				err = authentication.StartTwoFactorChallenge(userId, remember, enroll, w, r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				http.Redirect(w, r, "/admin/login/twofactor/", 302)
+				return
+			}
+			err = authentication.RecordLoginAttempt(name, true, r)
+			if err != nil {
+				log.Println("Couldn't record login attempt:", err)
+			}
+			logInUser(name, remember, w, r)
 		} else {
 			log.Println("Failed login attempt for user " + name)
+			audit(r, &structure.AuditEntry{UserName: name, Action: "login.failed"})
+			err = authentication.RecordLoginAttempt(name, false, r)
+			if err != nil {
+				log.Println("Couldn't record login attempt:", err)
+			}
+			// Tell the user if this attempt locked the account
+			delay, locked, err = authentication.LoginDelay(name, r)
+			if err == nil && locked {
+				log.Println("Locked login for user " + name)
+				redirectToLogin(w, r, delay, locked)
+				return
+			}
+			http.Redirect(w, r, "/admin/login/?error=invalid", 302)
+			return
 		}
 	}
 	http.Redirect(w, r, "/admin/", 302)
 	return
 }
 
+// redirectToLogin sends the client back to the login page, which explains how long to wait before the next attempt.
+func redirectToLogin(w http.ResponseWriter, r *http.Request, delay time.Duration, locked bool) {
+	reason := "wait"
+	if locked {
+		reason = "locked"
+	}
+	seconds := int64(math.Ceil(delay.Seconds()))
+	http.Redirect(w, r, "/admin/login/?error="+reason+"&wait="+strconv.FormatInt(seconds, 10), 302)
+}
+
 // getRegistrationHandler serves the registration form for new users.
 func getRegistrationHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	if database.RetrieveUsersCount() == 0 {
@@ -112,7 +198,7 @@ func getRegistrationHandler(w http.ResponseWriter, r *http.Request, _ map[string
 
 // postRegistrationHandler processes registration form submissions and creates new users.
 func postRegistrationHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	if database.RetrieveUsersCount() == 0 { // TODO: Or check if authenticated user is admin when adding users from inside the admin area
+	if database.RetrieveUsersCount() == 0 {
 		name := r.FormValue("name")
 		email := r.FormValue("email")
 		password := r.FormValue("password")
@@ -128,21 +214,28 @@ func postRegistrationHandler(w http.ResponseWriter, r *http.Request, _ map[strin
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
+			audit(r, &structure.AuditEntry{UserId: user.Id, UserName: name, Action: "user.register", TargetType: "user", TargetId: user.Id, TargetName: name})
 			http.Redirect(w, r, "/admin/", 302)
 			return
 		}
 		http.Redirect(w, r, "/admin/", 302)
 		return
 	} else {
-		// TODO: Handle creation of other users (not just the first one)
-		http.Error(w, "Not implemented yet.", http.StatusInternalServerError)
+		// All other users join the blog through invitations or are created from inside the admin area
+		http.Error(w, "Registration is closed. Please ask an administrator of this blog for an invitation.", http.StatusForbidden)
 		return
 	}
 }
 
 // logoutHandler clears the user session and redirects to login.
 func logoutHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	authentication.ClearSession(w)
+	if session, err := authentication.GetSession(r); err == nil {
+		audit(r, &structure.AuditEntry{UserId: session.UserId, UserName: string(session.UserName), Action: "logout"})
+	}
+	err := authentication.ClearSession(w, r)
+	if err != nil {
+		log.Println("Couldn't delete session:", err)
+	}
 	http.Redirect(w, r, "/admin/login/", 302)
 	return
 }
@@ -155,6 +248,8 @@ func adminHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	} else {
 		userName := authentication.GetUserName(r)
 		if userName != "" {
+			// Renew the csrf cookie, it doesn't outlive the browser like remembered sessions do
+			authentication.SetCsrfCookie(w, r)
 			http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "admin.html"))
 			return
 		} else {
@@ -181,6 +276,11 @@ func adminFileHandler(w http.ResponseWriter, r *http.Request, params map[string]
 func apiPostsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		number := params["number"]
 		page, err := strconv.Atoi(number)
 		if err != nil || page < 1 {
@@ -188,7 +288,45 @@ func apiPostsHandler(w http.ResponseWriter, r *http.Request, params map[string]s
 			return
 		}
 		postsPerPage := int64(15)
-		posts, err := database.RetrievePostsForApi(postsPerPage, ((int64(page) - 1) * postsPerPage))
+		var posts []structure.Post
+		// Search posts if a query is given
+		if query := r.URL.Query().Get("q"); query != "" {
+			// Authors only get to see their own posts
+			authorId := int64(0)
+			if !authentication.CanSeeAllPosts(user) {
+				authorId = user.Id
+			}
+			posts, snippets, err := methods.SearchPosts(query, false, authorId, postsPerPage, ((int64(page) - 1) * postsPerPage))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			jsonPosts := postsToJson(posts)
+			for index, _ := range *jsonPosts {
+				(*jsonPosts)[index].Snippet = string(snippets[index])
+			}
+			json, err := json.Marshal(jsonPosts)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(json)
+			return
+		}
+		if tagSlug := r.URL.Query().Get("tag"); tagSlug != "" {
+			// Filter by tag, e.g. an internal tag like #needs-review (slug hash-needs-review)
+			authorId := int64(0)
+			if !authentication.CanSeeAllPosts(user) {
+				authorId = user.Id
+			}
+			posts, err = database.RetrievePostsForApiByTag(tagSlug, authorId, postsPerPage, ((int64(page) - 1) * postsPerPage))
+		} else if authentication.CanSeeAllPosts(user) {
+			posts, err = database.RetrievePostsForApi(postsPerPage, ((int64(page) - 1) * postsPerPage))
+		} else {
+			// Authors only get to see their own posts
+			posts, err = database.RetrievePostsForApiByUser(user.Id, postsPerPage, ((int64(page) - 1) * postsPerPage))
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -211,6 +349,11 @@ func apiPostsHandler(w http.ResponseWriter, r *http.Request, params map[string]s
 func getApiPostHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		id := params["id"]
 		// Get post
 		postId, err := strconv.ParseInt(id, 10, 64)
@@ -223,6 +366,10 @@ func getApiPostHandler(w http.ResponseWriter, r *http.Request, params map[string
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !authentication.CanEditPost(user, post) {
+			http.Error(w, "You don't have permission to access this post.", http.StatusForbidden)
+			return
+		}
 		json, err := json.Marshal(postToJson(post))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -260,13 +407,24 @@ func postApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]str
 		} else {
 			postSlug = slug.Generate(json.Title, "posts")
 		}
-		currentTime := date.GetCurrentTime()
-		post := structure.Post{Title: []byte(json.Title), Slug: postSlug, Markdown: []byte(json.Markdown), Html: conversion.GenerateHtmlFromMarkdown([]byte(json.Markdown)), IsFeatured: json.IsFeatured, IsPage: json.IsPage, IsPublished: json.IsPublished, MetaDescription: []byte(json.MetaDescription), Image: []byte(json.Image), Date: &currentTime, Tags: methods.GenerateTagsFromCommaString(json.Tags), Author: &structure.User{Id: userId}}
+		postDate, err := getPostDate(&json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		post := structure.Post{Title: []byte(json.Title), Slug: postSlug, Markdown: []byte(json.Markdown), Html: conversion.GenerateHtmlFromMarkdown([]byte(json.Markdown)), IsFeatured: json.IsFeatured, IsPage: json.IsPage, IsPublished: json.IsPublished, IsScheduled: json.IsScheduled, MetaDescription: []byte(json.MetaDescription), Image: []byte(json.Image), Date: postDate, Tags: methods.GenerateTagsFromCommaString(json.Tags), Author: &structure.User{Id: userId}}
+		err = setPostMetadata(&post, &json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		err = methods.SavePost(&post)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		_, summary := auditChanges(map[string]string{}, auditPostSummary(&post))
+		audit(r, &structure.AuditEntry{UserId: userId, UserName: userName, Action: "post.create", TargetType: "post", TargetId: post.Id, TargetName: json.Title, SummaryAfter: summary})
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Post created!"))
 		return
@@ -280,7 +438,7 @@ func postApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]str
 func patchApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
-		userId, err := getUserId(userName)
+		user, err := getUser(userName)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -300,20 +458,80 @@ func patchApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !authentication.CanEditPost(user, post) {
+			http.Error(w, "You don't have permission to change this post.", http.StatusForbidden)
+			return
+		}
+		if post.DeletedAt != nil {
+			http.Error(w, "This post is in the trash. Restore it to make changes.", http.StatusConflict)
+			return
+		}
+		// Somebody else saved the post since the client loaded it. Send the current version so the client can resolve the conflict.
+		// Clients that don't send the version they loaded overwrite the post regardless of other changes.
+		if json.UpdatedAt != nil && post.UpdatedAt != nil && !json.UpdatedAt.Equal(*post.UpdatedAt) {
+			writePostConflict(w, post)
+			return
+		}
 		if json.Slug != post.Slug { // Check if user has submitted a custom slug
 			postSlug = slug.Generate(json.Slug, "posts")
 		} else {
 			postSlug = post.Slug
 		}
-		currentTime := date.GetCurrentTime()
-		*post = structure.Post{Id: json.Id, Title: []byte(json.Title), Slug: postSlug, Markdown: []byte(json.Markdown), Html: conversion.GenerateHtmlFromMarkdown([]byte(json.Markdown)), IsFeatured: json.IsFeatured, IsPage: json.IsPage, IsPublished: json.IsPublished, MetaDescription: []byte(json.MetaDescription), Image: []byte(json.Image), Date: &currentTime, Tags: methods.GenerateTagsFromCommaString(json.Tags), Author: &structure.User{Id: userId}}
+		postDate, err := getPostDate(&json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		oldSummary := auditPostSummary(post)
+		oldSlug := post.Slug
+		wasPublished := post.IsPublished
+		var previousUpdatedAt *time.Time
+		if json.UpdatedAt != nil {
+			previousUpdatedAt = post.UpdatedAt
+		}
+		*post = structure.Post{Id: json.Id, Title: []byte(json.Title), Slug: postSlug, Markdown: []byte(json.Markdown), Html: conversion.GenerateHtmlFromMarkdown([]byte(json.Markdown)), IsFeatured: json.IsFeatured, IsPage: json.IsPage, IsPublished: json.IsPublished, IsScheduled: json.IsScheduled, MetaDescription: []byte(json.MetaDescription), Image: []byte(json.Image), Date: postDate, Tags: methods.GenerateTagsFromCommaString(json.Tags), Author: &structure.User{Id: user.Id}, UpdatedAt: previousUpdatedAt}
+		err = setPostMetadata(post, &json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		err = methods.UpdatePost(post)
+		if err == methods.ErrPostChanged {
+			// The post was saved by somebody else between the check above and the update
+			post, err = database.RetrievePostById(json.Id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writePostConflict(w, post)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Links to the old url of a published post should keep working
+		if wasPublished && postSlug != oldSlug {
+			err = methods.SaveSlugRedirect(oldSlug, postSlug, user.Id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		// The unsaved changes of the user are part of the post now
+		err = database.DeleteAutosave(post.Id, user.Id)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Post updated!"))
+		before, after := auditChanges(oldSummary, auditPostSummary(post))
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "post.update", TargetType: "post", TargetId: post.Id, TargetName: json.Title, SummaryBefore: before, SummaryAfter: after})
+		// Respond with the updated post so the client knows the new version
+		post, err = database.RetrievePostById(post.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJsonPost(w, http.StatusOK, post)
 		return
 	} else {
 		http.Error(w, "Not logged in!", http.StatusInternalServerError)
@@ -325,6 +543,11 @@ func patchApiPostHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 func deleteApiPostHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		id := params["id"]
 		// Delete post
 		postId, err := strconv.ParseInt(id, 10, 64)
@@ -332,13 +555,36 @@ func deleteApiPostHandler(w http.ResponseWriter, r *http.Request, params map[str
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		err = methods.DeletePost(postId)
+		post, err := database.RetrievePostById(postId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanEditPost(user, post) {
+			http.Error(w, "You don't have permission to delete this post.", http.StatusForbidden)
+			return
+		}
+		// Deleting a post moves it to the trash. Deleting it from the trash removes it permanently.
+		if post.DeletedAt != nil {
+			err = methods.DeletePost(postId)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "post.purge", TargetType: "post", TargetId: post.Id, TargetName: string(post.Title)})
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Post deleted permanently!"))
+			return
+		}
+		err = methods.TrashPost(postId, user.Id)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		summary, _ := auditChanges(auditPostSummary(post), map[string]string{})
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "post.trash", TargetType: "post", TargetId: post.Id, TargetName: string(post.Title), SummaryBefore: summary})
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Post deleted!"))
+		w.Write([]byte("Post moved to the trash!"))
 		return
 	} else {
 		http.Error(w, "Not logged in!", http.StatusInternalServerError)
@@ -350,6 +596,11 @@ func deleteApiPostHandler(w http.ResponseWriter, r *http.Request, params map[str
 func apiUploadHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
+		userId, err := getUserId(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		// Create multipart reader
 		reader, err := r.MultipartReader()
 		if err != nil {
@@ -395,6 +646,7 @@ func apiUploadHandler(w http.ResponseWriter, r *http.Request, _ map[string]strin
 			// Make sure to always use "/" as path separator (to make a valid url that we can use on the blog)
 			filePath = filepath.ToSlash(filePath)
 			allFilePaths = append(allFilePaths, filePath)
+			audit(r, &structure.AuditEntry{UserId: userId, UserName: userName, Action: "image.upload", TargetType: "image", TargetName: filePath})
 		}
 		json, err := json.Marshal(allFilePaths)
 		if err != nil {
@@ -468,11 +720,21 @@ func apiImagesHandler(w http.ResponseWriter, r *http.Request, params map[string]
 // API function to delete an image by its filename.
 func deleteApiImageHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	userName := authentication.GetUserName(r)
-	if userName != "" { // TODO: Check if the user has permissions to delete the image
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Images could be used in posts of other users
+		if !authentication.CanDeleteImages(user) {
+			http.Error(w, "You don't have permission to delete images.", http.StatusForbidden)
+			return
+		}
 		// Get the file name from the json data
 		decoder := json.NewDecoder(r.Body)
 		var json JsonImage
-		err := decoder.Decode(&json)
+		err = decoder.Decode(&json)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -490,6 +752,7 @@ func deleteApiImageHandler(w http.ResponseWriter, r *http.Request, _ map[string]
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "image.delete", TargetType: "image", TargetName: json.Filename})
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Image deleted!"))
 		return
@@ -525,11 +788,15 @@ func getApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]stri
 func patchApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
-		userId, err := getUserId(userName)
+		user, err := getUser(userName)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to change the blog settings.", http.StatusForbidden)
+			return
+		}
 		decoder := json.NewDecoder(r.Body)
 		var json JsonBlog
 		err = decoder.Decode(&json)
@@ -557,8 +824,12 @@ func patchApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		tempBlog := structure.Blog{Url: []byte(configuration.Config.Url), Title: []byte(json.Title), Description: []byte(json.Description), Logo: []byte(json.Logo), Cover: []byte(json.Cover), AssetPath: []byte("/assets/"), PostCount: blog.PostCount, PostsPerPage: json.PostsPerPage, ActiveTheme: json.ActiveTheme, NavigationItems: json.NavigationItems}
-		err = methods.UpdateBlog(&tempBlog, userId)
+		tempBlog := structure.Blog{Url: []byte(configuration.Config.Url), Title: []byte(json.Title), Description: []byte(json.Description), Logo: []byte(json.Logo), Cover: []byte(json.Cover), AssetPath: []byte("/assets/"), PostCount: blog.PostCount, PostsPerPage: json.PostsPerPage, ActiveTheme: json.ActiveTheme, NavigationItems: json.NavigationItems, CodeInjectionHead: []byte(json.CodeInjectionHead), CodeInjectionFoot: []byte(json.CodeInjectionFoot)}
+		err = methods.UpdateBlog(&tempBlog, user.Id)
+		if err == nil {
+			before, after := auditChanges(auditBlogSummary(blog), auditBlogSummary(&tempBlog))
+			audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "blog.update", TargetType: "blog", TargetName: json.Title, SummaryBefore: before, SummaryAfter: after})
+		}
 		// Check if active theme setting has been changed, if so, generate templates from new theme
 		if tempBlog.ActiveTheme != blog.ActiveTheme {
 			err = templates.Generate()
@@ -586,7 +857,7 @@ func patchApiBlogHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 func getApiUserHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
-		userId, err := getUserId(userName)
+		authenticatedUser, err := getUser(userName)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -596,15 +867,17 @@ func getApiUserHandler(w http.ResponseWriter, r *http.Request, params map[string
 		if err != nil || userIdToGet < 1 {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
-		} else if userIdToGet != userId { // Make sure the authenticated user is only accessing his/her own data. TODO: Make sure the user is admin when multiple users have been introduced
-			http.Error(w, "You don't have permission to access this data.", http.StatusForbidden)
-			return
 		}
 		user, err := database.RetrieveUser(userIdToGet)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// Make sure the authenticated user is allowed to access the data of this user
+		if !authentication.CanEditUser(authenticatedUser, user) {
+			http.Error(w, "You don't have permission to access this data.", http.StatusForbidden)
+			return
+		}
 		userJson := userToJson(user)
 		json, err := json.Marshal(userJson)
 		if err != nil {
@@ -624,11 +897,12 @@ func getApiUserHandler(w http.ResponseWriter, r *http.Request, params map[string
 func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
-		userId, err := getUserId(userName)
+		authenticatedUser, err := getUser(userName)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		userId := authenticatedUser.Id
 		decoder := json.NewDecoder(r.Body)
 		var json JsonUser
 		err = decoder.Decode(&json)
@@ -640,9 +914,6 @@ func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 		if json.Id < 1 {
 			http.Error(w, "Wrong user id.", http.StatusInternalServerError)
 			return
-		} else if userId != json.Id { // Make sure the authenticated user is only changing his/her own data. TODO: Make sure the user is admin when multiple users have been introduced
-			http.Error(w, "You don't have permission to change this data.", http.StatusInternalServerError)
-			return
 		}
 		// Get old user data to compare
 		tempUser, err := database.RetrieveUser(json.Id)
@@ -650,10 +921,25 @@ func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// Make sure the authenticated user is allowed to change the data of this user
+		if !authentication.CanEditUser(authenticatedUser, tempUser) {
+			http.Error(w, "You don't have permission to change this data.", http.StatusForbidden)
+			return
+		}
+		// A leaked api token must not be enough to take over the account
+		if json.Password != "" && authentication.HasApiToken(r) {
+			http.Error(w, "Passwords can't be changed with an api token.", http.StatusForbidden)
+			return
+		}
 		// Make sure user email is provided
 		if json.Email == "" {
 			json.Email = string(tempUser.Email)
 		}
+		// The email address receives password reset links, so it's as sensitive as the password
+		if json.Email != string(tempUser.Email) && authentication.HasApiToken(r) {
+			http.Error(w, "Email addresses can't be changed with an api token.", http.StatusForbidden)
+			return
+		}
 		// Make sure user name is provided
 		if json.Name == "" {
 			json.Name = string(tempUser.Name)
@@ -686,21 +972,30 @@ func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// The author sitemap lists the user by slug
+		templates.InvalidateSitemap()
+		before, after := auditChanges(auditUserSummary(tempUser), auditUserSummary(&user))
+		if before != "" || after != "" {
+			audit(r, &structure.AuditEntry{UserId: userId, UserName: userName, Action: "user.update", TargetType: "user", TargetId: user.Id, TargetName: json.Name, SummaryBefore: before, SummaryAfter: after})
+		}
 		if json.Password != "" && (json.Password == json.PasswordRepeated) { // Update password if a new one was submitted
 			encryptedPassword, err := authentication.EncryptPassword(json.Password)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			err = database.UpdateUserPassword(user.Id, encryptedPassword, date.GetCurrentTime(), json.Id)
+			err = database.UpdateUserPassword(user.Id, encryptedPassword, date.GetCurrentTime(), userId)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-		}
-		// Check if the user name was changed. If so, update the session cookie to the new user name.
-		if json.Name != string(tempUser.Name) {
-			logInUser(json.Name, w)
+			// Log out everywhere else with the old password
+			err = authentication.RevokeOtherSessions(user.Id, r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			audit(r, &structure.AuditEntry{UserId: userId, UserName: userName, Action: "user.password", TargetType: "user", TargetId: user.Id, TargetName: json.Name})
 		}
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("User settings updated!"))
@@ -715,12 +1010,12 @@ func patchApiUserHandler(w http.ResponseWriter, r *http.Request, _ map[string]st
 func getApiUserIdHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	userName := authentication.GetUserName(r)
 	if userName != "" {
-		userId, err := getUserId(userName)
+		user, err := getUser(userName)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		jsonUserId := JsonUserId{Id: userId}
+		jsonUserId := JsonUserId{Id: user.Id, Role: user.Role}
 		json, err := json.Marshal(jsonUserId)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -743,18 +1038,70 @@ func getUserId(userName string) (int64, error) {
 	return user.Id, nil
 }
 
-func logInUser(name string, w http.ResponseWriter) {
-	authentication.SetSession(name, w)
+func getUser(userName string) (*structure.User, error) {
+	return database.RetrieveUserByName([]byte(userName))
+}
+
+func logInUser(name string, remember bool, w http.ResponseWriter, r *http.Request) {
 	userId, err := getUserId(name)
 	if err != nil {
 		log.Println("Couldn't get id of logged in user:", err)
+		return
+	}
+	err = authentication.SetSession(userId, remember, w, r)
+	if err != nil {
+		log.Println("Couldn't create session for user:", err)
+		return
 	}
+	audit(r, &structure.AuditEntry{UserId: userId, UserName: name, Action: "login"})
 	err = database.UpdateLastLogin(date.GetCurrentTime(), userId)
 	if err != nil {
 		log.Println("Couldn't update last login date of a user:", err)
 	}
 }
 
+// getPostDate returns the publication date for a scheduled post or the current time otherwise.
+// To cancel a schedule the post is submitted with IsScheduled set to false.
+func getPostDate(json *JsonPost) (*time.Time, error) {
+	currentTime := date.GetCurrentTime()
+	if !json.IsScheduled {
+		return &currentTime, nil
+	}
+	if json.PublishAt == nil {
+		return nil, errors.New("A publication date is required to schedule a post.")
+	}
+	if !json.PublishAt.After(currentTime) {
+		return nil, errors.New("The publication date of a scheduled post has to be in the future.")
+	}
+	// A scheduled post is not published until the scheduler publishes it
+	json.IsPublished = false
+	publishAt := json.PublishAt.UTC()
+	return &publishAt, nil
+}
+
+// setPostMetadata copies the search engine and social network fields and the code injection to the post. The canonical url has to be an absolute http(s) url or a path on the blog.
+func setPostMetadata(post *structure.Post, json *JsonPost) error {
+	canonicalUrl := strings.TrimSpace(json.CanonicalUrl)
+	if canonicalUrl != "" && !isLocalPath(canonicalUrl) {
+		parsedUrl, err := url.Parse(canonicalUrl)
+		if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+			return errors.New("The canonical url has to start with http://, https:// or /.")
+		}
+	}
+	post.MetaTitle = []byte(json.MetaTitle)
+	post.CustomExcerpt = []byte(json.CustomExcerpt)
+	post.CanonicalUrl = []byte(canonicalUrl)
+	post.OgTitle = []byte(json.OgTitle)
+	post.OgDescription = []byte(json.OgDescription)
+	post.OgImage = []byte(json.OgImage)
+	post.TwitterTitle = []byte(json.TwitterTitle)
+	post.TwitterDescription = []byte(json.TwitterDescription)
+	post.TwitterImage = []byte(json.TwitterImage)
+	post.CodeInjectionHead = []byte(json.CodeInjectionHead)
+	post.CodeInjectionFoot = []byte(json.CodeInjectionFoot)
+	return nil
+}
+
 func postsToJson(posts []structure.Post) *[]JsonPost {
 	jsonPosts := make([]JsonPost, len(posts))
 	for index, _ := range posts {
@@ -773,14 +1120,31 @@ func postToJson(post *structure.Post) *JsonPost {
 	jsonPost.IsFeatured = post.IsFeatured
 	jsonPost.IsPage = post.IsPage
 	jsonPost.IsPublished = post.IsPublished
+	jsonPost.IsScheduled = post.IsScheduled
+	if post.IsScheduled {
+		jsonPost.PublishAt = post.Date
+	}
 	jsonPost.MetaDescription = string(post.MetaDescription)
+	jsonPost.MetaTitle = string(post.MetaTitle)
+	jsonPost.CustomExcerpt = string(post.CustomExcerpt)
+	jsonPost.CanonicalUrl = string(post.CanonicalUrl)
+	jsonPost.OgTitle = string(post.OgTitle)
+	jsonPost.OgDescription = string(post.OgDescription)
+	jsonPost.OgImage = string(post.OgImage)
+	jsonPost.TwitterTitle = string(post.TwitterTitle)
+	jsonPost.TwitterDescription = string(post.TwitterDescription)
+	jsonPost.TwitterImage = string(post.TwitterImage)
+	jsonPost.CodeInjectionHead = string(post.CodeInjectionHead)
+	jsonPost.CodeInjectionFoot = string(post.CodeInjectionFoot)
 	jsonPost.Image = string(post.Image)
 	jsonPost.Date = post.Date
 	tags := make([]string, len(post.Tags))
 	for index, _ := range post.Tags {
-		tags[index] = string(post.Tags[index].Name)
+		tags[index] = methods.TagPath(&post.Tags[index])
 	}
 	jsonPost.Tags = strings.Join(tags, ",")
+	jsonPost.DeletedAt = post.DeletedAt
+	jsonPost.UpdatedAt = post.UpdatedAt
 	return &jsonPost
 }
 
@@ -795,6 +1159,8 @@ func blogToJson(blog *structure.Blog) *JsonBlog {
 	jsonBlog.Themes = templates.GetAllThemes()
 	jsonBlog.ActiveTheme = blog.ActiveTheme
 	jsonBlog.NavigationItems = blog.NavigationItems
+	jsonBlog.CodeInjectionHead = string(blog.CodeInjectionHead)
+	jsonBlog.CodeInjectionFoot = string(blog.CodeInjectionFoot)
 	return &jsonBlog
 }
 
@@ -809,6 +1175,9 @@ func userToJson(user *structure.User) *JsonUser {
 	jsonUser.Bio = string(user.Bio)
 	jsonUser.Website = string(user.Website)
 	jsonUser.Location = string(user.Location)
+	jsonUser.Role = user.Role
+	jsonUser.Status = user.Status
+	jsonUser.LastLogin = user.LastLogin
 	return &jsonUser
 }
 
@@ -816,10 +1185,19 @@ func InitializeAdmin(router *httptreemux.TreeMux) {
 	// For admin panel
 	router.GET("/admin/", adminHandler)
 	router.GET("/admin/login/", getLoginHandler)
-	router.POST("/admin/login/", postLoginHandler)
+	router.POST("/admin/login/", originProtected(postLoginHandler))
+	router.GET("/admin/login/twofactor/", getTwoFactorLoginHandler)
+	router.GET("/admin/login/twofactor/info", getTwoFactorLoginInfoHandler)
+	router.POST("/admin/login/twofactor/", originProtected(postTwoFactorLoginHandler))
+	router.GET("/admin/forgot/", getForgotPasswordHandler)
+	router.POST("/admin/forgot/", originProtected(postForgotPasswordHandler))
+	router.GET("/admin/reset/:token/", getPasswordResetHandler)
+	router.POST("/admin/reset/:token/", originProtected(postPasswordResetHandler))
 	router.GET("/admin/register/", getRegistrationHandler)
-	router.POST("/admin/register/", postRegistrationHandler)
+	router.POST("/admin/register/", originProtected(postRegistrationHandler))
 	router.GET("/admin/logout/", logoutHandler)
+	router.GET("/admin/invitation/:token/", getInvitationHandler)
+	router.POST("/admin/invitation/:token/", originProtected(postInvitationHandler))
 	router.GET("/admin/*filepath", adminFileHandler)
 
 	// For admin API (no trailing slash)
@@ -827,20 +1205,82 @@ func InitializeAdmin(router *httptreemux.TreeMux) {
 	router.GET("/admin/api/posts/:number", apiPostsHandler)
 	// Post
 	router.GET("/admin/api/post/:id", getApiPostHandler)
-	router.POST("/admin/api/post", postApiPostHandler)
-	router.PATCH("/admin/api/post", patchApiPostHandler)
-	router.DELETE("/admin/api/post/:id", deleteApiPostHandler)
+	router.POST("/admin/api/post", csrfProtected(postApiPostHandler))
+	router.PATCH("/admin/api/post", csrfProtected(patchApiPostHandler))
+	router.DELETE("/admin/api/post/:id", csrfProtected(deleteApiPostHandler))
+	// Revisions
+	router.GET("/admin/api/post/:id/revisions", apiPostRevisionsHandler)
+	router.GET("/admin/api/post/:id/diff/:from/:to", apiPostRevisionDiffHandler)
+	router.POST("/admin/api/post/:id/restore/:revision", csrfProtected(postApiPostRevisionRestoreHandler))
+	// Autosave
+	router.GET("/admin/api/post/:id/autosave", getApiPostAutosaveHandler)
+	router.POST("/admin/api/post/:id/autosave", csrfProtected(postApiPostAutosaveHandler))
+	router.DELETE("/admin/api/post/:id/autosave", csrfProtected(deleteApiPostAutosaveHandler))
+	// Preview links
+	router.GET("/admin/api/post/:id/preview", getApiPostPreviewHandler)
+	router.POST("/admin/api/post/:id/preview", csrfProtected(postApiPostPreviewHandler))
+	router.DELETE("/admin/api/post/:id/preview", csrfProtected(deleteApiPostPreviewHandler))
+	// Trash
+	router.GET("/admin/api/trash/:number", apiTrashHandler)
+	router.DELETE("/admin/api/trash", csrfProtected(deleteApiTrashHandler))
+	router.POST("/admin/api/post/:id/restore", csrfProtected(postApiPostRestoreHandler))
 	// Upload
-	router.POST("/admin/api/upload", apiUploadHandler)
+	router.POST("/admin/api/upload", csrfProtected(apiUploadHandler))
 	// Images
 	router.GET("/admin/api/images/:number", apiImagesHandler)
-	router.DELETE("/admin/api/image", deleteApiImageHandler)
+	router.DELETE("/admin/api/image", csrfProtected(deleteApiImageHandler))
 	// Blog
 	router.GET("/admin/api/blog", getApiBlogHandler)
-	router.PATCH("/admin/api/blog", patchApiBlogHandler)
+	router.PATCH("/admin/api/blog", csrfProtected(patchApiBlogHandler))
+	// Tags
+	router.GET("/admin/api/tags", apiTagsHandler)
+	router.POST("/admin/api/tags", csrfProtected(postApiTagHandler))
+	router.GET("/admin/api/tag/:id", getApiTagHandler)
+	router.PATCH("/admin/api/tag/:id", csrfProtected(patchApiTagHandler))
+	router.DELETE("/admin/api/tag/:id", csrfProtected(deleteApiTagHandler))
+	router.POST("/admin/api/tag/:id/merge", csrfProtected(postApiTagMergeHandler))
+	router.DELETE("/admin/api/tags/unused", csrfProtected(deleteApiUnusedTagsHandler))
 	// User
 	router.GET("/admin/api/user/:id", getApiUserHandler)
-	router.PATCH("/admin/api/user", patchApiUserHandler)
+	router.PATCH("/admin/api/user", csrfProtected(patchApiUserHandler))
 	// User id
 	router.GET("/admin/api/userid", getApiUserIdHandler)
+	// Sessions
+	router.GET("/admin/api/sessions", sessionRequired(apiSessionsHandler))
+	router.DELETE("/admin/api/sessions", sessionRequired(csrfProtected(deleteApiSessionsHandler)))
+	router.DELETE("/admin/api/session/:id", sessionRequired(csrfProtected(deleteApiSessionHandler)))
+	// Two-factor authentication
+	router.GET("/admin/api/twofactor", sessionRequired(getApiTwoFactorHandler))
+	router.POST("/admin/api/twofactor/setup", sessionRequired(csrfProtected(postApiTwoFactorSetupHandler)))
+	router.POST("/admin/api/twofactor/enable", sessionRequired(csrfProtected(postApiTwoFactorEnableHandler)))
+	router.POST("/admin/api/twofactor/disable", sessionRequired(csrfProtected(postApiTwoFactorDisableHandler)))
+	router.POST("/admin/api/twofactor/recoverycodes", sessionRequired(csrfProtected(postApiRecoveryCodesHandler)))
+	router.PATCH("/admin/api/twofactor/requirement", sessionRequired(csrfProtected(patchApiTwoFactorRequirementHandler)))
+	router.DELETE("/admin/api/user/:id/twofactor", sessionRequired(csrfProtected(deleteApiUserTwoFactorHandler)))
+	// Personal api tokens
+	router.GET("/admin/api/tokens", sessionRequired(apiApiTokensHandler))
+	router.POST("/admin/api/tokens", sessionRequired(csrfProtected(postApiApiTokenHandler)))
+	router.DELETE("/admin/api/token/:id", sessionRequired(csrfProtected(deleteApiApiTokenHandler)))
+	// Content api keys
+	router.GET("/admin/api/apikeys", sessionRequired(apiApiKeysHandler))
+	router.POST("/admin/api/apikeys", sessionRequired(csrfProtected(postApiApiKeyHandler)))
+	router.DELETE("/admin/api/apikey/:id", sessionRequired(csrfProtected(deleteApiApiKeyHandler)))
+	// Users
+	router.GET("/admin/api/users", apiUsersHandler)
+	router.POST("/admin/api/users", sessionRequired(csrfProtected(postApiUsersHandler)))
+	router.PATCH("/admin/api/user/:id/role", sessionRequired(csrfProtected(patchApiUserRoleHandler)))
+	router.PATCH("/admin/api/user/:id/status", sessionRequired(csrfProtected(patchApiUserStatusHandler)))
+	router.DELETE("/admin/api/user/:id", sessionRequired(csrfProtected(deleteApiUserHandler)))
+	// Invites
+	router.GET("/admin/api/invites", sessionRequired(apiInvitesHandler))
+	router.POST("/admin/api/invite", sessionRequired(csrfProtected(postApiInviteHandler)))
+	router.DELETE("/admin/api/invite/:id", sessionRequired(csrfProtected(deleteApiInviteHandler)))
+	// Lockouts
+	router.GET("/admin/api/lockouts", apiLockoutsHandler)
+	// Redirects
+	router.GET("/admin/api/redirects", apiRedirectsHandler)
+	router.POST("/admin/api/redirects", csrfProtected(postApiRedirectHandler))
+	router.DELETE("/admin/api/redirect/:id", csrfProtected(deleteApiRedirectHandler))
+	// Audit log
+	router.GET("/admin/api/audit/:number", apiAuditLogHandler)
 }
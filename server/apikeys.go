@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+type JsonApiKey struct {
+	Id        int64
+	Name      string
+	Secret    string
+	CreatedAt *time.Time
+}
+
+// API function to get all content api keys
+func apiApiKeysHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to manage api keys.", http.StatusForbidden)
+			return
+		}
+		apiKeys, err := database.RetrieveApiKeys()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonApiKeys := make([]JsonApiKey, len(apiKeys))
+		for index, _ := range apiKeys {
+			jsonApiKeys[index] = *apiKeyToJson(&apiKeys[index])
+		}
+		json, err := json.Marshal(jsonApiKeys)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to create a new content api key
+func postApiApiKeyHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to manage api keys.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var requestedApiKey JsonApiKey
+		err = decoder.Decode(&requestedApiKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		requestedApiKey.Name = strings.TrimSpace(requestedApiKey.Name)
+		if requestedApiKey.Name == "" {
+			http.Error(w, "Name is required.", http.StatusBadRequest)
+			return
+		}
+		secret, err := authentication.GenerateToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		apiKey := structure.ApiKey{Name: []byte(requestedApiKey.Name), Secret: secret, CreatedBy: user.Id}
+		err = methods.SaveApiKey(&apiKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "apikey.create", TargetType: "apikey", TargetId: apiKey.Id, TargetName: requestedApiKey.Name})
+		json, err := json.Marshal(apiKeyToJson(&apiKey))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to revoke a content api key
+func deleteApiApiKeyHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to manage api keys.", http.StatusForbidden)
+			return
+		}
+		apiKeyId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || apiKeyId < 1 {
+			http.Error(w, "Wrong api key id.", http.StatusInternalServerError)
+			return
+		}
+		err = methods.DeleteApiKey(apiKeyId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "apikey.delete", TargetType: "apikey", TargetId: apiKeyId})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Api key deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+func apiKeyToJson(apiKey *structure.ApiKey) *JsonApiKey {
+	var jsonApiKey JsonApiKey
+	jsonApiKey.Id = apiKey.Id
+	jsonApiKey.Name = string(apiKey.Name)
+	jsonApiKey.Secret = apiKey.Secret
+	jsonApiKey.CreatedAt = apiKey.CreatedAt
+	return &jsonApiKey
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+type JsonApiToken struct {
+	Id         int64
+	Name       string
+	Scope      string
+	Token      string `json:",omitempty"` // Only set when the token is created since we don't store the token itself
+	CreatedAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// API function to get the api tokens of the logged in user
+func apiApiTokensHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		apiTokens, err := database.RetrieveApiTokensByUserId(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonApiTokens := make([]JsonApiToken, len(apiTokens))
+		for index, _ := range apiTokens {
+			jsonApiTokens[index] = *apiTokenToJson(&apiTokens[index])
+		}
+		json, err := json.Marshal(jsonApiTokens)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to create a new api token for the logged in user. The token is only returned in this response.
+func postApiApiTokenHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var requestedApiToken JsonApiToken
+		err = decoder.Decode(&requestedApiToken)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		requestedApiToken.Name = strings.TrimSpace(requestedApiToken.Name)
+		if requestedApiToken.Name == "" {
+			http.Error(w, "Name is required.", http.StatusBadRequest)
+			return
+		}
+		if requestedApiToken.Scope != structure.ApiTokenScopeRead && requestedApiToken.Scope != structure.ApiTokenScopeFull {
+			http.Error(w, "Invalid scope.", http.StatusBadRequest)
+			return
+		}
+		token, err := authentication.GenerateToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		apiToken := structure.ApiToken{UserId: user.Id, UserName: user.Name, Name: []byte(requestedApiToken.Name), Scope: requestedApiToken.Scope}
+		err = methods.SaveApiToken(&apiToken, authentication.HashToken(token))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "apitoken.create", TargetType: "apitoken", TargetId: apiToken.Id, TargetName: string(apiToken.Name), SummaryAfter: "scope: " + strconv.Quote(apiToken.Scope)})
+		jsonApiToken := apiTokenToJson(&apiToken)
+		jsonApiToken.Token = token
+		json, err := json.Marshal(jsonApiToken)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to revoke one of the api tokens of the logged in user
+func deleteApiApiTokenHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		apiTokenId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || apiTokenId < 1 {
+			http.Error(w, "Wrong api token id.", http.StatusInternalServerError)
+			return
+		}
+		apiToken, err := database.RetrieveApiTokenById(apiTokenId)
+		if err != nil || apiToken.UserId != user.Id {
+			http.Error(w, "Api token not found.", http.StatusNotFound)
+			return
+		}
+		err = methods.DeleteApiToken(apiToken.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "apitoken.delete", TargetType: "apitoken", TargetId: apiToken.Id, TargetName: string(apiToken.Name)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Api token revoked!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+func apiTokenToJson(apiToken *structure.ApiToken) *JsonApiToken {
+	var jsonApiToken JsonApiToken
+	jsonApiToken.Id = apiToken.Id
+	jsonApiToken.Name = string(apiToken.Name)
+	jsonApiToken.Scope = apiToken.Scope
+	jsonApiToken.CreatedAt = apiToken.CreatedAt
+	jsonApiToken.LastUsedAt = apiToken.LastUsedAt
+	return &jsonApiToken
+}
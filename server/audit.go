@@ -0,0 +1,227 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/filter"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+// Number of audit log entries per page of the admin api
+const auditEntriesPerPage = 50
+
+// Values in the change summaries are shortened to this many characters
+const auditMaxValueLength = 100
+
+type JsonAuditLog struct {
+	Entries []structure.AuditEntry
+	Total   int64
+}
+
+// API function to browse the audit log. Supports the filter syntax of the content api with the keys actor, action, target,
+// target_id and ip (e.g. ?filter=actor:owner+action:[post.update,post.delete]) and a time range with since and until (RFC 3339).
+func apiAuditLogHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to read the audit log.", http.StatusForbidden)
+			return
+		}
+		page, err := strconv.ParseInt(params["number"], 10, 64)
+		if err != nil || page < 1 {
+			http.Error(w, "Wrong page number.", http.StatusBadRequest)
+			return
+		}
+		query := r.URL.Query()
+		conditions, err := filter.Parse(query.Get("filter"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		since := time.Time{}
+		until := time.Now().UTC().AddDate(100, 0, 0)
+		if value := query.Get("since"); value != "" {
+			since, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "Invalid since date.", http.StatusBadRequest)
+				return
+			}
+		}
+		if value := query.Get("until"); value != "" {
+			until, err = time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "Invalid until date.", http.StatusBadRequest)
+				return
+			}
+		}
+		since = since.UTC()
+		until = until.UTC()
+		var auditLog JsonAuditLog
+		auditLog.Entries, err = database.RetrieveAuditEntries(conditions, since, until, auditEntriesPerPage, (page-1)*auditEntriesPerPage)
+		if err == filter.ErrUnsupportedKey || err == filter.ErrInvalidFilter {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		auditLog.Total, err = database.RetrieveNumberOfAuditEntries(conditions, since, until)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(auditLog)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// audit records the entry in the audit log together with the ip address of the request. Errors are only logged, a failing audit log never stops the action itself.
+func audit(r *http.Request, entry *structure.AuditEntry) {
+	entry.IpAddress = authentication.RemoteIp(r)
+	err := methods.SaveAuditEntry(entry)
+	if err != nil {
+		log.Println("Couldn't write audit log entry:", err)
+	}
+}
+
+// auditChanges summarizes the fields that differ between before and after, e.g. `title: "Old"` and `title: "New"`.
+func auditChanges(before map[string]string, after map[string]string) (string, string) {
+	keys := make([]string, 0, len(after))
+	for key, _ := range after {
+		if before[key] != after[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	beforeParts := make([]string, len(keys))
+	afterParts := make([]string, len(keys))
+	for index, key := range keys {
+		beforeParts[index] = key + ": " + auditValue(before[key])
+		afterParts[index] = key + ": " + auditValue(after[key])
+	}
+	return strings.Join(beforeParts, ", "), strings.Join(afterParts, ", ")
+}
+
+func auditValue(value string) string {
+	if len([]rune(value)) > auditMaxValueLength {
+		value = string([]rune(value)[:auditMaxValueLength]) + "…"
+	}
+	return strconv.Quote(value)
+}
+
+func auditRole(role int) string {
+	switch role {
+	case structure.RoleOwner:
+		return "owner"
+	case structure.RoleAdministrator:
+		return "administrator"
+	case structure.RoleEditor:
+		return "editor"
+	case structure.RoleAuthor:
+		return "author"
+	}
+	return strconv.Itoa(role)
+}
+
+func auditPostSummary(post *structure.Post) map[string]string {
+	status := "draft"
+	if post.IsScheduled {
+		status = "scheduled"
+	} else if post.IsPublished {
+		status = "published"
+	}
+	tags := make([]string, len(post.Tags))
+	for index, _ := range post.Tags {
+		tags[index] = methods.TagPath(&post.Tags[index])
+	}
+	summary := map[string]string{
+		"title":            string(post.Title),
+		"slug":             post.Slug,
+		"status":           status,
+		"featured":         strconv.FormatBool(post.IsFeatured),
+		"page":             strconv.FormatBool(post.IsPage),
+		"tags":             strings.Join(tags, ","),
+		"image":            string(post.Image),
+		"meta_description": string(post.MetaDescription),
+		"meta_title":       string(post.MetaTitle),
+		"custom_excerpt":   string(post.CustomExcerpt),
+		"canonical_url":    string(post.CanonicalUrl),
+		"code_head":        string(post.CodeInjectionHead),
+		"code_foot":        string(post.CodeInjectionFoot),
+		"markdown":         strconv.Itoa(len(post.Markdown)) + " bytes",
+	}
+	if post.IsScheduled && post.Date != nil {
+		summary["publish_at"] = post.Date.Format(time.RFC3339)
+	}
+	return summary
+}
+
+func auditBlogSummary(blog *structure.Blog) map[string]string {
+	navigation := make([]string, len(blog.NavigationItems))
+	for index, _ := range blog.NavigationItems {
+		navigation[index] = blog.NavigationItems[index].Label + " " + blog.NavigationItems[index].Url
+	}
+	return map[string]string{
+		"title":          string(blog.Title),
+		"description":    string(blog.Description),
+		"logo":           string(blog.Logo),
+		"cover":          string(blog.Cover),
+		"posts_per_page": strconv.FormatInt(blog.PostsPerPage, 10),
+		"theme":          blog.ActiveTheme,
+		"navigation":     strings.Join(navigation, ", "),
+		"code_head":      string(blog.CodeInjectionHead),
+		"code_foot":      string(blog.CodeInjectionFoot),
+	}
+}
+
+func auditTagSummary(tag *structure.Tag) map[string]string {
+	parent := ""
+	if tag.Parent != nil {
+		parent = methods.TagPath(tag.Parent)
+	}
+	return map[string]string{
+		"name":             string(tag.Name),
+		"slug":             tag.Slug,
+		"description":      string(tag.Description),
+		"image":            string(tag.Image),
+		"meta_title":       string(tag.MetaTitle),
+		"meta_description": string(tag.MetaDescription),
+		"parent":           parent,
+	}
+}
+
+func auditUserSummary(user *structure.User) map[string]string {
+	return map[string]string{
+		"name":     string(user.Name),
+		"slug":     user.Slug,
+		"email":    string(user.Email),
+		"image":    string(user.Image),
+		"cover":    string(user.Cover),
+		"bio":      string(user.Bio),
+		"website":  string(user.Website),
+		"location": string(user.Location),
+	}
+}
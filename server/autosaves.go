@@ -0,0 +1,167 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+)
+
+type JsonAutosave struct {
+	PostId          int64
+	Title           string
+	Markdown        string
+	Tags            string
+	MetaDescription string
+	Image           string
+	IsFeatured      bool
+	IsPage          bool
+	BaseVersion     *time.Time // Version of the post the changes were made to
+	IsOutdated      bool       // True if the post has been saved since the changes were made
+	Date            *time.Time
+}
+
+// API function to get the unsaved changes of the user to a post
+func getApiPostAutosaveHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		autosave, err := database.RetrieveAutosave(post.Id, user.Id)
+		if err == sql.ErrNoRows {
+			http.Error(w, "There are no unsaved changes.", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(autosaveToJson(autosave, post))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to store the unsaved changes of the user to a post. The post itself stays untouched.
+func postApiPostAutosaveHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if post.DeletedAt != nil {
+			http.Error(w, "This post is in the trash. Restore it to make changes.", http.StatusConflict)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var jsonPost JsonPost
+		err = decoder.Decode(&jsonPost)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Changes without a version are based on the current post
+		baseVersion := jsonPost.UpdatedAt
+		if baseVersion == nil {
+			baseVersion = post.UpdatedAt
+		}
+		err = database.InsertAutosave(post.Id, user.Id, []byte(jsonPost.Title), []byte(jsonPost.Markdown), []byte(jsonPost.Tags), []byte(jsonPost.MetaDescription), []byte(jsonPost.Image), jsonPost.IsFeatured, jsonPost.IsPage, baseVersion, date.GetCurrentTime())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Changes saved!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to discard the unsaved changes of the user to a post
+func deleteApiPostAutosaveHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		err = database.DeleteAutosave(post.Id, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Changes discarded!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// writePostConflict rejects an update that was made to an outdated version of the post. The response contains the current version.
+func writePostConflict(w http.ResponseWriter, post *structure.Post) {
+	writeJsonPost(w, http.StatusConflict, post)
+}
+
+func writeJsonPost(w http.ResponseWriter, status int, post *structure.Post) {
+	json, err := json.Marshal(postToJson(post))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(json)
+}
+
+func autosaveToJson(autosave *structure.Autosave, post *structure.Post) *JsonAutosave {
+	var jsonAutosave JsonAutosave
+	jsonAutosave.PostId = autosave.PostId
+	jsonAutosave.Title = string(autosave.Title)
+	jsonAutosave.Markdown = string(autosave.Markdown)
+	jsonAutosave.Tags = string(autosave.Tags)
+	jsonAutosave.MetaDescription = string(autosave.MetaDescription)
+	jsonAutosave.Image = string(autosave.Image)
+	jsonAutosave.IsFeatured = autosave.IsFeatured
+	jsonAutosave.IsPage = autosave.IsPage
+	jsonAutosave.BaseVersion = autosave.BaseVersion
+	jsonAutosave.IsOutdated = autosave.BaseVersion != nil && post.UpdatedAt != nil && !autosave.BaseVersion.Equal(*post.UpdatedAt)
+	jsonAutosave.Date = autosave.Date
+	return &jsonAutosave
+}
@@ -5,6 +5,7 @@ import (
 	"journey/server/images"
 	"journey/server/static"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -55,13 +56,11 @@ func authorHandler(w http.ResponseWriter, r *http.Request, params map[string]str
 			return
 		}
 		return
-	} else if function == "rss" {
-		// Render author rss feed
-		err := templates.ShowAuthorRss(w, slug)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	} else if format, ok := feedFormats[function]; ok {
+		// Render author feed
+		serveFeed(w, r, func(page int) error {
+			return templates.ShowAuthorFeed(w, r, slug, format, page)
+		})
 		return
 	}
 	page, err := strconv.Atoi(number)
@@ -86,17 +85,19 @@ func tagHandler(w http.ResponseWriter, r *http.Request, params map[string]string
 		// Render tag template (first page)
 		err := templates.ShowTagTemplate(w, r, slug, 1)
 		if err != nil {
+			// The tag might have moved to another slug
+			if redirectRequest(w, r) {
+				return
+			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		return
-	} else if function == "rss" {
-		// Render tag rss feed
-		err := templates.ShowTagRss(w, slug)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	} else if format, ok := feedFormats[function]; ok {
+		// Render tag feed
+		serveFeed(w, r, func(page int) error {
+			return templates.ShowTagFeed(w, r, slug, format, page)
+		})
 		return
 	}
 	page, err := strconv.Atoi(number)
@@ -107,6 +108,9 @@ func tagHandler(w http.ResponseWriter, r *http.Request, params map[string]string
 	// Render tag template
 	err = templates.ShowTagTemplate(w, r, slug, page)
 	if err != nil {
+		if redirectRequest(w, r) {
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -118,18 +122,78 @@ func postHandler(w http.ResponseWriter, r *http.Request, params map[string]strin
 	if slug == "" {
 		http.Redirect(w, r, "/", http.StatusFound)
 		return
-	} else if slug == "rss" {
-		// Render index rss feed
-		err := templates.ShowIndexRss(w)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	} else if format, ok := feedFormats[slug]; ok {
+		// Render index feed
+		serveFeed(w, r, func(page int) error {
+			return templates.ShowIndexFeed(w, r, format, page)
+		})
 		return
 	}
 
 	// Render post template
 	err := templates.ShowPostTemplate(w, r, slug)
+	if err != nil {
+		// The post might have moved to another slug
+		if redirectRequest(w, r) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	return
+}
+
+// The json feeds are served at feed.json next to the rss/ and atom/ directories
+func jsonFeedHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	slug := params["slug"]
+	serveFeed(w, r, func(page int) error {
+		if strings.HasPrefix(r.URL.Path, "/tag/") {
+			return templates.ShowTagFeed(w, r, slug, templates.FeedJson, page)
+		} else if strings.HasPrefix(r.URL.Path, "/author/") {
+			return templates.ShowAuthorFeed(w, r, slug, templates.FeedJson, page)
+		}
+		return templates.ShowIndexFeed(w, r, templates.FeedJson, page)
+	})
+}
+
+// Feeds that are served as directories, e.g. /rss/ and /tag/:slug/atom/
+var feedFormats = map[string]string{"rss": templates.FeedRss, "atom": templates.FeedAtom}
+
+// serveFeed renders the page of a feed that was requested with the page parameter.
+func serveFeed(w http.ResponseWriter, r *http.Request, show func(page int) error) {
+	page := 1
+	if number := r.URL.Query().Get("page"); number != "" {
+		var err error
+		page, err = strconv.Atoi(number)
+		if err != nil || page < 1 {
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+			return
+		}
+	}
+	err := show(page)
+	if err != nil {
+		// The tag or author of the feed might have moved to another slug
+		if redirectRequest(w, r) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func searchHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	query := r.URL.Query().Get("q")
+	page := 1
+	if number := r.URL.Query().Get("page"); number != "" {
+		var err error
+		page, err = strconv.Atoi(number)
+		if err != nil || page < 1 {
+			http.Redirect(w, r, "/search/?q="+url.QueryEscape(query), http.StatusFound)
+			return
+		}
+	}
+	// Render search template
+	err := templates.ShowSearchTemplate(w, r, query, page)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -179,7 +243,16 @@ func publicHandler(w http.ResponseWriter, r *http.Request, params map[string]str
 }
 
 func sitemapHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
-	err := templates.ShowSitemap(w)
+	page := 1
+	if number := r.URL.Query().Get("page"); number != "" {
+		var err error
+		page, err = strconv.Atoi(number)
+		if err != nil || page < 1 {
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+			return
+		}
+	}
+	err := templates.ShowSitemap(w, r, strings.TrimPrefix(r.URL.Path, "/"), page)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -192,14 +265,21 @@ func InitializeBlog(router *httptreemux.TreeMux) {
 	router.GET("/:slug/edit", postEditHandler)
 	router.GET("/:slug/", postHandler)
 	router.GET("/page/:number/", indexHandler)
+	router.GET("/feed.json", jsonFeedHandler)
 	// For author
 	router.GET("/author/:slug/", authorHandler)
+	router.GET("/author/:slug/feed.json", jsonFeedHandler)
 	router.GET("/author/:slug/:function/", authorHandler)
 	router.GET("/author/:slug/:function/:number/", authorHandler)
 	// For tag
 	router.GET("/tag/:slug/", tagHandler)
+	router.GET("/tag/:slug/feed.json", jsonFeedHandler)
 	router.GET("/tag/:slug/:function/", tagHandler)
 	router.GET("/tag/:slug/:function/:number/", tagHandler)
+	// For previews of unpublished posts
+	router.GET("/p/:uuid/", previewHandler)
+	// For search
+	router.GET("/search/", searchHandler)
 	// For serving asset files
 	router.GET("/assets/*filepath", assetsHandler)
 	router.GET("/images/*filepath", images.Handler)
@@ -207,6 +287,12 @@ func InitializeBlog(router *httptreemux.TreeMux) {
 	router.GET("/public/*filepath", publicHandler)
 	// For sitemap
 	router.GET("/sitemap.xml", sitemapHandler)
+	router.GET("/sitemap-posts.xml", sitemapHandler)
+	router.GET("/sitemap-pages.xml", sitemapHandler)
+	router.GET("/sitemap-tags.xml", sitemapHandler)
+	router.GET("/sitemap-authors.xml", sitemapHandler)
 	// For static files
 	static.RegisterHandlers(router)
+	// For redirects of urls that don't exist anymore
+	router.NotFoundHandler = notFoundHandler
 }
@@ -0,0 +1,417 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"journey/conversion"
+	"journey/database"
+	"journey/filter"
+	"journey/structure"
+	"journey/structure/methods"
+
+	"github.com/dimfeld/httptreemux"
+)
+
+const contentApiDefaultLimit = 15
+
+// Length of the excerpt of a post in characters
+const contentApiExcerptLength = 500
+
+// contentApiRequest holds the parameters that all browse endpoints of the content api share.
+type contentApiRequest struct {
+	conditions []filter.Condition
+	include    map[string]bool
+	fields     []string
+	limit      int64 // -1 = all
+	page       int64
+}
+
+type contentApiError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+type contentApiPagination struct {
+	Page  int64       `json:"page"`
+	Limit interface{} `json:"limit"`
+	Pages int64       `json:"pages"`
+	Total int64       `json:"total"`
+	Next  *int64      `json:"next"`
+	Prev  *int64      `json:"prev"`
+}
+
+type contentApiMeta struct {
+	Pagination *contentApiPagination `json:"pagination,omitempty"`
+}
+
+// Content API function to get published posts. The id or slug parameter selects a single post.
+func contentApiPostsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	contentApiPosts(w, r, params, false, "posts")
+}
+
+// Content API function to get published pages. The id or slug parameter selects a single page.
+func contentApiPagesHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	contentApiPosts(w, r, params, true, "pages")
+}
+
+func contentApiPosts(w http.ResponseWriter, r *http.Request, params map[string]string, isPage bool, resource string) {
+	request, ok := parseContentApiRequest(w, r, params)
+	if !ok {
+		return
+	}
+	posts, err := database.RetrievePostsForContentApi(isPage, request.conditions, request.limit, request.offset())
+	if err != nil {
+		writeContentApiQueryError(w, err)
+		return
+	}
+	total, err := database.RetrieveNumberOfPostsForContentApi(isPage, request.conditions)
+	if err != nil {
+		writeContentApiQueryError(w, err)
+		return
+	}
+	methods.Blog.RLock()
+	blogUrl := string(methods.Blog.Url)
+	methods.Blog.RUnlock()
+	objects := make([]map[string]interface{}, len(posts))
+	for index, _ := range posts {
+		objects[index] = request.selectFields(contentApiPost(&posts[index], blogUrl, request.include))
+	}
+	writeContentApiResult(w, params, resource, objects, request.pagination(total))
+}
+
+// Content API function to get tags. The id or slug parameter selects a single tag.
+func contentApiTagsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	request, ok := parseContentApiRequest(w, r, params)
+	if !ok {
+		return
+	}
+	tags, postCounts, err := database.RetrieveTagsForContentApi(request.conditions, request.limit, request.offset())
+	if err != nil {
+		writeContentApiQueryError(w, err)
+		return
+	}
+	total, err := database.RetrieveNumberOfTagsForContentApi(request.conditions)
+	if err != nil {
+		writeContentApiQueryError(w, err)
+		return
+	}
+	methods.Blog.RLock()
+	blogUrl := string(methods.Blog.Url)
+	methods.Blog.RUnlock()
+	objects := make([]map[string]interface{}, len(tags))
+	for index, _ := range tags {
+		object := contentApiTag(&tags[index], blogUrl)
+		if request.include["count.posts"] {
+			object["count"] = map[string]int64{"posts": postCounts[index]}
+		}
+		objects[index] = request.selectFields(object)
+	}
+	writeContentApiResult(w, params, "tags", objects, request.pagination(total))
+}
+
+// Content API function to get the users that have published posts. The id or slug parameter selects a single author.
+func contentApiAuthorsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	request, ok := parseContentApiRequest(w, r, params)
+	if !ok {
+		return
+	}
+	authors, postCounts, err := database.RetrieveAuthorsForContentApi(request.conditions, request.limit, request.offset())
+	if err != nil {
+		writeContentApiQueryError(w, err)
+		return
+	}
+	total, err := database.RetrieveNumberOfAuthorsForContentApi(request.conditions)
+	if err != nil {
+		writeContentApiQueryError(w, err)
+		return
+	}
+	methods.Blog.RLock()
+	blogUrl := string(methods.Blog.Url)
+	methods.Blog.RUnlock()
+	objects := make([]map[string]interface{}, len(authors))
+	for index, _ := range authors {
+		object := contentApiAuthor(&authors[index], blogUrl)
+		if request.include["count.posts"] {
+			object["count"] = map[string]int64{"posts": postCounts[index]}
+		}
+		objects[index] = request.selectFields(object)
+	}
+	writeContentApiResult(w, params, "authors", objects, request.pagination(total))
+}
+
+// Content API function to get the public settings of the blog
+func contentApiSettingsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if !checkContentApiKey(w, r) {
+		return
+	}
+	methods.Blog.RLock()
+	settings := map[string]interface{}{
+		"title":          string(methods.Blog.Title),
+		"description":    string(methods.Blog.Description),
+		"logo":           string(methods.Blog.Logo),
+		"cover_image":    string(methods.Blog.Cover),
+		"url":            string(methods.Blog.Url),
+		"navigation":     methods.Blog.NavigationItems,
+		"posts_per_page": methods.Blog.PostsPerPage,
+	}
+	methods.Blog.RUnlock()
+	writeContentApiJson(w, http.StatusOK, map[string]interface{}{"settings": settings, "meta": contentApiMeta{}})
+}
+
+// parseContentApiRequest checks the api key and reads the query parameters. If it returns false, an error has already been written.
+func parseContentApiRequest(w http.ResponseWriter, r *http.Request, params map[string]string) (*contentApiRequest, bool) {
+	if !checkContentApiKey(w, r) {
+		return nil, false
+	}
+	query := r.URL.Query()
+	request := contentApiRequest{include: make(map[string]bool), limit: contentApiDefaultLimit, page: 1}
+	var err error
+	request.conditions, err = filter.Parse(query.Get("filter"))
+	if err != nil {
+		writeContentApiError(w, http.StatusBadRequest, "BadRequestError", err.Error())
+		return nil, false
+	}
+	// A single resource is requested by id or slug
+	if params["id"] != "" {
+		request.conditions = append(request.conditions, filter.Condition{Key: "id", Values: []string{params["id"]}})
+	} else if params["slug"] != "" {
+		request.conditions = append(request.conditions, filter.Condition{Key: "slug", Values: []string{params["slug"]}})
+	}
+	for _, include := range strings.Split(query.Get("include"), ",") {
+		if include = strings.TrimSpace(include); include != "" {
+			request.include[include] = true
+		}
+	}
+	for _, field := range strings.Split(query.Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			request.fields = append(request.fields, field)
+		}
+	}
+	if limit := query.Get("limit"); limit == "all" {
+		request.limit = -1
+	} else if limit != "" {
+		request.limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || request.limit < 1 {
+			writeContentApiError(w, http.StatusBadRequest, "BadRequestError", "Invalid limit.")
+			return nil, false
+		}
+	}
+	if page := query.Get("page"); page != "" {
+		request.page, err = strconv.ParseInt(page, 10, 64)
+		if err != nil || request.page < 1 {
+			writeContentApiError(w, http.StatusBadRequest, "BadRequestError", "Invalid page.")
+			return nil, false
+		}
+	}
+	return &request, true
+}
+
+// checkContentApiKey writes an error and returns false if the request doesn't carry a valid content api key.
+func checkContentApiKey(w http.ResponseWriter, r *http.Request) bool {
+	// Themes and front-ends on other domains are allowed to use the api
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeContentApiError(w, http.StatusUnauthorized, "UnauthorizedError", "Authorization failed: no content api key provided.")
+		return false
+	}
+	_, err := database.RetrieveApiKeyBySecret(key)
+	if err != nil {
+		writeContentApiError(w, http.StatusUnauthorized, "UnauthorizedError", "Authorization failed: unknown content api key.")
+		return false
+	}
+	return true
+}
+
+func (request *contentApiRequest) offset() int64 {
+	if request.limit == -1 {
+		return 0
+	}
+	return (request.page - 1) * request.limit
+}
+
+func (request *contentApiRequest) pagination(total int64) *contentApiPagination {
+	pagination := contentApiPagination{Page: request.page, Total: total, Pages: 1}
+	if request.limit == -1 {
+		pagination.Page = 1
+		pagination.Limit = "all"
+		return &pagination
+	}
+	pagination.Limit = request.limit
+	if total > 0 {
+		pagination.Pages = (total + request.limit - 1) / request.limit
+	}
+	if request.page < pagination.Pages {
+		next := request.page + 1
+		pagination.Next = &next
+	}
+	if request.page > 1 {
+		prev := request.page - 1
+		pagination.Prev = &prev
+	}
+	return &pagination
+}
+
+// selectFields removes all fields from the object that were not requested with the fields parameter.
+func (request *contentApiRequest) selectFields(object map[string]interface{}) map[string]interface{} {
+	if len(request.fields) == 0 {
+		return object
+	}
+	selected := make(map[string]interface{}, len(request.fields))
+	for _, field := range request.fields {
+		if value, ok := object[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected
+}
+
+func writeContentApiResult(w http.ResponseWriter, params map[string]string, resource string, objects []map[string]interface{}, pagination *contentApiPagination) {
+	// Requests for a single resource don't get pagination, but a 404 if there is no such resource
+	if params["id"] != "" || params["slug"] != "" {
+		if len(objects) == 0 {
+			writeContentApiError(w, http.StatusNotFound, "NotFoundError", "Resource not found.")
+			return
+		}
+		writeContentApiJson(w, http.StatusOK, map[string]interface{}{resource: objects[:1], "meta": contentApiMeta{}})
+		return
+	}
+	writeContentApiJson(w, http.StatusOK, map[string]interface{}{resource: objects, "meta": contentApiMeta{Pagination: pagination}})
+}
+
+func writeContentApiQueryError(w http.ResponseWriter, err error) {
+	if err == filter.ErrInvalidFilter || err == filter.ErrUnsupportedKey {
+		writeContentApiError(w, http.StatusBadRequest, "BadRequestError", err.Error())
+		return
+	}
+	writeContentApiError(w, http.StatusInternalServerError, "InternalServerError", err.Error())
+}
+
+func writeContentApiError(w http.ResponseWriter, status int, errorType string, message string) {
+	writeContentApiJson(w, status, map[string][]contentApiError{"errors": {{Message: message, Type: errorType}}})
+}
+
+func writeContentApiJson(w http.ResponseWriter, status int, value interface{}) {
+	json, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(json)
+}
+
+func contentApiPost(post *structure.Post, blogUrl string, include map[string]bool) map[string]interface{} {
+	excerpt := []rune(string(conversion.StripTagsFromHtml(post.Html)))
+	if len(post.CustomExcerpt) != 0 {
+		excerpt = []rune(string(post.CustomExcerpt))
+	}
+	if len(excerpt) > contentApiExcerptLength {
+		excerpt = excerpt[:contentApiExcerptLength]
+	}
+	object := map[string]interface{}{
+		"id":                  post.Id,
+		"uuid":                string(post.Uuid),
+		"title":               string(post.Title),
+		"slug":                post.Slug,
+		"html":                string(post.Html),
+		"excerpt":             strings.TrimSpace(string(excerpt)),
+		"feature_image":       nullableString(post.Image),
+		"featured":            post.IsFeatured,
+		"page":                post.IsPage,
+		"meta_description":    nullableString(post.MetaDescription),
+		"meta_title":          nullableString(post.MetaTitle),
+		"custom_excerpt":      nullableString(post.CustomExcerpt),
+		"canonical_url":       nullableString(post.CanonicalUrl),
+		"og_title":            nullableString(post.OgTitle),
+		"og_description":      nullableString(post.OgDescription),
+		"og_image":            nullableString(post.OgImage),
+		"twitter_title":       nullableString(post.TwitterTitle),
+		"twitter_description": nullableString(post.TwitterDescription),
+		"twitter_image":       nullableString(post.TwitterImage),
+		"codeinjection_head":  nullableString(post.CodeInjectionHead),
+		"codeinjection_foot":  nullableString(post.CodeInjectionFoot),
+		"published_at":        post.Date,
+		"url":                 blogUrl + "/" + post.Slug + "/",
+	}
+	if include["tags"] {
+		// Internal tags are only used to organize posts
+		publicTags := methods.PublicTags(post.Tags)
+		tags := make([]map[string]interface{}, len(publicTags))
+		for index, _ := range publicTags {
+			tags[index] = contentApiTag(&publicTags[index], blogUrl)
+		}
+		object["tags"] = tags
+		object["primary_tag"] = nil
+		if len(tags) > 0 {
+			object["primary_tag"] = tags[0]
+		}
+	}
+	if include["authors"] {
+		author := contentApiAuthor(post.Author, blogUrl)
+		object["authors"] = []map[string]interface{}{author}
+		object["primary_author"] = author
+	}
+	return object
+}
+
+func contentApiTag(tag *structure.Tag, blogUrl string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               tag.Id,
+		"name":             string(tag.Name),
+		"slug":             tag.Slug,
+		"description":      nullableString(tag.Description),
+		"feature_image":    nullableString(tag.Image),
+		"meta_title":       nullableString(tag.MetaTitle),
+		"meta_description": nullableString(tag.MetaDescription),
+		"url":              blogUrl + "/tag/" + tag.Slug + "/",
+	}
+}
+
+// contentApiAuthor returns the public fields of a user. The email address and role are never exposed.
+func contentApiAuthor(user *structure.User, blogUrl string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            user.Id,
+		"name":          string(user.Name),
+		"slug":          user.Slug,
+		"profile_image": nullableString(user.Image),
+		"cover_image":   nullableString(user.Cover),
+		"bio":           nullableString(user.Bio),
+		"website":       nullableString(user.Website),
+		"location":      nullableString(user.Location),
+		"url":           blogUrl + "/author/" + user.Slug + "/",
+	}
+}
+
+// nullableString returns nil for empty values so they are encoded as null (like Ghost does).
+func nullableString(value []byte) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return string(value)
+}
+
+func InitializeContentApi(router *httptreemux.TreeMux) {
+	// Posts
+	router.GET("/ghost/api/content/posts/", contentApiPostsHandler)
+	router.GET("/ghost/api/content/posts/:id/", contentApiPostsHandler)
+	router.GET("/ghost/api/content/posts/slug/:slug/", contentApiPostsHandler)
+	// Pages
+	router.GET("/ghost/api/content/pages/", contentApiPagesHandler)
+	router.GET("/ghost/api/content/pages/:id/", contentApiPagesHandler)
+	router.GET("/ghost/api/content/pages/slug/:slug/", contentApiPagesHandler)
+	// Tags
+	router.GET("/ghost/api/content/tags/", contentApiTagsHandler)
+	router.GET("/ghost/api/content/tags/:id/", contentApiTagsHandler)
+	router.GET("/ghost/api/content/tags/slug/:slug/", contentApiTagsHandler)
+	// Authors
+	router.GET("/ghost/api/content/authors/", contentApiAuthorsHandler)
+	router.GET("/ghost/api/content/authors/:id/", contentApiAuthorsHandler)
+	router.GET("/ghost/api/content/authors/slug/:slug/", contentApiAuthorsHandler)
+	// Settings
+	router.GET("/ghost/api/content/settings/", contentApiSettingsHandler)
+}
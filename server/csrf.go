@@ -0,0 +1,62 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"journey/authentication"
+
+	"github.com/dimfeld/httptreemux"
+)
+
+// csrfProtected wraps handlers of the admin api that change data. The request has to come from a page of the blog
+// and carry the csrf token of the session, which only the admin interface can read from its cookie.
+// Requests with an api token don't need a csrf token (browsers never add it on their own), but the token has to allow changes.
+func csrfProtected(handler httptreemux.HandlerFunc) httptreemux.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if authentication.HasApiToken(r) {
+			apiToken, err := authentication.GetApiToken(r)
+			if err != nil {
+				http.Error(w, "Invalid api token.", http.StatusUnauthorized)
+				return
+			}
+			if !authentication.ApiTokenAllows(apiToken, r) {
+				http.Error(w, "This api token is read-only.", http.StatusForbidden)
+				return
+			}
+			handler(w, r, params)
+			return
+		}
+		if !authentication.OriginIsValid(r) || !authentication.CsrfTokenIsValid(r) {
+			log.Println("Rejected request without valid csrf token: " + r.Method + " " + r.URL.Path)
+			http.Error(w, "Invalid csrf token. Please reload the page.", http.StatusForbidden)
+			return
+		}
+		handler(w, r, params)
+	}
+}
+
+// originProtected wraps form handlers that are used before there is a session (login, registration, invitations).
+// These only check that the form was submitted from a page of the blog.
+func originProtected(handler httptreemux.HandlerFunc) httptreemux.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if !authentication.OriginIsValid(r) {
+			log.Println("Rejected cross-origin request: " + r.Method + " " + r.URL.Path)
+			http.Error(w, "Cross-origin requests are not allowed.", http.StatusForbidden)
+			return
+		}
+		handler(w, r, params)
+	}
+}
+
+// sessionRequired wraps handlers that must not be used with an api token, e.g. managing the tokens themselves or the
+// sessions and two-factor settings of the user. Otherwise a leaked token could be used to take over the account.
+func sessionRequired(handler httptreemux.HandlerFunc) httptreemux.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if authentication.HasApiToken(r) {
+			http.Error(w, "This can't be done with an api token. Please log in.", http.StatusForbidden)
+			return
+		}
+		handler(w, r, params)
+	}
+}
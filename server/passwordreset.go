@@ -0,0 +1,157 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"journey/authentication"
+	"journey/configuration"
+	"journey/database"
+	"journey/date"
+	"journey/filenames"
+	"journey/mail"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+// Reset links stay valid for this long
+const passwordResetLifetime = time.Hour
+
+// A user can only request one reset email in this time
+const passwordResetInterval = 5 * time.Minute
+
+var errPasswordResetInvalid = errors.New("This reset link is invalid or has expired.")
+
+// getForgotPasswordHandler serves the form to request a password reset email.
+func getForgotPasswordHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "forgot.html"))
+	return
+}
+
+// postForgotPasswordHandler sends a reset link to the email address of the user. The response is the same whether
+// the user exists or not, so that the form can't be used to find out user names or email addresses.
+func postForgotPasswordHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name != "" {
+		err := sendPasswordReset(name)
+		if err != nil {
+			log.Println("Couldn't send password reset email:", err)
+		}
+	}
+	http.Redirect(w, r, "/admin/login/?info=reset-requested", 302)
+	return
+}
+
+// getPasswordResetHandler serves the form to choose a new password.
+func getPasswordResetHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	_, err := getValidPasswordReset(params["token"])
+	if err != nil {
+		http.Redirect(w, r, "/admin/forgot/?error=expired", 302)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "reset.html"))
+	return
+}
+
+// postPasswordResetHandler sets the new password. All sessions of the user end, so they have to log in with the new password.
+func postPasswordResetHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	passwordReset, err := getValidPasswordReset(params["token"])
+	if err != nil {
+		http.Redirect(w, r, "/admin/forgot/?error=expired", 302)
+		return
+	}
+	password := r.FormValue("password")
+	if password == "" || password != r.FormValue("repeated-password") {
+		http.Redirect(w, r, r.URL.Path, 302)
+		return
+	}
+	user, err := database.RetrieveUser(passwordReset.UserId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	hashedPassword, err := authentication.EncryptPassword(password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	unused, err := methods.ResetPassword(passwordReset, hashedPassword)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !unused {
+		http.Redirect(w, r, "/admin/forgot/?error=expired", 302)
+		return
+	}
+	// The user proved that they own the account, so earlier failed logins shouldn't lock them out anymore
+	err = authentication.RecordLoginAttempt(string(user.Name), true, r)
+	if err != nil {
+		log.Println("Couldn't record login attempt:", err)
+	}
+	log.Println("Password of user " + string(user.Name) + " has been reset")
+	audit(r, &structure.AuditEntry{UserId: user.Id, UserName: string(user.Name), Action: "password.reset", TargetType: "user", TargetId: user.Id, TargetName: string(user.Name)})
+	http.Redirect(w, r, "/admin/login/?info=reset", 302)
+	return
+}
+
+// sendPasswordReset creates a reset token for the user with the given name or email address and emails the link.
+// Nothing is sent for unknown and suspended users or if the user requested a reset only a moment ago.
+func sendPasswordReset(nameOrEmail string) error {
+	user, err := database.RetrieveUserByName([]byte(nameOrEmail))
+	if err == sql.ErrNoRows {
+		user, err = database.RetrieveUserByEmail([]byte(nameOrEmail))
+	}
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if user.Status == structure.UserStatusInactive || len(user.Email) == 0 {
+		return nil
+	}
+	currentTime := date.GetCurrentTime()
+	if lastReset, err := database.RetrieveLastPasswordResetDate(user.Id); err == nil && currentTime.Sub(*lastReset) < passwordResetInterval {
+		return nil
+	}
+	token, err := authentication.GenerateToken()
+	if err != nil {
+		return err
+	}
+	err = database.InsertPasswordReset(authentication.HashToken(token), user.Id, currentTime, currentTime.Add(passwordResetLifetime))
+	if err != nil {
+		return err
+	}
+	methods.Blog.RLock()
+	blogTitle := string(methods.Blog.Title)
+	methods.Blog.RUnlock()
+	body := "Hello " + string(user.Name) + ",\n\n" +
+		"somebody (hopefully you) asked to reset your password for " + blogTitle + ". Open the following link to choose a new password:\n\n" +
+		adminUrl() + "/admin/reset/" + token + "/\n\n" +
+		"The link is valid for one hour and can only be used once. If you didn't ask for a new password, you can ignore this email.\n"
+	return methods.SendMail(&mail.Message{To: string(user.Email), Subject: "Reset your password for " + blogTitle, Body: body})
+}
+
+func getValidPasswordReset(token string) (*structure.PasswordReset, error) {
+	passwordReset, err := database.RetrievePasswordResetByTokenHash(authentication.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if passwordReset.UsedAt != nil || !passwordReset.ExpiresAt.After(date.GetCurrentTime()) {
+		return nil, errPasswordResetInvalid
+	}
+	return passwordReset, nil
+}
+
+// adminUrl returns the url the admin area is reachable at (https if enabled in the config).
+func adminUrl() string {
+	if configuration.Config.HttpsUsage == "AdminOnly" || configuration.Config.HttpsUsage == "All" {
+		return configuration.Config.HttpsUrl
+	}
+	return configuration.Config.Url
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"journey/authentication"
+	"journey/configuration"
+	"journey/database"
+	"journey/structure"
+	"journey/structure/methods"
+	"journey/templates"
+)
+
+type JsonPostPreview struct {
+	Enabled   bool
+	Url       string
+	ExpiresAt *time.Time
+}
+
+type JsonPostPreviewRequest struct {
+	ExpiresIn int // Hours until the link expires, 0 for a link that doesn't expire
+}
+
+// previewHandler renders a post that has a preview link, whether it's published or not. Previews are never indexed or cached.
+func previewHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	post, err := methods.GetPreviewPost(params["uuid"])
+	if err == methods.ErrPreviewNotAvailable {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Cache-Control", "private, no-store")
+	err = templates.ShowPreviewTemplate(w, r, post)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+}
+
+// API function to get the preview link of a post
+func getApiPostPreviewHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		jsonPreview := JsonPostPreview{Url: previewUrl(post)}
+		if preview, err := database.RetrievePostPreview(post.Id); err == nil {
+			jsonPreview.Enabled = true
+			jsonPreview.ExpiresAt = preview.ExpiresAt
+		}
+		json, err := json.Marshal(jsonPreview)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to create a preview link for a post. An existing link of the post keeps its url but gets the new expiry date.
+func postApiPostPreviewHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var request JsonPostPreviewRequest
+		err = decoder.Decode(&request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if request.ExpiresIn < 0 {
+			http.Error(w, "Invalid expiry.", http.StatusBadRequest)
+			return
+		}
+		preview, err := methods.SavePostPreview(post.Id, time.Duration(request.ExpiresIn)*time.Hour, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "post.preview", TargetType: "post", TargetId: post.Id, TargetName: string(post.Title)})
+		json, err := json.Marshal(JsonPostPreview{Enabled: true, Url: previewUrl(post), ExpiresAt: preview.ExpiresAt})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to revoke the preview link of a post
+func deleteApiPostPreviewHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		err = methods.DeletePostPreview(post.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "post.preview_revoke", TargetType: "post", TargetId: post.Id, TargetName: string(post.Title)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Preview link revoked!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+func previewUrl(post *structure.Post) string {
+	return configuration.Config.Url + "/p/" + string(post.Uuid) + "/"
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/helpers"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+type JsonRedirect struct {
+	From  string
+	To    string
+	Match string
+}
+
+// API function to get all redirects
+func apiRedirectsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to manage redirects.", http.StatusForbidden)
+			return
+		}
+		redirects, err := database.RetrieveRedirects()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(redirects)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to add a manual redirect. A redirect with the same path and match type is replaced.
+func postApiRedirectHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to manage redirects.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var requestedRedirect JsonRedirect
+		err = decoder.Decode(&requestedRedirect)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		redirect := structure.Redirect{From: strings.TrimSpace(requestedRedirect.From), To: strings.TrimSpace(requestedRedirect.To), Match: requestedRedirect.Match, CreatedBy: user.Id}
+		if redirect.Match == "" {
+			redirect.Match = structure.RedirectMatchExact
+		}
+		if redirect.Match != structure.RedirectMatchExact && redirect.Match != structure.RedirectMatchPrefix {
+			http.Error(w, "Unknown match type.", http.StatusBadRequest)
+			return
+		}
+		if !isLocalPath(redirect.From) {
+			http.Error(w, "The path to redirect from has to be a path on the blog (e.g. /old-post/).", http.StatusBadRequest)
+			return
+		}
+		if !isLocalPath(redirect.To) && !strings.HasPrefix(redirect.To, "http://") && !strings.HasPrefix(redirect.To, "https://") {
+			http.Error(w, "The redirect target has to be a path or a full url.", http.StatusBadRequest)
+			return
+		}
+		if redirect.From == redirect.To {
+			http.Error(w, "A path can't redirect to itself.", http.StatusBadRequest)
+			return
+		}
+		err = methods.SaveRedirect(&redirect)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "redirect.create", TargetType: "redirect", TargetId: redirect.Id, TargetName: redirect.From, SummaryAfter: "to: " + strconv.Quote(redirect.To) + ", match: " + strconv.Quote(redirect.Match)})
+		json, err := json.Marshal(redirect)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete a redirect
+func deleteApiRedirectHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to manage redirects.", http.StatusForbidden)
+			return
+		}
+		redirectId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || redirectId < 1 {
+			http.Error(w, "Wrong redirect id.", http.StatusInternalServerError)
+			return
+		}
+		err = methods.DeleteRedirect(redirectId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "redirect.delete", TargetType: "redirect", TargetId: redirectId})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Redirect deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// notFoundHandler answers requests that don't match any route. Old urls that have a redirect are sent on, everything else gets a 404.
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	if redirectRequest(w, r) {
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// redirectRequest sends a permanent redirect if there is a redirect for the path of the request. Returns false if there is none.
+func redirectRequest(w http.ResponseWriter, r *http.Request) bool {
+	redirect, err := database.RetrieveRedirectForPath(r.URL.Path)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println("Couldn't look up redirect:", err)
+		}
+		return false
+	}
+	target, ok := helpers.RedirectTarget(redirect, r.URL.Path)
+	if !ok {
+		return false
+	}
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+	return true
+}
+
+// isLocalPath reports whether the path belongs to the blog ("//" would be a url of another host).
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//")
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"journey/authentication"
+	"journey/conversion"
+	"journey/database"
+	"journey/date"
+	"journey/diff"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+var errNoPostPermission = errors.New("You don't have permission to access this post.")
+var errRevisionNotFound = errors.New("This revision doesn't belong to the post.")
+
+type JsonRevision struct {
+	Id              int64
+	PostId          int64
+	Title           string
+	Markdown        string
+	Tags            string
+	MetaDescription string
+	Image           string
+	IsFeatured      bool
+	IsPage          bool
+	Date            *time.Time
+	CreatedBy       int64
+}
+
+type JsonRevisionDiff struct {
+	From     *JsonRevision
+	To       *JsonRevision
+	Title    []diff.Line
+	Markdown []diff.Line
+}
+
+// API function to get all revisions of a post (newest first)
+func apiPostRevisionsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		revisions, err := database.RetrieveRevisionsByPostId(post.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonRevisions := make([]JsonRevision, len(revisions))
+		for index, _ := range revisions {
+			jsonRevisions[index] = *revisionToJson(&revisions[index])
+		}
+		json, err := json.Marshal(jsonRevisions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to get the difference between two revisions of a post
+func apiPostRevisionDiffHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		from, err := getRevisionOfPost(post.Id, params["from"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		to, err := getRevisionOfPost(post.Id, params["to"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		revisionDiff := JsonRevisionDiff{From: revisionToJson(from), To: revisionToJson(to), Title: diff.Lines(string(from.Title), string(to.Title)), Markdown: diff.Lines(string(from.Markdown), string(to.Markdown))}
+		json, err := json.Marshal(revisionDiff)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to restore an older revision of a post. The restored version is saved as a new revision.
+func postApiPostRevisionRestoreHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		revision, err := getRevisionOfPost(post.Id, params["revision"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		oldSummary := auditPostSummary(post)
+		if post.DeletedAt != nil {
+			http.Error(w, "This post is in the trash. Restore it to make changes.", http.StatusConflict)
+			return
+		}
+		// Slug, publication state, the search engine and social network fields and the code injection are not part of a revision and stay as they are
+		current := *post
+		postDate := post.Date
+		if !post.IsScheduled {
+			currentTime := date.GetCurrentTime()
+			postDate = &currentTime
+		}
+		*post = structure.Post{Id: post.Id, Title: revision.Title, Slug: post.Slug, Markdown: revision.Markdown, Html: conversion.GenerateHtmlFromMarkdown(revision.Markdown), IsFeatured: revision.IsFeatured, IsPage: revision.IsPage, IsPublished: post.IsPublished, IsScheduled: post.IsScheduled, MetaDescription: revision.MetaDescription, Image: revision.Image, Date: postDate, Tags: methods.GenerateTagsFromCommaString(string(revision.Tags)), Author: &structure.User{Id: user.Id}}
+		post.MetaTitle, post.CustomExcerpt, post.CanonicalUrl = current.MetaTitle, current.CustomExcerpt, current.CanonicalUrl
+		post.OgTitle, post.OgDescription, post.OgImage = current.OgTitle, current.OgDescription, current.OgImage
+		post.TwitterTitle, post.TwitterDescription, post.TwitterImage = current.TwitterTitle, current.TwitterDescription, current.TwitterImage
+		post.CodeInjectionHead, post.CodeInjectionFoot = current.CodeInjectionHead, current.CodeInjectionFoot
+		err = methods.UpdatePost(post)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		before, after := auditChanges(oldSummary, auditPostSummary(post))
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "post.restore", TargetType: "post", TargetId: post.Id, TargetName: string(post.Title), SummaryBefore: before, SummaryAfter: after})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Revision restored!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getEditablePost returns the post with the given id if the user is allowed to edit it.
+func getEditablePost(userName string, id string) (*structure.Post, error) {
+	user, err := getUser(userName)
+	if err != nil {
+		return nil, err
+	}
+	postId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	post, err := database.RetrievePostById(postId)
+	if err != nil {
+		return nil, err
+	}
+	if !authentication.CanEditPost(user, post) {
+		return nil, errNoPostPermission
+	}
+	return post, nil
+}
+
+// getRevisionOfPost returns the revision with the given id if it belongs to the post.
+func getRevisionOfPost(postId int64, id string) (*structure.Revision, error) {
+	revisionId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := database.RetrieveRevisionById(revisionId)
+	if err != nil {
+		return nil, err
+	}
+	if revision.PostId != postId {
+		return nil, errRevisionNotFound
+	}
+	return revision, nil
+}
+
+func revisionToJson(revision *structure.Revision) *JsonRevision {
+	var jsonRevision JsonRevision
+	jsonRevision.Id = revision.Id
+	jsonRevision.PostId = revision.PostId
+	jsonRevision.Title = string(revision.Title)
+	jsonRevision.Markdown = string(revision.Markdown)
+	jsonRevision.Tags = string(revision.Tags)
+	jsonRevision.MetaDescription = string(revision.MetaDescription)
+	jsonRevision.Image = string(revision.Image)
+	jsonRevision.IsFeatured = revision.IsFeatured
+	jsonRevision.IsPage = revision.IsPage
+	jsonRevision.Date = revision.Date
+	jsonRevision.CreatedBy = revision.CreatedBy
+	return &jsonRevision
+}
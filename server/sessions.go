@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+)
+
+type JsonSession struct {
+	Id         int64
+	UserId     int64
+	UserName   string
+	UserAgent  string
+	IpAddress  string
+	Remember   bool
+	Current    bool // The session the request was made with
+	CreatedAt  *time.Time
+	LastSeenAt *time.Time
+	ExpiresAt  *time.Time
+}
+
+// API function to get the active sessions. Administrators see the sessions of all users, everybody else only their own.
+func apiSessionsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		currentSession, err := authentication.GetSession(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var sessions []structure.Session
+		if authentication.CanManageSettings(user) {
+			sessions, err = database.RetrieveAllSessions(date.GetCurrentTime())
+		} else {
+			sessions, err = database.RetrieveSessionsByUserId(user.Id, date.GetCurrentTime())
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonSessions := make([]JsonSession, len(sessions))
+		for index, _ := range sessions {
+			jsonSessions[index] = *sessionToJson(&sessions[index])
+			jsonSessions[index].Current = sessions[index].Id == currentSession.Id
+		}
+		json, err := json.Marshal(jsonSessions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to revoke a session. Users can revoke their own sessions and the sessions of users they are allowed to manage.
+func deleteApiSessionHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sessionId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || sessionId < 1 {
+			http.Error(w, "Wrong session id.", http.StatusInternalServerError)
+			return
+		}
+		session, err := database.RetrieveSessionById(sessionId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if session.UserId != user.Id {
+			target, err := database.RetrieveUser(session.UserId)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !authentication.CanManageUser(user, target) {
+				http.Error(w, "You don't have permission to revoke this session.", http.StatusForbidden)
+				return
+			}
+		}
+		err = database.DeleteSessionById(sessionId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "session.revoke", TargetType: "user", TargetId: session.UserId, TargetName: string(session.UserName)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Session revoked!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to revoke all sessions of the authenticated user except the current one
+func deleteApiSessionsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		err = authentication.RevokeOtherSessions(user.Id, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "sessions.revoke", TargetType: "user", TargetId: user.Id, TargetName: userName})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Sessions revoked!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+func sessionToJson(session *structure.Session) *JsonSession {
+	var jsonSession JsonSession
+	jsonSession.Id = session.Id
+	jsonSession.UserId = session.UserId
+	jsonSession.UserName = string(session.UserName)
+	jsonSession.UserAgent = session.UserAgent
+	jsonSession.IpAddress = session.IpAddress
+	jsonSession.Remember = session.Remember
+	jsonSession.CreatedAt = session.CreatedAt
+	jsonSession.LastSeenAt = session.LastSeenAt
+	jsonSession.ExpiresAt = session.ExpiresAt
+	return &jsonSession
+}
@@ -0,0 +1,391 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/slug"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+type JsonTag struct {
+	Id              int64
+	Name            string
+	Slug            string
+	Description     string
+	Image           string
+	MetaTitle       string
+	MetaDescription string
+	ParentId        int64
+	PostCount       int64
+}
+
+// API function to get all tags with the number of posts of each tag
+func apiTagsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		tags, postCounts, err := database.RetrieveTagsForAdmin()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonTags := make([]JsonTag, len(tags))
+		for index, _ := range tags {
+			jsonTags[index] = jsonTagFromTag(&tags[index], postCounts[index])
+		}
+		json, err := json.Marshal(jsonTags)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to get a tag by id
+func getApiTagHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		tagId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || tagId < 1 {
+			http.Error(w, "Wrong tag id.", http.StatusInternalServerError)
+			return
+		}
+		tag, err := database.RetrieveTag(tagId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		postCount, err := database.RetrieveNumberOfAllPostsByTag(tag.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(jsonTagFromTag(tag, postCount))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to create a tag
+func postApiTagHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageTags(user) {
+			http.Error(w, "You don't have permission to manage tags.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var requestedTag JsonTag
+		err = decoder.Decode(&requestedTag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tag := structure.Tag{}
+		err = setTagFields(&tag, &requestedTag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = methods.SaveTag(&tag, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, after := auditChanges(map[string]string{}, auditTagSummary(&tag))
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "tag.create", TargetType: "tag", TargetId: tag.Id, TargetName: string(tag.Name), SummaryAfter: after})
+		writeJsonTag(w, &tag, 0)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to change the name, slug, description, cover image and meta data of a tag
+func patchApiTagHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageTags(user) {
+			http.Error(w, "You don't have permission to manage tags.", http.StatusForbidden)
+			return
+		}
+		tagId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || tagId < 1 {
+			http.Error(w, "Wrong tag id.", http.StatusInternalServerError)
+			return
+		}
+		tag, err := database.RetrieveTag(tagId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var requestedTag JsonTag
+		err = decoder.Decode(&requestedTag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		oldTag := *tag
+		err = setTagFields(tag, &requestedTag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = methods.UpdateTag(tag, oldTag.Slug, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		before, after := auditChanges(auditTagSummary(&oldTag), auditTagSummary(tag))
+		if before != "" || after != "" {
+			audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "tag.update", TargetType: "tag", TargetId: tag.Id, TargetName: string(tag.Name), SummaryBefore: before, SummaryAfter: after})
+		}
+		postCount, err := database.RetrieveNumberOfAllPostsByTag(tag.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJsonTag(w, tag, postCount)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to merge a tag into another tag. The posts of the tag get the other tag and the tag is deleted.
+func postApiTagMergeHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageTags(user) {
+			http.Error(w, "You don't have permission to manage tags.", http.StatusForbidden)
+			return
+		}
+		tagId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || tagId < 1 {
+			http.Error(w, "Wrong tag id.", http.StatusInternalServerError)
+			return
+		}
+		tag, err := database.RetrieveTag(tagId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var request struct {
+			Into int64
+		}
+		err = decoder.Decode(&request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if request.Into == tag.Id {
+			http.Error(w, "A tag can't be merged into itself.", http.StatusBadRequest)
+			return
+		}
+		into, err := database.RetrieveTag(request.Into)
+		if err != nil {
+			http.Error(w, "The tag to merge into doesn't exist.", http.StatusBadRequest)
+			return
+		}
+		// The child tags of the tag move to the other tag, which mustn't be one of them
+		if isTagBelow(into, tag.Id) {
+			http.Error(w, "A tag can't be merged into one of its child tags.", http.StatusBadRequest)
+			return
+		}
+		err = methods.MergeTag(tag, into, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "tag.merge", TargetType: "tag", TargetId: tag.Id, TargetName: string(tag.Name), SummaryBefore: "slug: " + tag.Slug, SummaryAfter: "merged into: " + string(into.Name) + " (" + into.Slug + ")"})
+		postCount, err := database.RetrieveNumberOfAllPostsByTag(into.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJsonTag(w, into, postCount)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete a tag. The tag is removed from all posts that use it.
+func deleteApiTagHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageTags(user) {
+			http.Error(w, "You don't have permission to manage tags.", http.StatusForbidden)
+			return
+		}
+		tagId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || tagId < 1 {
+			http.Error(w, "Wrong tag id.", http.StatusInternalServerError)
+			return
+		}
+		tag, err := database.RetrieveTag(tagId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		err = methods.DeleteTag(tag.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, before := auditChanges(map[string]string{}, auditTagSummary(tag))
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "tag.delete", TargetType: "tag", TargetId: tag.Id, TargetName: string(tag.Name), SummaryBefore: before})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Tag deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete all tags that aren't used by any post
+func deleteApiUnusedTagsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageTags(user) {
+			http.Error(w, "You don't have permission to manage tags.", http.StatusForbidden)
+			return
+		}
+		count, err := methods.DeleteUnusedTags()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if count > 0 {
+			audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "tag.purge", TargetType: "tag", SummaryAfter: "deleted unused tags: " + strconv.FormatInt(count, 10)})
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(strconv.FormatInt(count, 10) + " unused tags deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// setTagFields copies the submitted fields to the tag. The slug is generated from the name if it's left empty and has to be unused by other tags.
+func setTagFields(tag *structure.Tag, jsonTag *JsonTag) error {
+	name := strings.TrimSpace(jsonTag.Name)
+	if name == "" {
+		return errors.New("Name is required.")
+	}
+	var parent *structure.Tag
+	if jsonTag.ParentId != 0 {
+		if jsonTag.ParentId == tag.Id {
+			return errors.New("A tag can't be its own parent.")
+		}
+		var err error
+		parent, err = database.RetrieveTag(jsonTag.ParentId)
+		if err != nil {
+			return errors.New("The parent tag doesn't exist.")
+		}
+		if isTagBelow(parent, tag.Id) {
+			return errors.New("A tag can't be placed below one of its child tags.")
+		}
+	}
+	tagSlug := strings.TrimSpace(jsonTag.Slug)
+	if tagSlug == "" {
+		// Same slug as if the tag was entered as e.g. "programming/go" in a post
+		tagSlug = methods.TagPath(&structure.Tag{Name: []byte(name), Parent: parent})
+	}
+	tagSlug = slug.Generate(tagSlug, "tags")
+	if tagSlug == "" {
+		return errors.New("The slug needs at least one letter or digit.")
+	}
+	if existingId, err := database.RetrieveTagIdBySlug(tagSlug); err == nil && existingId != tag.Id {
+		return errors.New("Another tag already uses the slug \"" + tagSlug + "\".")
+	}
+	image := strings.TrimSpace(jsonTag.Image)
+	if image != "" && !isLocalPath(image) && !strings.HasPrefix(image, "http://") && !strings.HasPrefix(image, "https://") {
+		return errors.New("The image has to be a path or a full url.")
+	}
+	tag.Name = []byte(name)
+	tag.Slug = tagSlug
+	tag.Description = []byte(strings.TrimSpace(jsonTag.Description))
+	tag.Image = []byte(image)
+	tag.MetaTitle = []byte(strings.TrimSpace(jsonTag.MetaTitle))
+	tag.MetaDescription = []byte(strings.TrimSpace(jsonTag.MetaDescription))
+	tag.ParentId = jsonTag.ParentId
+	tag.Parent = parent
+	return nil
+}
+
+// isTagBelow checks if the tag is the tag with the given id or one of the tags below it.
+func isTagBelow(tag *structure.Tag, id int64) bool {
+	for current := tag; current != nil; current = current.Parent {
+		if current.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTagFromTag(tag *structure.Tag, postCount int64) JsonTag {
+	return JsonTag{Id: tag.Id, Name: string(tag.Name), Slug: tag.Slug, Description: string(tag.Description), Image: string(tag.Image), MetaTitle: string(tag.MetaTitle), MetaDescription: string(tag.MetaDescription), ParentId: tag.ParentId, PostCount: postCount}
+}
+
+func writeJsonTag(w http.ResponseWriter, tag *structure.Tag, postCount int64) {
+	json, err := json.Marshal(jsonTagFromTag(tag, postCount))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(json)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+// API function to get the posts in the trash, most recently deleted first. Authors only see their own posts.
+func apiTrashHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		number := params["number"]
+		page, err := strconv.Atoi(number)
+		if err != nil || page < 1 {
+			http.Error(w, "Not a valid api function!", http.StatusInternalServerError)
+			return
+		}
+		postsPerPage := int64(15)
+		var posts []structure.Post
+		if authentication.CanSeeAllPosts(user) {
+			posts, err = database.RetrieveTrashedPosts(postsPerPage, ((int64(page) - 1) * postsPerPage))
+		} else {
+			posts, err = database.RetrieveTrashedPostsByUser(user.Id, postsPerPage, ((int64(page) - 1) * postsPerPage))
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(postsToJson(posts))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to take a post out of the trash
+func postApiPostRestoreHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		post, err := getEditablePost(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if post.DeletedAt == nil {
+			http.Error(w, "This post is not in the trash.", http.StatusBadRequest)
+			return
+		}
+		err = methods.RestorePost(post.Id, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "post.untrash", TargetType: "post", TargetId: post.Id, TargetName: string(post.Title)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Post restored!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to permanently delete all posts in the trash. Authors only purge their own posts.
+func deleteApiTrashHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var posts []structure.Post
+		if authentication.CanSeeAllPosts(user) {
+			posts, err = database.RetrieveTrashedPosts(-1, 0)
+		} else {
+			posts, err = database.RetrieveTrashedPostsByUser(user.Id, -1, 0)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for index, _ := range posts {
+			err = methods.DeletePost(posts[index].Id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "post.purge", TargetType: "post", TargetId: posts[index].Id, TargetName: string(posts[index].Title)})
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Trash emptied!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
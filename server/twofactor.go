@@ -0,0 +1,486 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"journey/authentication"
+	"journey/database"
+	"journey/date"
+	"journey/filenames"
+	"journey/structure"
+	"journey/structure/methods"
+	"journey/totp"
+)
+
+type JsonTwoFactor struct {
+	Enabled           bool
+	Required          bool
+	RecoveryCodesLeft int64
+}
+
+type JsonTwoFactorSetup struct {
+	Enroll bool // Only set for logins that have to set up two-factor authentication first
+	Secret string
+	Uri    string
+}
+
+type JsonTwoFactorCode struct {
+	Code string
+}
+
+type JsonRecoveryCodes struct {
+	RecoveryCodes []string
+}
+
+type JsonTwoFactorRequirement struct {
+	Required bool
+}
+
+// getTwoFactorLoginHandler serves the page for the second step of the login.
+func getTwoFactorLoginHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if _, err := authentication.GetTwoFactorChallenge(r); err != nil {
+		http.Redirect(w, r, "/admin/login/", 302)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "twofactor.html"))
+	return
+}
+
+// getTwoFactorLoginInfoHandler tells the two-factor login page whether the user has to set up an authenticator app first.
+func getTwoFactorLoginInfoHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	challenge, err := authentication.GetTwoFactorChallenge(r)
+	if err != nil {
+		http.Error(w, "Your login has expired. Please log in again.", http.StatusUnauthorized)
+		return
+	}
+	var setup JsonTwoFactorSetup
+	if challenge.Secret != "" {
+		user, err := database.RetrieveUser(challenge.UserId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		setup = *twoFactorSetupToJson(string(user.Name), challenge.Secret)
+		setup.Enroll = true
+	}
+	json, err := json.Marshal(setup)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(json)
+	return
+}
+
+// postTwoFactorLoginHandler checks the code of the second login step and logs the user in.
+// Wrong codes count as failed logins. Users that set up two-factor authentication during the login get their recovery codes in the response.
+func postTwoFactorLoginHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	challenge, err := authentication.GetTwoFactorChallenge(r)
+	if err != nil {
+		http.Error(w, "Your login has expired. Please log in again.", http.StatusUnauthorized)
+		return
+	}
+	user, err := database.RetrieveUser(challenge.UserId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := string(user.Name)
+	delay, locked, err := authentication.LoginDelay(name, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if delay > 0 {
+		log.Println("Blocked two-factor login attempt for user " + name)
+		http.Error(w, loginDelayMessage(delay, locked), http.StatusTooManyRequests)
+		return
+	}
+	code := r.FormValue("code")
+	var valid bool
+	if challenge.Secret != "" {
+		valid, err = authentication.EnableTwoFactor(user.Id, challenge.Secret, code)
+	} else {
+		valid, err = authentication.VerifyTwoFactorCode(user.Id, code)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		log.Println("Failed two-factor login attempt for user " + name)
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: name, Action: "login.failed", SummaryAfter: "step: \"two-factor\""})
+		err = authentication.RecordLoginAttempt(name, false, r)
+		if err != nil {
+			log.Println("Couldn't record login attempt:", err)
+		}
+		http.Error(w, "Invalid code.", http.StatusUnauthorized)
+		return
+	}
+	var recoveryCodes JsonRecoveryCodes
+	if challenge.Secret != "" {
+		recoveryCodes.RecoveryCodes, err = authentication.GenerateRecoveryCodes(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	err = authentication.RecordLoginAttempt(name, true, r)
+	if err != nil {
+		log.Println("Couldn't record login attempt:", err)
+	}
+	err = authentication.ClearTwoFactorChallenge(w, r)
+	if err != nil {
+		log.Println("Couldn't delete two-factor challenge:", err)
+	}
+	logInUser(name, challenge.Remember, w, r)
+	json, err := json.Marshal(recoveryCodes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(json)
+	return
+}
+
+// API function to get the two-factor status of the logged in user
+func getApiTwoFactorHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var twoFactor JsonTwoFactor
+		twoFactor.Enabled, err = authentication.TwoFactorEnabled(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		twoFactor.Required, err = database.RetrieveRequireTwoFactor()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		twoFactor.RecoveryCodesLeft, err = database.RetrieveNumberOfUnusedRecoveryCodes(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(twoFactor)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to start setting up two-factor authentication. The new secret is only used after it has been confirmed with a code.
+func postApiTwoFactorSetupHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		enabled, err := authentication.TwoFactorEnabled(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if enabled {
+			http.Error(w, "Two-factor authentication is already enabled.", http.StatusBadRequest)
+			return
+		}
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		err = database.InsertTwoFactor(user.Id, secret, date.GetCurrentTime())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(twoFactorSetupToJson(userName, secret))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to confirm the new secret with a code. Returns the recovery codes.
+func postApiTwoFactorEnableHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var code JsonTwoFactorCode
+		err = decoder.Decode(&code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		twoFactor, err := database.RetrieveTwoFactor(user.Id)
+		if err != nil {
+			http.Error(w, "Please set up two-factor authentication first.", http.StatusBadRequest)
+			return
+		}
+		if twoFactor.Enabled {
+			http.Error(w, "Two-factor authentication is already enabled.", http.StatusBadRequest)
+			return
+		}
+		valid, err := authentication.EnableTwoFactor(user.Id, twoFactor.Secret, code.Code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			http.Error(w, "Invalid code.", http.StatusBadRequest)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "twofactor.enable", TargetType: "user", TargetId: user.Id, TargetName: userName})
+		writeRecoveryCodes(w, user.Id)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to turn off two-factor authentication. Requires a current code.
+func postApiTwoFactorDisableHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		required, err := database.RetrieveRequireTwoFactor()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if required {
+			http.Error(w, "Two-factor authentication is required for all users of this blog.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var code JsonTwoFactorCode
+		err = decoder.Decode(&code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		valid, err := authentication.VerifyTwoFactorCode(user.Id, code.Code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			http.Error(w, "Invalid code.", http.StatusBadRequest)
+			return
+		}
+		err = database.DeleteTwoFactorByUserId(user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "twofactor.disable", TargetType: "user", TargetId: user.Id, TargetName: userName})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Two-factor authentication disabled!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to replace the recovery codes of the logged in user. Requires a current code.
+func postApiRecoveryCodesHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var code JsonTwoFactorCode
+		err = decoder.Decode(&code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		valid, err := authentication.VerifyTwoFactorCode(user.Id, code.Code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			http.Error(w, "Invalid code.", http.StatusBadRequest)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "twofactor.recovery_codes", TargetType: "user", TargetId: user.Id, TargetName: userName})
+		writeRecoveryCodes(w, user.Id)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to make two-factor authentication mandatory for all users (owner only)
+func patchApiTwoFactorRequirementHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanRequireTwoFactor(user) {
+			http.Error(w, "Only the owner of the blog can change this setting.", http.StatusForbidden)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var requirement JsonTwoFactorRequirement
+		err = decoder.Decode(&requirement)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		err = database.UpdateRequireTwoFactor(requirement.Required, date.GetCurrentTime(), user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "twofactor.requirement", TargetType: "blog", SummaryAfter: "required: " + strconv.FormatBool(requirement.Required)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Two-factor requirement updated!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to turn off two-factor authentication for a user who lost their device
+func deleteApiUserTwoFactorHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		userId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || userId < 1 {
+			http.Error(w, "Wrong user id.", http.StatusInternalServerError)
+			return
+		}
+		target, err := database.RetrieveUser(userId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageUser(user, target) {
+			http.Error(w, "You don't have permission to manage this user.", http.StatusForbidden)
+			return
+		}
+		err = database.DeleteTwoFactorByUserId(target.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "user.twofactor_reset", TargetType: "user", TargetId: target.Id, TargetName: string(target.Name)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Two-factor authentication reset!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// twoFactorRequired reports whether the user has to enter a code after the password and whether they still have to set up two-factor authentication.
+func twoFactorRequired(userId int64) (bool, bool, error) {
+	enabled, err := authentication.TwoFactorEnabled(userId)
+	if err != nil {
+		return false, false, err
+	}
+	if enabled {
+		return true, false, nil
+	}
+	required, err := database.RetrieveRequireTwoFactor()
+	if err != nil {
+		return false, false, err
+	}
+	return required, required, nil
+}
+
+// loginDelayMessage explains how long to wait before the next login attempt (the same text the login page shows).
+func loginDelayMessage(delay time.Duration, locked bool) string {
+	seconds := int64(math.Ceil(delay.Seconds()))
+	duration := strconv.FormatInt(seconds, 10) + " seconds"
+	if seconds > 90 {
+		duration = strconv.FormatInt(int64(math.Ceil(float64(seconds)/60)), 10) + " minutes"
+	}
+	if locked {
+		return "This account has been locked because of too many failed login attempts. Please try again in " + duration + "."
+	}
+	return "Too many failed login attempts. Please wait " + duration + " before trying again."
+}
+
+func writeRecoveryCodes(w http.ResponseWriter, userId int64) {
+	recoveryCodes, err := authentication.GenerateRecoveryCodes(userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json, err := json.Marshal(JsonRecoveryCodes{RecoveryCodes: recoveryCodes})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(json)
+}
+
+func twoFactorSetupToJson(account string, secret string) *JsonTwoFactorSetup {
+	issuer := "Journey"
+	methods.Blog.RLock()
+	if len(methods.Blog.Title) > 0 {
+		issuer = string(methods.Blog.Title)
+	}
+	methods.Blog.RUnlock()
+	return &JsonTwoFactorSetup{Secret: secret, Uri: totp.ProvisioningUri(issuer, account, secret)}
+}
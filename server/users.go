@@ -0,0 +1,506 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"journey/authentication"
+	"journey/configuration"
+	"journey/database"
+	"journey/date"
+	"journey/filenames"
+	"journey/slug"
+	"journey/structure"
+	"journey/structure/methods"
+	"journey/templates"
+)
+
+// Invitations are valid for one week
+const inviteLifetime = 7 * 24 * time.Hour
+
+var errInviteInvalid = errors.New("invite is not pending or has expired")
+
+type JsonNewUser struct {
+	Name     string
+	Email    string
+	Password string
+	Role     int
+}
+
+type JsonUserRole struct {
+	Role int
+}
+
+type JsonUserStatus struct {
+	Status string
+}
+
+type JsonInvite struct {
+	Id        int64
+	Email     string
+	Role      int
+	ExpiresAt *time.Time
+	CreatedAt *time.Time
+	Url       string // Only set when the invite is created since we don't store the token itself
+}
+
+// getInvitationHandler serves the form to accept an invitation.
+func getInvitationHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	_, err := getValidInvite(params["token"])
+	if err != nil {
+		http.Error(w, "This invitation is invalid or has expired.", http.StatusNotFound)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(filenames.AdminFilepath, "invitation.html"))
+	return
+}
+
+// postInvitationHandler creates the invited user and logs them in.
+func postInvitationHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	invite, err := getValidInvite(params["token"])
+	if err != nil {
+		http.Error(w, "This invitation is invalid or has expired.", http.StatusNotFound)
+		return
+	}
+	name := r.FormValue("name")
+	password := r.FormValue("password")
+	if name == "" || password == "" {
+		http.Redirect(w, r, r.URL.Path, 302)
+		return
+	}
+	_, err = database.RetrieveUserByName([]byte(name))
+	if err == nil {
+		http.Error(w, "This user name is already taken.", http.StatusConflict)
+		return
+	}
+	_, err = database.RetrieveUserByEmail(invite.Email)
+	if err == nil {
+		http.Error(w, "A user with this email address already exists.", http.StatusConflict)
+		return
+	}
+	hashedPassword, err := authentication.EncryptPassword(password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user := structure.User{Name: []byte(name), Slug: slug.Generate(name, "users"), Email: invite.Email, Image: []byte(filenames.DefaultUserImageFilename), Cover: []byte(filenames.DefaultUserCoverFilename)}
+	err = methods.AcceptInvite(invite, &user, hashedPassword)
+	if err == methods.ErrInviteUsed {
+		http.Error(w, "This invitation is invalid or has expired.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	audit(r, &structure.AuditEntry{UserId: user.Id, UserName: name, Action: "invite.accept", TargetType: "invite", TargetId: invite.Id, TargetName: string(invite.Email), SummaryAfter: "role: " + auditRole(invite.Role)})
+	logInUser(name, false, w, r)
+	http.Redirect(w, r, "/admin/", 302)
+	return
+}
+
+// API function to get all users of the blog
+func apiUsersHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanListUsers(user) {
+			http.Error(w, "You don't have permission to list users.", http.StatusForbidden)
+			return
+		}
+		users, err := database.RetrieveAllUsers()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonUsers := make([]JsonUser, len(users))
+		for index, _ := range users {
+			jsonUsers[index] = *userToJson(&users[index])
+		}
+		json, err := json.Marshal(jsonUsers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to create a user directly (without an invitation)
+func postApiUsersHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var json JsonNewUser
+		err = decoder.Decode(&json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanAssignRole(user, json.Role) {
+			http.Error(w, "You don't have permission to create a user with this role.", http.StatusForbidden)
+			return
+		}
+		if json.Name == "" || json.Email == "" || json.Password == "" {
+			http.Error(w, "Name, email and password are required.", http.StatusBadRequest)
+			return
+		}
+		_, err = database.RetrieveUserByName([]byte(json.Name))
+		if err == nil {
+			http.Error(w, "This user name is already taken.", http.StatusConflict)
+			return
+		}
+		_, err = database.RetrieveUserByEmail([]byte(json.Email))
+		if err == nil {
+			http.Error(w, "A user with this email address already exists.", http.StatusConflict)
+			return
+		}
+		hashedPassword, err := authentication.EncryptPassword(json.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		newUser := structure.User{Name: []byte(json.Name), Slug: slug.Generate(json.Name, "users"), Email: []byte(json.Email), Image: []byte(filenames.DefaultUserImageFilename), Cover: []byte(filenames.DefaultUserCoverFilename), Role: json.Role}
+		err = methods.SaveUser(&newUser, hashedPassword, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "user.create", TargetType: "user", TargetId: newUser.Id, TargetName: json.Name, SummaryAfter: "role: " + auditRole(json.Role)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User created!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to change the role of a user
+func patchApiUserRoleHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, target, err := getUserAndTarget(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var json JsonUserRole
+		err = decoder.Decode(&json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageUser(user, target) || !authentication.CanAssignRole(user, json.Role) {
+			http.Error(w, "You don't have permission to change the role of this user.", http.StatusForbidden)
+			return
+		}
+		err = methods.UpdateUserRole(target.Id, json.Role)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "user.role", TargetType: "user", TargetId: target.Id, TargetName: string(target.Name), SummaryBefore: "role: " + auditRole(target.Role), SummaryAfter: "role: " + auditRole(json.Role)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User role updated!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to suspend or reactivate a user
+func patchApiUserStatusHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, target, err := getUserAndTarget(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var json JsonUserStatus
+		err = decoder.Decode(&json)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if json.Status != structure.UserStatusActive && json.Status != structure.UserStatusInactive {
+			http.Error(w, "Unknown user status.", http.StatusBadRequest)
+			return
+		}
+		if !authentication.CanManageUser(user, target) {
+			http.Error(w, "You don't have permission to change the status of this user.", http.StatusForbidden)
+			return
+		}
+		err = methods.UpdateUserStatus(target.Id, json.Status, user.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "user.status", TargetType: "user", TargetId: target.Id, TargetName: string(target.Name), SummaryBefore: "status: " + strconv.Quote(target.Status), SummaryAfter: "status: " + strconv.Quote(json.Status)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User status updated!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to delete a user. The posts of that user are handed over to the owner.
+func deleteApiUserHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, target, err := getUserAndTarget(userName, params["id"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageUser(user, target) {
+			http.Error(w, "You don't have permission to delete this user.", http.StatusForbidden)
+			return
+		}
+		err = methods.DeleteUser(target.Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// The posts of the user were handed over to the owner
+		templates.InvalidateSitemap()
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "user.delete", TargetType: "user", TargetId: target.Id, TargetName: string(target.Name)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("User deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to get all pending invitations
+func apiInvitesHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanListUsers(user) {
+			http.Error(w, "You don't have permission to list invitations.", http.StatusForbidden)
+			return
+		}
+		invites, err := database.RetrievePendingInvites()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonInvites := make([]JsonInvite, len(invites))
+		for index, _ := range invites {
+			jsonInvites[index] = *inviteToJson(&invites[index])
+		}
+		json, err := json.Marshal(jsonInvites)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to invite a new user. Returns the invite including the link that has to be handed to the new user.
+func postApiInviteHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		decoder := json.NewDecoder(r.Body)
+		var requestedInvite JsonInvite
+		err = decoder.Decode(&requestedInvite)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanAssignRole(user, requestedInvite.Role) {
+			http.Error(w, "You don't have permission to invite a user with this role.", http.StatusForbidden)
+			return
+		}
+		if requestedInvite.Email == "" {
+			http.Error(w, "Email address is required.", http.StatusBadRequest)
+			return
+		}
+		_, err = database.RetrieveUserByEmail([]byte(requestedInvite.Email))
+		if err == nil {
+			http.Error(w, "A user with this email address already exists.", http.StatusConflict)
+			return
+		}
+		token, err := authentication.GenerateToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		expiresAt := date.GetCurrentTime().Add(inviteLifetime)
+		invite := structure.Invite{Email: []byte(requestedInvite.Email), Role: requestedInvite.Role, ExpiresAt: &expiresAt, CreatedBy: user.Id}
+		err = methods.SaveInvite(&invite, authentication.HashToken(token))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "invite.create", TargetType: "invite", TargetId: invite.Id, TargetName: requestedInvite.Email, SummaryAfter: "role: " + auditRole(requestedInvite.Role)})
+		jsonInvite := inviteToJson(&invite)
+		jsonInvite.Url = configuration.Config.Url + "/admin/invitation/" + token + "/"
+		json, err := json.Marshal(jsonInvite)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// API function to revoke an invitation
+func deleteApiInviteHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanListUsers(user) {
+			http.Error(w, "You don't have permission to revoke invitations.", http.StatusForbidden)
+			return
+		}
+		inviteId, err := strconv.ParseInt(params["id"], 10, 64)
+		if err != nil || inviteId < 1 {
+			http.Error(w, "Wrong invite id.", http.StatusInternalServerError)
+			return
+		}
+		invite, err := database.RetrieveInviteById(inviteId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Only users who could have sent the invitation may revoke it
+		if !authentication.CanAssignRole(user, invite.Role) {
+			http.Error(w, "You don't have permission to revoke an invitation with this role.", http.StatusForbidden)
+			return
+		}
+		err = methods.DeleteInvite(inviteId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		audit(r, &structure.AuditEntry{UserId: user.Id, UserName: userName, Action: "invite.revoke", TargetType: "invite", TargetId: inviteId, TargetName: string(invite.Email), SummaryBefore: "role: " + auditRole(invite.Role)})
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Invite deleted!"))
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getUserAndTarget retrieves the authenticated user and the user with the given id.
+func getUserAndTarget(userName string, id string) (*structure.User, *structure.User, error) {
+	user, err := getUser(userName)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := database.RetrieveUser(targetId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, target, nil
+}
+
+// getValidInvite returns the pending, not yet expired invite that belongs to the token.
+func getValidInvite(token string) (*structure.Invite, error) {
+	invite, err := database.RetrieveInviteByTokenHash(authentication.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invite.Status != "pending" || invite.ExpiresAt == nil || invite.ExpiresAt.Before(date.GetCurrentTime()) {
+		return nil, errInviteInvalid
+	}
+	return invite, nil
+}
+
+func inviteToJson(invite *structure.Invite) *JsonInvite {
+	var jsonInvite JsonInvite
+	jsonInvite.Id = invite.Id
+	jsonInvite.Email = string(invite.Email)
+	jsonInvite.Role = invite.Role
+	jsonInvite.ExpiresAt = invite.ExpiresAt
+	jsonInvite.CreatedAt = invite.CreatedAt
+	return &jsonInvite
+}
+
+// API function to get the most recent login lockouts
+func apiLockoutsHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	userName := authentication.GetUserName(r)
+	if userName != "" {
+		user, err := getUser(userName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !authentication.CanManageSettings(user) {
+			http.Error(w, "You don't have permission to review lockouts.", http.StatusForbidden)
+			return
+		}
+		lockouts, err := database.RetrieveLockouts(100)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json, err := json.Marshal(lockouts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(json)
+		return
+	} else {
+		http.Error(w, "Not logged in!", http.StatusInternalServerError)
+		return
+	}
+}
@@ -9,6 +9,16 @@ import (
 )
 
 func Generate(input string, table string) string {
+	// Internal tags start with '#'. Their slugs start with "hash-", so they don't collide with the public tag of the same name.
+	if table == "tags" {
+		names := strings.Split(input, "/")
+		for index, _ := range names {
+			if name := strings.TrimSpace(names[index]); strings.HasPrefix(name, "#") {
+				names[index] = "hash-" + name[1:]
+			}
+		}
+		input = strings.Join(names, "/")
+	}
 	hyphensRegex := regexp.MustCompile("-+")
 	output := hyphensRegex.ReplaceAllString(strings.Map(func(r rune) rune {
 		switch {
@@ -34,7 +44,7 @@ func Generate(input string, table string) string {
 		output = string(runes)
 	}
 	// Don't allow a few specific slugs that are used by the blog
-	if table == "posts" && (output == "rss" || output == "tag" || output == "author" || output == "page" || output == "admin") {
+	if table == "posts" && (output == "rss" || output == "tag" || output == "author" || output == "page" || output == "admin" || output == "search" || output == "atom") {
 		output = generateUniqueSlug(output, table, 2)
 	} else if table == "tags" || table == "navigation" { // We want duplicate tag and navigation slugs
 		return output
@@ -0,0 +1,15 @@
+package structure
+
+import (
+	"time"
+)
+
+// ApiKey grants read access to the published content of the blog through the content api.
+// Content api keys are meant to be used in themes and front-end code, so the secret is stored as it is and can be shown in the admin.
+type ApiKey struct {
+	Id        int64
+	Name      []byte
+	Secret    string
+	CreatedAt *time.Time
+	CreatedBy int64
+}
@@ -0,0 +1,22 @@
+package structure
+
+import (
+	"time"
+)
+
+// Scopes of api tokens
+const (
+	ApiTokenScopeRead = "read" // Only GET requests
+	ApiTokenScopeFull = "full" // Everything the user may do in the admin area
+)
+
+// ApiToken lets scripts use the admin api in the name of a user. Only the hash of the token is stored in the database.
+type ApiToken struct {
+	Id         int64
+	UserId     int64
+	UserName   []byte
+	Name       []byte
+	Scope      string
+	CreatedAt  *time.Time
+	LastUsedAt *time.Time
+}
@@ -0,0 +1,20 @@
+package structure
+
+import (
+	"time"
+)
+
+// AuditEntry: a change in the admin area or a login, with the user who did it. The summaries describe the changed fields before and after the change.
+type AuditEntry struct {
+	Id            int64
+	UserId        int64
+	UserName      string
+	Action        string // e.g. "post.update"
+	TargetType    string // e.g. "post"
+	TargetId      int64
+	TargetName    string
+	SummaryBefore string
+	SummaryAfter  string
+	IpAddress     string
+	CreatedAt     *time.Time
+}
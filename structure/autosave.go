@@ -0,0 +1,20 @@
+package structure
+
+import (
+	"time"
+)
+
+// Autosave: the unsaved changes a user is making to a post. Every user has at most one autosave per post, which is removed when the post is saved.
+type Autosave struct {
+	PostId          int64
+	UserId          int64
+	Title           []byte
+	Markdown        []byte
+	Tags            []byte // Comma separated tag names
+	MetaDescription []byte
+	Image           []byte
+	IsFeatured      bool
+	IsPage          bool
+	BaseVersion     *time.Time // Version of the post the changes were made to
+	Date            *time.Time
+}
@@ -2,19 +2,24 @@ package structure
 
 import (
 	"sync"
+	"time"
 )
 
 // Blog: settings that are used for template execution
 type Blog struct {
 	sync.RWMutex
-	Url             []byte
-	Title           []byte
-	Description     []byte
-	Logo            []byte
-	Cover           []byte
-	AssetPath       []byte
-	PostCount       int64
-	PostsPerPage    int64
-	ActiveTheme     string
-	NavigationItems []Navigation
+	Url               []byte
+	Title             []byte
+	Description       []byte
+	Logo              []byte
+	Cover             []byte
+	AssetPath         []byte
+	PostCount         int64
+	PostsPerPage      int64
+	ActiveTheme       string
+	NavigationItems   []Navigation
+	CodeInjectionHead []byte // Added to ghost_head on every page
+	CodeInjectionFoot []byte // Added to ghost_foot on every page
+	// Every change to posts and tags generates the blog again, so this only moves forward
+	GeneratedAt time.Time
 }
@@ -0,0 +1,16 @@
+package structure
+
+import (
+	"time"
+)
+
+// Invite: a pending invitation for a new user to join the blog with the given role
+type Invite struct {
+	Id        int64
+	Email     []byte
+	Role      int
+	Status    string
+	ExpiresAt *time.Time
+	CreatedAt *time.Time
+	CreatedBy int64
+}
@@ -0,0 +1,15 @@
+package structure
+
+import (
+	"time"
+)
+
+// Lockout: an account (UserName) or an ip address (IpAddress) that was locked after too many failed logins
+type Lockout struct {
+	Id          int64
+	UserName    string
+	IpAddress   string
+	Failures    int64
+	LockedUntil *time.Time
+	CreatedAt   *time.Time
+}
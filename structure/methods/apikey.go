@@ -0,0 +1,22 @@
+package methods
+
+import (
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+)
+
+func SaveApiKey(k *structure.ApiKey) error {
+	createdAt := date.GetCurrentTime()
+	apiKeyId, err := database.InsertApiKey(k.Name, k.Secret, createdAt, k.CreatedBy)
+	if err != nil {
+		return err
+	}
+	k.Id = apiKeyId
+	k.CreatedAt = &createdAt
+	return nil
+}
+
+func DeleteApiKey(apiKeyId int64) error {
+	return database.DeleteApiKeyById(apiKeyId)
+}
@@ -0,0 +1,22 @@
+package methods
+
+import (
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+)
+
+func SaveApiToken(t *structure.ApiToken, tokenHash string) error {
+	createdAt := date.GetCurrentTime()
+	apiTokenId, err := database.InsertApiToken(t.UserId, t.Name, tokenHash, t.Scope, createdAt)
+	if err != nil {
+		return err
+	}
+	t.Id = apiTokenId
+	t.CreatedAt = &createdAt
+	return nil
+}
+
+func DeleteApiToken(apiTokenId int64) error {
+	return database.DeleteApiTokenById(apiTokenId)
+}
@@ -0,0 +1,13 @@
+package methods
+
+import (
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+)
+
+func SaveAuditEntry(e *structure.AuditEntry) error {
+	createdAt := date.GetCurrentTime()
+	e.CreatedAt = &createdAt
+	return database.InsertAuditEntry(e.UserId, e.UserName, e.Action, e.TargetType, e.TargetId, e.TargetName, e.SummaryBefore, e.SummaryAfter, e.IpAddress, createdAt)
+}
@@ -21,7 +21,7 @@ func UpdateBlog(b *structure.Blog, userId int64) error {
 	if err != nil {
 		return err
 	}
-	err = database.UpdateSettings(b.Title, b.Description, b.Logo, b.Cover, b.PostsPerPage, b.ActiveTheme, navigation, date.GetCurrentTime(), userId)
+	err = database.UpdateSettings(b.Title, b.Description, b.Logo, b.Cover, b.PostsPerPage, b.ActiveTheme, navigation, b.CodeInjectionHead, b.CodeInjectionFoot, date.GetCurrentTime(), userId)
 	if err != nil {
 		return err
 	}
@@ -60,6 +60,7 @@ func GenerateBlog() error {
 	// Add parameters that are not saved in db
 	blog.Url = []byte(configuration.Config.Url)
 	blog.AssetPath = assetPath
+	blog.GeneratedAt = date.GetCurrentTime()
 	// Create navigation slugs
 	for index, _ := range blog.NavigationItems {
 		blog.NavigationItems[index].Slug = slug.Generate(blog.NavigationItems[index].Label, "navigation")
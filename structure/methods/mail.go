@@ -0,0 +1,31 @@
+package methods
+
+import (
+	"journey/configuration"
+	"journey/mail"
+	"sync"
+)
+
+// The mailer is shared by all messages so that concurrent messages are written one after the other
+var mailer mail.Mailer
+var mailerOnce sync.Once
+
+// SendMail sends the message with the transport that is set in the config file.
+func SendMail(message *mail.Message) error {
+	mailerOnce.Do(func() {
+		mailer = newMailer()
+	})
+	return mailer.Send(message)
+}
+
+func newMailer() mail.Mailer {
+	settings := configuration.Config.Mail
+	switch settings.Transport {
+	case "smtp":
+		return &mail.SmtpMailer{Host: settings.SmtpHost, Port: settings.SmtpPort, User: settings.SmtpUser, Password: settings.SmtpPassword, From: settings.From}
+	case "file":
+		return &mail.FileMailer{Path: settings.FilePath, From: settings.From}
+	default:
+		return &mail.LogMailer{From: settings.From}
+	}
+}
@@ -1,31 +1,34 @@
 package methods
 
 import (
+	"errors"
 	"journey/database"
 	"journey/date"
 	"journey/structure"
 	"log"
+	"time"
 )
 
+var ErrPostChanged = errors.New("The post was changed by somebody else in the meantime.")
+
 func SavePost(p *structure.Post) error {
 	tagIds := make([]int64, 0)
 	// Insert tags
-	for _, tag := range p.Tags {
-		// Tag slug might already be in database
-		tagId, err := database.RetrieveTagIdBySlug(tag.Slug)
+	for index, _ := range p.Tags {
+		tagId, err := saveTagWithParents(&p.Tags[index], p.Author.Id)
 		if err != nil {
-			// Tag is probably not in database yet
-			tagId, err = database.InsertTag(tag.Name, tag.Slug, date.GetCurrentTime(), p.Author.Id)
-			if err != nil {
-				return err
-			}
+			return err
 		}
 		if tagId != 0 {
 			tagIds = append(tagIds, tagId)
 		}
 	}
 	// Insert post
-	postId, err := database.InsertPost(p.Title, p.Slug, p.Markdown, p.Html, p.IsFeatured, p.IsPage, p.IsPublished, p.MetaDescription, p.Image, *p.Date, p.Author.Id)
+	postId, err := database.InsertPost(p.Title, p.Slug, p.Markdown, p.Html, p.IsFeatured, p.IsPage, p.IsPublished, p.IsScheduled, p.MetaDescription, p.Image, *p.Date, date.GetCurrentTime(), p.Author.Id)
+	if err != nil {
+		return err
+	}
+	err = updatePostMetadata(postId, p)
 	if err != nil {
 		return err
 	}
@@ -36,33 +39,49 @@ func SavePost(p *structure.Post) error {
 			return err
 		}
 	}
+	// Save first revision
+	p.Id = postId
+	err = saveRevision(p, p.Author.Id)
+	if err != nil {
+		return err
+	}
 	// Generate new global blog
 	err = GenerateBlog()
 	if err != nil {
 		log.Panic("Error: couldn't generate blog data:", err)
 	}
+	// The post might have been scheduled or its schedule might have changed
+	wakeUpScheduler()
 	return nil
 }
 
+// UpdatePost saves the changes to the post. If p.UpdatedAt is set, the post is only saved if it wasn't updated since then and ErrPostChanged is returned otherwise. If it's nil, the changes are saved regardless.
 func UpdatePost(p *structure.Post) error {
 	tagIds := make([]int64, 0)
 	// Insert tags
-	for _, tag := range p.Tags {
-		// Tag slug might already be in database
-		tagId, err := database.RetrieveTagIdBySlug(tag.Slug)
+	for index, _ := range p.Tags {
+		tagId, err := saveTagWithParents(&p.Tags[index], p.Author.Id)
 		if err != nil {
-			// Tag is probably not in database yet
-			tagId, err = database.InsertTag(tag.Name, tag.Slug, date.GetCurrentTime(), p.Author.Id)
-			if err != nil {
-				return err
-			}
+			return err
 		}
 		if tagId != 0 {
 			tagIds = append(tagIds, tagId)
 		}
 	}
+	// Make sure the version before this update can be restored
+	err := saveInitialRevision(p.Id, p.Author.Id)
+	if err != nil {
+		return err
+	}
 	// Update post
-	err := database.UpdatePost(p.Id, p.Title, p.Slug, p.Markdown, p.Html, p.IsFeatured, p.IsPage, p.IsPublished, p.MetaDescription, p.Image, *p.Date, p.Author.Id)
+	updated, err := database.UpdatePost(p.Id, p.Title, p.Slug, p.Markdown, p.Html, p.IsFeatured, p.IsPage, p.IsPublished, p.IsScheduled, p.MetaDescription, p.Image, *p.Date, date.GetCurrentTime(), p.Author.Id, p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrPostChanged
+	}
+	err = updatePostMetadata(p.Id, p)
 	if err != nil {
 		return err
 	}
@@ -78,14 +97,58 @@ func UpdatePost(p *structure.Post) error {
 			return err
 		}
 	}
+	// Save revision
+	err = saveRevision(p, p.Author.Id)
+	if err != nil {
+		return err
+	}
+	// Generate new global blog
+	err = GenerateBlog()
+	if err != nil {
+		log.Panic("Error: couldn't generate blog data:", err)
+	}
+	// The post might have been scheduled or its schedule might have changed
+	wakeUpScheduler()
+	return nil
+}
+
+func updatePostMetadata(postId int64, p *structure.Post) error {
+	return database.UpdatePostMetadata(postId, p.MetaTitle, p.CustomExcerpt, p.CanonicalUrl, p.OgTitle, p.OgDescription, p.OgImage, p.TwitterTitle, p.TwitterDescription, p.TwitterImage, p.CodeInjectionHead, p.CodeInjectionFoot)
+}
+
+// TrashPost moves the post to the trash. It disappears from the blog but can be restored until it's purged.
+func TrashPost(postId int64, userId int64) error {
+	currentTime := date.GetCurrentTime()
+	err := database.UpdatePostDeleted(postId, &currentTime, currentTime, userId)
+	if err != nil {
+		return err
+	}
 	// Generate new global blog
 	err = GenerateBlog()
 	if err != nil {
 		log.Panic("Error: couldn't generate blog data:", err)
 	}
+	// A scheduled post in the trash must not be published anymore
+	wakeUpScheduler()
 	return nil
 }
 
+// RestorePost takes the post out of the trash. It keeps the status it had before it was deleted.
+func RestorePost(postId int64, userId int64) error {
+	err := database.UpdatePostDeleted(postId, nil, date.GetCurrentTime(), userId)
+	if err != nil {
+		return err
+	}
+	// Generate new global blog
+	err = GenerateBlog()
+	if err != nil {
+		log.Panic("Error: couldn't generate blog data:", err)
+	}
+	wakeUpScheduler()
+	return nil
+}
+
+// DeletePost removes the post and its revisions permanently.
 func DeletePost(postId int64) error {
 	err := database.DeletePostById(postId)
 	if err != nil {
@@ -98,3 +161,18 @@ func DeletePost(postId int64) error {
 	}
 	return nil
 }
+
+// PurgeTrash permanently deletes all posts that were moved to the trash before the given date and returns how many posts were deleted.
+func PurgeTrash(before time.Time) (int, error) {
+	postIds, err := database.RetrieveTrashedPostIdsBefore(before)
+	if err != nil {
+		return 0, err
+	}
+	for index, postId := range postIds {
+		err = database.DeletePostById(postId)
+		if err != nil {
+			return index, err
+		}
+	}
+	return len(postIds), nil
+}
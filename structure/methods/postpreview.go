@@ -0,0 +1,52 @@
+package methods
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+)
+
+var ErrPreviewNotAvailable = errors.New("This preview link is invalid or has expired.")
+
+// SavePostPreview enables the preview link of the post. If lifetime is 0, the link stays valid until it's revoked.
+func SavePostPreview(postId int64, lifetime time.Duration, userId int64) (*structure.PostPreview, error) {
+	createdAt := date.GetCurrentTime()
+	preview := structure.PostPreview{PostId: postId, CreatedAt: &createdAt, CreatedBy: userId}
+	if lifetime > 0 {
+		expiresAt := createdAt.Add(lifetime)
+		preview.ExpiresAt = &expiresAt
+	}
+	err := database.InsertPostPreview(postId, preview.ExpiresAt, createdAt, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+func DeletePostPreview(postId int64) error {
+	return database.DeletePostPreviewByPostId(postId)
+}
+
+// GetPreviewPost returns the post with the uuid if its preview link is enabled and hasn't expired.
+func GetPreviewPost(uuid string) (*structure.Post, error) {
+	post, err := database.RetrievePostByUuid(uuid)
+	if err == sql.ErrNoRows {
+		return nil, ErrPreviewNotAvailable
+	} else if err != nil {
+		return nil, err
+	}
+	preview, err := database.RetrievePostPreview(post.Id)
+	if err == sql.ErrNoRows {
+		return nil, ErrPreviewNotAvailable
+	} else if err != nil {
+		return nil, err
+	}
+	if preview.ExpiresAt != nil && !preview.ExpiresAt.After(date.GetCurrentTime()) {
+		return nil, ErrPreviewNotAvailable
+	}
+	return post, nil
+}
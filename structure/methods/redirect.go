@@ -0,0 +1,52 @@
+package methods
+
+import (
+	"journey/database"
+	"journey/date"
+	"journey/helpers"
+	"journey/structure"
+)
+
+// SaveRedirect stores the redirect. If its target is redirected itself, the redirect points to the final target instead.
+func SaveRedirect(r *structure.Redirect) error {
+	if target, err := database.RetrieveRedirectForPath(r.To); err == nil {
+		if to, ok := helpers.RedirectTarget(target, r.To); ok {
+			r.To = to
+		}
+	}
+	createdAt := date.GetCurrentTime()
+	redirectId, err := database.InsertRedirect(r.From, r.To, r.Match, r.Automatic, createdAt, r.CreatedBy)
+	if err != nil {
+		return err
+	}
+	r.Id = redirectId
+	r.CreatedAt = &createdAt
+	return nil
+}
+
+// SaveSlugRedirect records that a post moved from oldSlug to newSlug, so that links to the old url keep working.
+func SaveSlugRedirect(oldSlug string, newSlug string, userId int64) error {
+	newPath := "/" + newSlug + "/"
+	// The post lives at the new path now, an older redirect away from it would send visitors in a circle
+	err := database.DeleteRedirectByFromPath(newPath, structure.RedirectMatchExact)
+	if err != nil {
+		return err
+	}
+	redirect := structure.Redirect{From: "/" + oldSlug + "/", To: newPath, Match: structure.RedirectMatchExact, Automatic: true, CreatedBy: userId}
+	return SaveRedirect(&redirect)
+}
+
+// SaveTagSlugRedirect records that a tag moved from oldSlug to newSlug. All paths below the old tag page (e.g. its feeds) are redirected.
+func SaveTagSlugRedirect(oldSlug string, newSlug string, userId int64) error {
+	newPath := "/tag/" + newSlug + "/"
+	err := database.DeleteRedirectByFromPath(newPath, structure.RedirectMatchPrefix)
+	if err != nil {
+		return err
+	}
+	redirect := structure.Redirect{From: "/tag/" + oldSlug + "/", To: newPath, Match: structure.RedirectMatchPrefix, Automatic: true, CreatedBy: userId}
+	return SaveRedirect(&redirect)
+}
+
+func DeleteRedirect(redirectId int64) error {
+	return database.DeleteRedirectById(redirectId)
+}
@@ -0,0 +1,39 @@
+package methods
+
+import (
+	"strings"
+
+	"journey/configuration"
+	"journey/database"
+	"journey/date"
+	"journey/structure"
+)
+
+// saveRevision stores a snapshot of the post and removes revisions that exceed the configured limit.
+func saveRevision(p *structure.Post, createdBy int64) error {
+	tagNames := make([]string, len(p.Tags))
+	for index, _ := range p.Tags {
+		tagNames[index] = TagPath(&p.Tags[index])
+	}
+	_, err := database.InsertRevision(p.Id, p.Title, p.Markdown, []byte(strings.Join(tagNames, ",")), p.MetaDescription, p.Image, p.IsFeatured, p.IsPage, date.GetCurrentTime(), createdBy)
+	if err != nil {
+		return err
+	}
+	return database.DeleteOldRevisionsForPostId(p.Id, configuration.Config.MaxPostRevisions)
+}
+
+// saveInitialRevision snapshots the stored version of posts that were created before revisions existed. This way the first update can be reverted, too.
+func saveInitialRevision(postId int64, createdBy int64) error {
+	count, err := database.RetrieveNumberOfRevisionsByPostId(postId)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	post, err := database.RetrievePostById(postId)
+	if err != nil {
+		return err
+	}
+	return saveRevision(post, createdBy)
+}
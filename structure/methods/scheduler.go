@@ -0,0 +1,91 @@
+package methods
+
+import (
+	"log"
+	"time"
+
+	"journey/configuration"
+	"journey/database"
+	"journey/date"
+)
+
+// The scheduler checks for due posts at least this often, even if no post is scheduled
+const maxSchedulerSleep = time.Hour
+
+// Never sleep for less than this to avoid spinning if a post can't be published for some reason
+const minSchedulerSleep = time.Second
+
+// Used to wake up the scheduler if the schedule of a post was changed
+var schedulerWakeup = make(chan bool, 1)
+
+// StartPostScheduler starts a background goroutine that publishes scheduled posts as soon as their publication date is reached.
+func StartPostScheduler() {
+	go func() {
+		for {
+			publishScheduledPosts()
+			purgeExpiredTrash()
+			timer := time.NewTimer(durationUntilNextScheduledPost())
+			select {
+			case <-timer.C:
+			case <-schedulerWakeup:
+				timer.Stop()
+			}
+		}
+	}()
+}
+
+// wakeUpScheduler makes the scheduler recalculate when the next post is due. It never blocks.
+func wakeUpScheduler() {
+	select {
+	case schedulerWakeup <- true:
+	default:
+	}
+}
+
+func publishScheduledPosts() {
+	count, err := database.PublishScheduledPosts(date.GetCurrentTime())
+	if err != nil {
+		log.Println("Error: couldn't publish scheduled posts:", err)
+		return
+	}
+	if count == 0 {
+		return
+	}
+	log.Printf("Published %d scheduled post(s).", count)
+	// Generate new global blog (the sitemap and feeds are built from the database on request)
+	err = GenerateBlog()
+	if err != nil {
+		log.Println("Error: couldn't generate blog data:", err)
+	}
+}
+
+// purgeExpiredTrash deletes the posts that have been in the trash for longer than the configured retention period.
+func purgeExpiredTrash() {
+	if configuration.Config.TrashRetention < 0 {
+		return
+	}
+	count, err := PurgeTrash(date.GetCurrentTime().AddDate(0, 0, -configuration.Config.TrashRetention))
+	if err != nil {
+		log.Println("Error: couldn't purge the trash:", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Purged %d post(s) from the trash.", count)
+	}
+}
+
+func durationUntilNextScheduledPost() time.Duration {
+	nextDate, err := database.RetrieveNextScheduledPostDate()
+	if err != nil {
+		// No post is scheduled
+		return maxSchedulerSleep
+	}
+	duration := nextDate.Sub(date.GetCurrentTime())
+	if duration < minSchedulerSleep {
+		return minSchedulerSleep
+	}
+	if duration > maxSchedulerSleep {
+		return maxSchedulerSleep
+	}
+	return duration
+}
@@ -0,0 +1,55 @@
+package methods
+
+import (
+	"strings"
+
+	"journey/conversion"
+	"journey/database"
+	"journey/structure"
+)
+
+// SearchPosts returns the posts that match the query (best match first) and a highlighted snippet for each of them.
+// If publishedOnly is false, drafts and scheduled posts are included. An authorId of 0 searches the posts of all authors.
+func SearchPosts(query string, publishedOnly bool, authorId int64, limit int64, offset int64) ([]structure.Post, [][]byte, error) {
+	posts := make([]structure.Post, 0)
+	snippets := make([][]byte, 0)
+	match := searchMatchExpression(query)
+	if match == "" {
+		return posts, snippets, nil
+	}
+	postIds, rawSnippets, err := database.RetrieveSearchResults(match, publishedOnly, authorId, limit, offset)
+	if err != nil {
+		return posts, snippets, err
+	}
+	for index, postId := range postIds {
+		post, err := database.RetrievePostById(postId)
+		if err != nil {
+			return posts, snippets, err
+		}
+		posts = append(posts, *post)
+		snippets = append(snippets, conversion.HighlightSnippet(rawSnippets[index]))
+	}
+	return posts, snippets, nil
+}
+
+// CountSearchResults returns the number of posts SearchPosts finds for the query.
+func CountSearchResults(query string, publishedOnly bool, authorId int64) (int64, error) {
+	match := searchMatchExpression(query)
+	if match == "" {
+		return 0, nil
+	}
+	return database.RetrieveNumberOfSearchResults(match, publishedOnly, authorId)
+}
+
+// searchMatchExpression turns user input into a FTS5 query. Every word is quoted (so FTS5 operators in the input have no effect)
+// and matched as a prefix. All words have to be present in a post.
+func searchMatchExpression(query string) string {
+	terms := make([]string, 0)
+	for _, word := range strings.Fields(query) {
+		word = strings.Replace(word, "\"", "", -1)
+		if word != "" {
+			terms = append(terms, "\""+word+"\"*")
+		}
+	}
+	return strings.Join(terms, " ")
+}
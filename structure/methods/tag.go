@@ -1,11 +1,15 @@
 package methods
 
 import (
+	"journey/database"
+	"journey/date"
 	"journey/slug"
 	"journey/structure"
+	"log"
 	"strings"
 )
 
+// GenerateTagsFromCommaString parses the tag list of a post. A tag like "programming/go" is the tag "go" below the tag "programming".
 func GenerateTagsFromCommaString(input string) []structure.Tag {
 	output := make([]structure.Tag, 0)
 	tags := strings.Split(input, ",")
@@ -13,9 +17,166 @@ func GenerateTagsFromCommaString(input string) []structure.Tag {
 		tags[index] = strings.TrimSpace(tags[index])
 	}
 	for _, tag := range tags {
-		if tag != "" {
-			output = append(output, structure.Tag{Name: []byte(tag), Slug: slug.Generate(tag, "tags")})
+		var parent *structure.Tag
+		names := make([]string, 0)
+		for _, name := range strings.Split(tag, "/") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			names = append(names, name)
+			parent = &structure.Tag{Name: []byte(name), Slug: slug.Generate(strings.Join(names, "/"), "tags"), Parent: parent}
+		}
+		if parent != nil {
+			output = append(output, *parent)
 		}
 	}
 	return output
 }
+
+// IsInternalTag reports whether the tag is an internal tag. Internal tags start with '#' (e.g. "#newsletter") and are only used
+// to organize posts. They don't show up on the blog, but can select a custom post template.
+func IsInternalTag(t *structure.Tag) bool {
+	return strings.HasPrefix(string(t.Name), "#")
+}
+
+// PublicTags returns the tags without the internal tags.
+func PublicTags(tags []structure.Tag) []structure.Tag {
+	public := make([]structure.Tag, 0, len(tags))
+	for index, _ := range tags {
+		if !IsInternalTag(&tags[index]) {
+			public = append(public, tags[index])
+		}
+	}
+	return public
+}
+
+// TagPath returns the names of the parents of the tag and the name of the tag, separated by "/" (e.g. "programming/go").
+func TagPath(t *structure.Tag) string {
+	names := []string{string(t.Name)}
+	for parent := t.Parent; parent != nil; parent = parent.Parent {
+		names = append([]string{string(parent.Name)}, names...)
+	}
+	return strings.Join(names, "/")
+}
+
+// ParentTags returns the parents of the tag, starting with the top-level tag.
+func ParentTags(t *structure.Tag) []structure.Tag {
+	parents := make([]structure.Tag, 0)
+	for parent := t.Parent; parent != nil; parent = parent.Parent {
+		parents = append([]structure.Tag{*parent}, parents...)
+	}
+	return parents
+}
+
+// ChildTags returns the tags directly below the tag. Their parents are filled in, so they can be used for breadcrumbs too.
+func ChildTags(t *structure.Tag) ([]structure.Tag, error) {
+	children, err := database.RetrieveChildTags(t.Id)
+	if err != nil {
+		return nil, err
+	}
+	for index, _ := range children {
+		children[index].Parent = t
+	}
+	return children, nil
+}
+
+// saveTagWithParents returns the id of the tag with the name and parents of the given tag. The tag and its parents are created if they don't exist yet.
+func saveTagWithParents(t *structure.Tag, userId int64) (int64, error) {
+	var parentId int64
+	if t.Parent != nil {
+		var err error
+		parentId, err = saveTagWithParents(t.Parent, userId)
+		if err != nil {
+			return 0, err
+		}
+	}
+	// Tags keep their slug if they're renamed or moved below another tag, so look for the name first
+	tagId, err := database.RetrieveTagIdByNameAndParent(t.Name, parentId)
+	if err == nil {
+		return tagId, nil
+	}
+	// Tag slug might already be in database
+	tagId, err = database.RetrieveTagIdBySlug(t.Slug)
+	if err == nil {
+		return tagId, nil
+	}
+	// Tag is probably not in database yet
+	return database.InsertTag(t.Name, t.Slug, parentId, date.GetCurrentTime(), userId)
+}
+
+// SaveTag creates a tag that isn't used by any post yet.
+func SaveTag(t *structure.Tag, userId int64) error {
+	tagId, err := database.InsertTag(t.Name, t.Slug, t.ParentId, date.GetCurrentTime(), userId)
+	if err != nil {
+		return err
+	}
+	t.Id = tagId
+	return database.UpdateTag(t.Id, t.Name, t.Slug, t.Description, t.Image, t.MetaTitle, t.MetaDescription, t.ParentId, date.GetCurrentTime(), userId)
+}
+
+// UpdateTag saves the changes to a tag. If the slug changed, the old tag pages (including feeds and pagination) redirect to the new ones.
+func UpdateTag(t *structure.Tag, oldSlug string, userId int64) error {
+	err := database.UpdateTag(t.Id, t.Name, t.Slug, t.Description, t.Image, t.MetaTitle, t.MetaDescription, t.ParentId, date.GetCurrentTime(), userId)
+	if err != nil {
+		return err
+	}
+	if t.Slug != oldSlug {
+		err = SaveTagSlugRedirect(oldSlug, t.Slug, userId)
+		if err != nil {
+			return err
+		}
+	}
+	// Generate new global blog
+	err = GenerateBlog()
+	if err != nil {
+		log.Panic("Error: couldn't generate blog data:", err)
+	}
+	return nil
+}
+
+// MergeTag moves all posts of the tag into another tag and deletes it. The old tag pages redirect to the pages of the other tag.
+func MergeTag(t *structure.Tag, into *structure.Tag, userId int64) error {
+	err := database.DeleteTagByIdAndReassignPosts(t.Id, into.Id)
+	if err != nil {
+		return err
+	}
+	err = SaveTagSlugRedirect(t.Slug, into.Slug, userId)
+	if err != nil {
+		return err
+	}
+	// Generate new global blog
+	err = GenerateBlog()
+	if err != nil {
+		log.Panic("Error: couldn't generate blog data:", err)
+	}
+	return nil
+}
+
+// DeleteTag removes the tag from all posts and deletes it.
+func DeleteTag(tagId int64) error {
+	err := database.DeleteTagById(tagId)
+	if err != nil {
+		return err
+	}
+	// Generate new global blog
+	err = GenerateBlog()
+	if err != nil {
+		log.Panic("Error: couldn't generate blog data:", err)
+	}
+	return nil
+}
+
+// DeleteUnusedTags deletes the tags that were left behind without posts, e.g. after they were removed from the last post that used them.
+func DeleteUnusedTags() (int64, error) {
+	count, err := database.DeleteUnusedTags()
+	if err != nil {
+		return 0, err
+	}
+	// Generate new global blog
+	err = GenerateBlog()
+	if err != nil {
+		log.Panic("Error: couldn't generate blog data:", err)
+	}
+	return count, nil
+}
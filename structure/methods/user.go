@@ -1,11 +1,14 @@
 package methods
 
 import (
+	"errors"
 	"journey/database"
 	"journey/date"
 	"journey/structure"
 )
 
+var ErrInviteUsed = errors.New("This invitation has already been used.")
+
 func SaveUser(u *structure.User, hashedPassword string, createdBy int64) error {
 	userId, err := database.InsertUser(u.Name, u.Slug, hashedPassword, u.Email, u.Image, u.Cover, date.GetCurrentTime(), createdBy)
 	if err != nil {
@@ -15,6 +18,7 @@ func SaveUser(u *structure.User, hashedPassword string, createdBy int64) error {
 	if err != nil {
 		return err
 	}
+	u.Id = userId
 	return nil
 }
 
@@ -25,3 +29,77 @@ func UpdateUser(u *structure.User, updatedById int64) error {
 	}
 	return nil
 }
+
+func UpdateUserRole(userId int64, role int) error {
+	return database.UpdateRoleUser(role, userId)
+}
+
+func UpdateUserStatus(userId int64, status string, updatedById int64) error {
+	err := database.UpdateUserStatus(userId, status, date.GetCurrentTime(), updatedById)
+	if err != nil {
+		return err
+	}
+	// Suspended users are logged out everywhere
+	if status == structure.UserStatusInactive {
+		return database.DeleteSessionsByUserId(userId, 0)
+	}
+	return nil
+}
+
+// ChangePassword sets a new password and logs the user out everywhere.
+func ChangePassword(userId int64, hashedPassword string, updatedById int64) error {
+	err := database.UpdateUserPassword(userId, hashedPassword, date.GetCurrentTime(), updatedById)
+	if err != nil {
+		return err
+	}
+	return database.DeleteSessionsByUserId(userId, 0)
+}
+
+// ResetPassword sets the new password of a user who forgot the old one. Returns false if the reset token has been used already.
+func ResetPassword(r *structure.PasswordReset, hashedPassword string) (bool, error) {
+	unused, err := database.UpdatePasswordResetUsed(r.Id, date.GetCurrentTime())
+	if err != nil || !unused {
+		return false, err
+	}
+	return true, ChangePassword(r.UserId, hashedPassword, r.UserId)
+}
+
+// DeleteUser removes the user. Posts written by that user are handed over to the owner of the blog.
+func DeleteUser(userId int64) error {
+	ownerId, err := database.RetrieveOwnerId()
+	if err != nil {
+		return err
+	}
+	err = database.DeleteUserById(userId, ownerId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func SaveInvite(i *structure.Invite, tokenHash string) error {
+	inviteId, err := database.InsertInvite(tokenHash, i.Email, i.Role, *i.ExpiresAt, date.GetCurrentTime(), i.CreatedBy)
+	if err != nil {
+		return err
+	}
+	i.Id = inviteId
+	return nil
+}
+
+// AcceptInvite creates the invited user and marks the invite as used. Returns ErrInviteUsed if the invite has been accepted in the meantime.
+func AcceptInvite(i *structure.Invite, u *structure.User, hashedPassword string) error {
+	u.Role = i.Role
+	userId, accepted, err := database.InsertInvitedUser(i.Id, i.Role, u.Name, u.Slug, hashedPassword, u.Email, u.Image, u.Cover, date.GetCurrentTime(), i.CreatedBy)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return ErrInviteUsed
+	}
+	u.Id = userId
+	return nil
+}
+
+func DeleteInvite(inviteId int64) error {
+	return database.DeleteInviteById(inviteId)
+}
@@ -0,0 +1,14 @@
+package structure
+
+import (
+	"time"
+)
+
+// PasswordReset: a single-use token that lets a user who forgot their password choose a new one
+type PasswordReset struct {
+	Id        int64
+	UserId    int64
+	CreatedAt *time.Time
+	ExpiresAt *time.Time
+	UsedAt    *time.Time
+}
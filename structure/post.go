@@ -5,18 +5,32 @@ import (
 )
 
 type Post struct {
-	Id              int64
-	Uuid            []byte
-	Title           []byte
-	Slug            string
-	Markdown        []byte
-	Html            []byte
-	IsFeatured      bool
-	IsPage          bool
-	IsPublished     bool
-	Date            *time.Time
-	Tags            []Tag
-	Author          *User
-	MetaDescription []byte
-	Image           []byte
+	Id                 int64
+	Uuid               []byte
+	Title              []byte
+	Slug               string
+	Markdown           []byte
+	Html               []byte
+	IsFeatured         bool
+	IsPage             bool
+	IsPublished        bool
+	IsScheduled        bool // Date holds the time the post will be published at
+	Date               *time.Time
+	Tags               []Tag
+	Author             *User
+	MetaDescription    []byte
+	MetaTitle          []byte // Title for search engines if it differs from the post title
+	CustomExcerpt      []byte
+	CanonicalUrl       []byte // Absolute url or path of the original version of the post
+	OgTitle            []byte
+	OgDescription      []byte
+	OgImage            []byte
+	TwitterTitle       []byte
+	TwitterDescription []byte
+	TwitterImage       []byte
+	CodeInjectionHead  []byte // Added to ghost_head on the page of the post
+	CodeInjectionFoot  []byte // Added to ghost_foot on the page of the post
+	Image              []byte
+	DeletedAt          *time.Time // Set while the post is in the trash
+	UpdatedAt          *time.Time // Changes with every update, used to detect conflicting edits
 }
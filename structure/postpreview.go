@@ -0,0 +1,13 @@
+package structure
+
+import (
+	"time"
+)
+
+// PostPreview makes a post viewable at /p/<uuid>/ before it's published, e.g. for reviewers without an account.
+type PostPreview struct {
+	PostId    int64
+	ExpiresAt *time.Time // nil if the preview link doesn't expire
+	CreatedAt *time.Time
+	CreatedBy int64
+}
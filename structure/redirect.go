@@ -0,0 +1,22 @@
+package structure
+
+import (
+	"time"
+)
+
+// Ways a redirect can match the path of a request
+const (
+	RedirectMatchExact  = "exact"  // The path has to be the same as From
+	RedirectMatchPrefix = "prefix" // The path has to start with From. The rest of the path is appended to To.
+)
+
+// Redirect sends visitors from a path that no longer exists to a new url. Automatic redirects are recorded when the slug of a post changes.
+type Redirect struct {
+	Id        int64
+	From      string
+	To        string
+	Match     string
+	Automatic bool
+	CreatedAt *time.Time
+	CreatedBy int64
+}
@@ -16,9 +16,13 @@ type RequestData struct {
 	CurrentIndexPage       int
 	CurrentPostIndex       int
 	CurrentTagIndex        int
+	CurrentTagList         []Tag // Tags of the {{#foreach}} block the current tag belongs to
 	CurrentNavigationIndex int
 	CurrentHelperContext   int      // 0 = index, 1 = post, 2 = tag, 3 = author, 4 = navigation - used by block helpers
-	CurrentTemplate        int      // 0 = index, 1 = post, 2 = tag, 3 = author - never changes during execution. Used by funcs like body_classFunc etc to output the correct class
+	CurrentTemplate        int      // 0 = index, 1 = post, 2 = tag, 3 = author, 4 = search - never changes during execution. Used by funcs like body_classFunc etc to output the correct class
 	ContentForHelpers      []Helper // contentFor helpers that are attached to the currently rendering helper
 	CurrentPath            string   // path of the the url of this request
+	SearchQuery            string   // search terms as entered by the reader (search template only)
+	SearchResultCount      int64    // total number of posts found (search template only)
+	SearchSnippets         [][]byte // highlighted snippet for each post in Posts (search template only)
 }
@@ -0,0 +1,20 @@
+package structure
+
+import (
+	"time"
+)
+
+// Revision: a snapshot of a post that is taken every time the post is saved
+type Revision struct {
+	Id              int64
+	PostId          int64
+	Title           []byte
+	Markdown        []byte
+	Tags            []byte // Comma separated tag names
+	MetaDescription []byte
+	Image           []byte
+	IsFeatured      bool
+	IsPage          bool
+	Date            *time.Time
+	CreatedBy       int64
+}
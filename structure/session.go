@@ -0,0 +1,18 @@
+package structure
+
+import (
+	"time"
+)
+
+// Session: a login of a user. Only the hash of the session token is stored in the database.
+type Session struct {
+	Id         int64
+	UserId     int64
+	UserName   []byte
+	Remember   bool // The session cookie outlives the browser session
+	UserAgent  string
+	IpAddress  string
+	CreatedAt  *time.Time
+	LastSeenAt *time.Time
+	ExpiresAt  *time.Time
+}
@@ -0,0 +1,12 @@
+package structure
+
+import (
+	"time"
+)
+
+// SitemapEntry: a post, page, tag or author page as it's listed in the sitemap
+type SitemapEntry struct {
+	Slug      string
+	Image     []byte     // Cover image of posts and pages
+	UpdatedAt *time.Time // Last update of the post or, for tags and authors, of their newest updated post
+}
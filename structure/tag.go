@@ -1,7 +1,13 @@
 package structure
 
 type Tag struct {
-	Id   int64
-	Name []byte
-	Slug string
+	Id              int64
+	Name            []byte
+	Slug            string
+	Description     []byte
+	Image           []byte // Cover image of the tag page
+	MetaTitle       []byte // Title for search engines if it differs from the tag name
+	MetaDescription []byte
+	ParentId        int64 // 0 for top-level tags
+	Parent          *Tag  // Filled in when a single tag is retrieved, nil for top-level tags
 }
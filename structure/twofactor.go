@@ -0,0 +1,24 @@
+package structure
+
+import (
+	"time"
+)
+
+// TwoFactor: the TOTP secret of a user. The secret is only used for logins after it has been confirmed with a valid code (Enabled).
+type TwoFactor struct {
+	UserId    int64
+	Secret    string
+	Enabled   bool
+	LastStep  int64 // Time step of the last accepted code, so that no code can be used twice
+	CreatedAt *time.Time
+}
+
+// TwoFactorChallenge: a login that passed the password check and waits for the second factor.
+// If Secret is set, the user has to set up two-factor authentication with this secret before logging in.
+type TwoFactorChallenge struct {
+	Id        int64
+	UserId    int64
+	Remember  bool
+	Secret    string
+	ExpiresAt *time.Time
+}
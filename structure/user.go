@@ -1,14 +1,34 @@
 package structure
 
+import (
+	"time"
+)
+
+// Role ids as inserted into the roles table
+const (
+	RoleAdministrator = 1
+	RoleEditor        = 2
+	RoleAuthor        = 3
+	RoleOwner         = 4
+)
+
+// User status values. Inactive users are suspended and can't log in.
+const (
+	UserStatusActive   = "active"
+	UserStatusInactive = "inactive"
+)
+
 type User struct {
-	Id       int64
-	Name     []byte
-	Slug     string
-	Email    []byte
-	Image    []byte
-	Cover    []byte
-	Bio      []byte
-	Website  []byte
-	Location []byte
-	Role     int //1 = Administrator, 2 = Editor, 3 = Author, 4 = Owner
+	Id        int64
+	Name      []byte
+	Slug      string
+	Email     []byte
+	Image     []byte
+	Cover     []byte
+	Bio       []byte
+	Website   []byte
+	Location  []byte
+	Status    string
+	LastLogin *time.Time
+	Role      int //1 = Administrator, 2 = Editor, 3 = Author, 4 = Owner
 }
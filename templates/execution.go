@@ -3,6 +3,7 @@ package templates
 import (
 	"bytes"
 	"errors"
+	"journey/configuration"
 	"journey/database"
 	"journey/filenames"
 	"journey/helpers"
@@ -35,7 +36,7 @@ func ShowPostTemplate(writer http.ResponseWriter, r *http.Request, slug string)
 		return err
 	}
 
-	if !post.IsPublished {
+	if !post.IsPublished || post.DeletedAt != nil {
 		return errors.New("Post not published.")
 	}
 
@@ -43,22 +44,28 @@ func ShowPostTemplate(writer http.ResponseWriter, r *http.Request, slug string)
 		http.Redirect(writer, r, "/"+post.Slug+"/", 301)
 		return nil
 	}
+	return showPost(writer, r, post)
+}
+
+// ShowPreviewTemplate renders the post like ShowPostTemplate, even if it isn't published yet. Posts in the trash can't be previewed.
+func ShowPreviewTemplate(writer http.ResponseWriter, r *http.Request, post *structure.Post) error {
+	// Read lock templates and global blog
+	compiledTemplates.RLock()
+	defer compiledTemplates.RUnlock()
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	if post.DeletedAt != nil {
+		return errors.New("Post not found.")
+	}
+	return showPost(writer, r, post)
+}
 
+// showPost executes the post template (or the page template) for the post. The caller has to hold the read locks of the templates and the blog.
+func showPost(writer http.ResponseWriter, r *http.Request, post *structure.Post) error {
+	var err error
 	requestData := structure.RequestData{Posts: make([]structure.Post, 1), Blog: methods.Blog, CurrentTemplate: 1, CurrentPath: r.URL.Path} // CurrentTemplate = post
 	requestData.Posts[0] = *post
-	// Check if there's a custom page template available for this slug
-	if template, ok := compiledTemplates.m["page-"+slug]; ok {
-		_, err = writer.Write(executeHelper(template, &requestData, 1)) // context = post
-		return err
-	}
-	// If the post is a page and the page template is available, use the page template
-	if post.IsPage {
-		if template, ok := compiledTemplates.m["page"]; ok {
-			_, err = writer.Write(executeHelper(template, &requestData, 1)) // context = post
-			return err
-		}
-	}
-	_, err = writer.Write(executeHelper(compiledTemplates.m["post"], &requestData, 1)) // context = post
+	_, err = writer.Write(executeHelper(postTemplate(post), &requestData, 1)) // context = post
 	if requestData.PluginVMs != nil {
 		// Put the lua state map back into the pool
 		plugins.LuaPool.Put(requestData.PluginVMs)
@@ -111,7 +118,11 @@ func ShowTagTemplate(writer http.ResponseWriter, r *http.Request, slug string, p
 	if err != nil {
 		return err
 	}
-	posts, err := database.RetrievePostsByTag(tag.Id, methods.Blog.PostsPerPage, (methods.Blog.PostsPerPage * postIndex))
+	// Internal tags don't have a tag page
+	if methods.IsInternalTag(tag) {
+		return errors.New("Tag not found.")
+	}
+	posts, err := retrievePostsByTag(tag.Id, methods.Blog.PostsPerPage, (methods.Blog.PostsPerPage * postIndex))
 	if err != nil {
 		return err
 	}
@@ -128,6 +139,74 @@ func ShowTagTemplate(writer http.ResponseWriter, r *http.Request, slug string, p
 	return err
 }
 
+// postTemplate chooses the template of the post: a custom page template for the slug (e.g. page-about.hbs), a custom template
+// for one of its internal tags (e.g. post-hash-wide.hbs for the tag #wide), the page template for pages or the post template.
+func postTemplate(post *structure.Post) *structure.Helper {
+	if template, ok := compiledTemplates.m["page-"+post.Slug]; ok {
+		return template
+	}
+	for index, _ := range post.Tags {
+		if methods.IsInternalTag(&post.Tags[index]) {
+			if template, ok := compiledTemplates.m["post-"+post.Tags[index].Slug]; ok {
+				return template
+			}
+		}
+	}
+	if post.IsPage {
+		if template, ok := compiledTemplates.m["page"]; ok {
+			return template
+		}
+	}
+	return compiledTemplates.m["post"]
+}
+
+// retrievePostsByTag returns the posts of the tag page. Depending on the configuration, the posts of child tags are included.
+func retrievePostsByTag(tagId int64, limit int64, offset int64) ([]structure.Post, error) {
+	if configuration.Config.IncludeChildTags {
+		return database.RetrievePostsByTagTree(tagId, limit, offset)
+	}
+	return database.RetrievePostsByTag(tagId, limit, offset)
+}
+
+// retrieveNumberOfPostsByTag counts the posts of the tag page, see retrievePostsByTag.
+func retrieveNumberOfPostsByTag(tagId int64) (int64, error) {
+	if configuration.Config.IncludeChildTags {
+		return database.RetrieveNumberOfPostsByTagTree(tagId)
+	}
+	return database.RetrieveNumberOfPostsByTag(tagId)
+}
+
+func ShowSearchTemplate(w http.ResponseWriter, r *http.Request, query string, page int) error {
+	// Read lock templates and global blog
+	compiledTemplates.RLock()
+	defer compiledTemplates.RUnlock()
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	postIndex := int64(page - 1)
+	if postIndex < 0 {
+		postIndex = 0
+	}
+	posts, snippets, err := methods.SearchPosts(query, true, 0, methods.Blog.PostsPerPage, (methods.Blog.PostsPerPage * postIndex))
+	if err != nil {
+		return err
+	}
+	count, err := methods.CountSearchResults(query, true, 0)
+	if err != nil {
+		return err
+	}
+	requestData := structure.RequestData{Posts: posts, Blog: methods.Blog, CurrentIndexPage: page, CurrentTemplate: 4, CurrentPath: r.URL.Path, SearchQuery: query, SearchResultCount: count, SearchSnippets: snippets} // CurrentTemplate = search
+	if template, ok := compiledTemplates.m["search"]; ok {
+		_, err = w.Write(executeHelper(template, &requestData, 0)) // context = index
+	} else {
+		_, err = w.Write(executeHelper(compiledTemplates.m["index"], &requestData, 0)) // context = index
+	}
+	if requestData.PluginVMs != nil {
+		// Put the lua state map back into the pool
+		plugins.LuaPool.Put(requestData.PluginVMs)
+	}
+	return err
+}
+
 func ShowIndexTemplate(w http.ResponseWriter, r *http.Request, page int) error {
 	// Read lock templates and global blog
 	compiledTemplates.RLock()
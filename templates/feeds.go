@@ -0,0 +1,382 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"journey/configuration"
+	"journey/database"
+	"journey/structure"
+	"journey/structure/methods"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kabukky/feeds"
+)
+
+// Formats the index, tag and author feeds are available in
+const (
+	FeedRss  = "rss"
+	FeedAtom = "atom"
+	FeedJson = "json"
+)
+
+// Number of words in the summary of a post if the feeds only contain excerpts
+const feedExcerptWords = 55
+
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// feedScope: the posts a feed is made of and the path of the page on the blog that shows the same posts
+type feedScope struct {
+	title string // Prefixed to the blog title, e.g. the tag name
+	path  string
+	posts func(limit int64, offset int64) ([]structure.Post, error)
+	count func() (int64, error)
+}
+
+func ShowIndexFeed(w http.ResponseWriter, r *http.Request, format string, page int) error {
+	scope := &feedScope{path: "/", posts: database.RetrievePostsForIndex, count: database.RetrieveNumberOfPosts}
+	return showFeed(w, r, format, page, scope)
+}
+
+func ShowTagFeed(w http.ResponseWriter, r *http.Request, slug string, format string, page int) error {
+	tag, err := database.RetrieveTagBySlug(slug)
+	if err != nil {
+		return err
+	}
+	if methods.IsInternalTag(tag) {
+		return errors.New("Tag not found.")
+	}
+	scope := &feedScope{
+		title: string(tag.Name),
+		path:  "/tag/" + tag.Slug + "/",
+		posts: func(limit int64, offset int64) ([]structure.Post, error) {
+			return retrievePostsByTag(tag.Id, limit, offset)
+		},
+		count: func() (int64, error) {
+			return retrieveNumberOfPostsByTag(tag.Id)
+		},
+	}
+	return showFeed(w, r, format, page, scope)
+}
+
+func ShowAuthorFeed(w http.ResponseWriter, r *http.Request, slug string, format string, page int) error {
+	author, err := database.RetrieveUserBySlug(slug)
+	if err != nil {
+		return err
+	}
+	scope := &feedScope{
+		title: string(author.Name),
+		path:  "/author/" + author.Slug + "/",
+		posts: func(limit int64, offset int64) ([]structure.Post, error) {
+			return database.RetrievePostsByUser(author.Id, limit, offset)
+		},
+		count: func() (int64, error) {
+			return database.RetrieveNumberOfPostsByUser(author.Id)
+		},
+	}
+	return showFeed(w, r, format, page, scope)
+}
+
+func showFeed(w http.ResponseWriter, r *http.Request, format string, page int, scope *feedScope) error {
+	// Read lock global blog
+	methods.Blog.RLock()
+	defer methods.Blog.RUnlock()
+	items := int64(configuration.Config.FeedItems)
+	count, err := scope.count()
+	if err != nil {
+		return err
+	}
+	lastPage := int((count + items - 1) / items)
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	if page > lastPage {
+		http.NotFound(w, r)
+		return nil
+	}
+	posts, err := scope.posts(items, items*int64(page-1))
+	if err != nil {
+		return err
+	}
+	feed := &pagedFeed{Blog: methods.Blog, Scope: scope, Format: format, Page: page, LastPage: lastPage, Posts: posts}
+	for index := range posts {
+		if posts[index].UpdatedAt != nil && posts[index].UpdatedAt.After(feed.Updated) {
+			feed.Updated = *posts[index].UpdatedAt
+		}
+	}
+	var buffer bytes.Buffer
+	var contentType string
+	switch format {
+	case FeedAtom:
+		contentType = "application/atom+xml; charset=utf-8"
+		err = writeAtomFeed(&buffer, feed)
+	case FeedJson:
+		contentType = "application/feed+json; charset=utf-8"
+		err = writeJsonFeed(&buffer, feed)
+	default:
+		contentType = "application/rss+xml; charset=utf-8"
+		err = writeRssFeed(&buffer, feed)
+	}
+	if err != nil {
+		return err
+	}
+	// Let feed readers make conditional requests. The newest post on the page isn't a reliable modification time, because it changes
+	// to an older one when that post is trashed or unpublished.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha1.Sum(buffer.Bytes())))
+	http.ServeContent(w, r, "", methods.Blog.GeneratedAt, bytes.NewReader(buffer.Bytes()))
+	return nil
+}
+
+// pagedFeed: one page of a feed
+type pagedFeed struct {
+	Blog     *structure.Blog
+	Scope    *feedScope
+	Format   string
+	Page     int
+	LastPage int
+	Posts    []structure.Post
+	Updated  time.Time // Newest update of the posts on this page
+}
+
+func (f *pagedFeed) title() string {
+	if f.Scope.title != "" {
+		return f.Scope.title + " - " + string(f.Blog.Title)
+	}
+	return string(f.Blog.Title)
+}
+
+func (f *pagedFeed) homeUrl() string {
+	return string(f.Blog.Url) + f.Scope.path
+}
+
+// pageUrl returns the url of the given page of the feed. The first page has no page parameter.
+func (f *pagedFeed) pageUrl(page int) string {
+	url := f.homeUrl()
+	switch f.Format {
+	case FeedAtom:
+		url += "atom/"
+	case FeedJson:
+		url += "feed.json"
+	default:
+		url += "rss/"
+	}
+	if page > 1 {
+		url += "?page=" + strconv.Itoa(page)
+	}
+	return url
+}
+
+type feedLink struct {
+	rel  string
+	href string
+}
+
+// pageLinks returns the links to the first, previous, next and last page of the feed (RFC 5005).
+func (f *pagedFeed) pageLinks() []feedLink {
+	links := []feedLink{{"first", f.pageUrl(1)}}
+	if f.Page > 1 {
+		links = append(links, feedLink{"previous", f.pageUrl(f.Page - 1)})
+	}
+	if f.Page < f.LastPage {
+		links = append(links, feedLink{"next", f.pageUrl(f.Page + 1)})
+	}
+	return append(links, feedLink{"last", f.pageUrl(f.LastPage)})
+}
+
+// content returns the html of the post or only a plain text excerpt, depending on the configuration.
+func (f *pagedFeed) content(post *structure.Post) string {
+	if configuration.Config.FeedContent == "excerpt" {
+		return plainExcerpt(post, feedExcerptWords)
+	}
+	return string(post.Html)
+}
+
+func (f *pagedFeed) createFeed() *feeds.Feed {
+	feed := &feeds.Feed{
+		Title:       f.title(),
+		Description: string(f.Blog.Description),
+		Link:        &feeds.Link{Href: f.homeUrl()},
+		Updated:     f.Updated,
+		Url:         f.pageUrl(f.Page),
+	}
+	if len(f.Blog.Logo) != 0 {
+		feed.Image = &feeds.Image{
+			Url:   absoluteUrl(f.Blog, string(f.Blog.Logo)),
+			Title: f.title(),
+			Link:  f.homeUrl(),
+		}
+	}
+	for index := range f.Posts {
+		post := &f.Posts[index]
+		item := &feeds.Item{
+			Title:       string(post.Title),
+			Description: f.content(post),
+			Link:        &feeds.Link{Href: postUrl(f.Blog, post)},
+			Id:          string(post.Uuid),
+			Author:      &feeds.Author{Name: string(post.Author.Name), Email: ""},
+		}
+		if post.Date != nil {
+			item.Created = *post.Date
+		}
+		if post.UpdatedAt != nil {
+			item.Updated = *post.UpdatedAt
+		}
+		// If the post has a cover image, add it to the item
+		if len(post.Image) != 0 {
+			item.Image = &feeds.Image{
+				Url: absoluteUrl(f.Blog, string(post.Image)),
+			}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+// pagedRssFeed: the rss document of the feeds package with additional atom links to the other pages
+type pagedRssFeed struct {
+	XMLName    xml.Name `xml:"rss"`
+	Version    string   `xml:"version,attr"`
+	XmlnsMedia string   `xml:"xmlns:media,attr"`
+	XmlnsDc    string   `xml:"xmlns:dc,attr"`
+	XmlnsAtom  string   `xml:"xmlns:atom,attr"`
+	Channel    *pagedRssChannel
+}
+
+type pagedRssChannel struct {
+	*feeds.RssFeed
+	PageLinks []*feeds.RssAtomLink
+}
+
+func writeRssFeed(buffer *bytes.Buffer, f *pagedFeed) error {
+	channel := &pagedRssChannel{RssFeed: (&feeds.Rss{Feed: f.createFeed()}).RssFeed()}
+	for _, link := range f.pageLinks() {
+		channel.PageLinks = append(channel.PageLinks, &feeds.RssAtomLink{Href: link.href, Rel: link.rel, Type: "application/rss+xml"})
+	}
+	rss := &pagedRssFeed{Version: "2.0", XmlnsMedia: "http://search.yahoo.com/mrss/", XmlnsDc: "http://purl.org/dc/elements/1.1/", XmlnsAtom: "http://www.w3.org/2005/Atom", Channel: channel}
+	return writeXml(buffer, rss)
+}
+
+// pagedAtomFeed: the atom document of the feeds package with additional links to itself and the other pages
+type pagedAtomFeed struct {
+	*feeds.AtomFeed
+	Links []*feeds.AtomLink
+}
+
+func writeAtomFeed(buffer *bytes.Buffer, f *pagedFeed) error {
+	atom := &pagedAtomFeed{AtomFeed: (&feeds.Atom{Feed: f.createFeed()}).AtomFeed()}
+	// The id stays the same on all pages
+	atom.Id = f.pageUrl(1)
+	// Every entry names its own author
+	atom.Author = nil
+	atom.Links = append(atom.Links, &feeds.AtomLink{Href: f.pageUrl(f.Page), Rel: "self"})
+	for _, link := range f.pageLinks() {
+		atom.Links = append(atom.Links, &feeds.AtomLink{Href: link.href, Rel: link.rel})
+	}
+	for index, entry := range atom.Entries {
+		post := &f.Posts[index]
+		entry.Id = "urn:uuid:" + string(post.Uuid)
+		if post.Date != nil {
+			entry.Published = post.Date.Format(time.RFC3339)
+		}
+		if configuration.Config.FeedContent == "excerpt" {
+			entry.Summary = &feeds.AtomSummary{Content: entry.Content.Content, Type: "text"}
+			entry.Content = nil
+		}
+	}
+	return writeXml(buffer, atom)
+}
+
+func writeXml(buffer *bytes.Buffer, document interface{}) error {
+	buffer.WriteString(xml.Header)
+	encoder := xml.NewEncoder(buffer)
+	encoder.Indent("", "  ")
+	return encoder.Encode(document)
+}
+
+// jsonFeed: a feed in the JSON Feed 1.1 format (https://jsonfeed.org/version/1.1)
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageUrl string         `json:"home_page_url"`
+	FeedUrl     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	NextUrl     string         `json:"next_url,omitempty"`
+	Icon        string         `json:"icon,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	Id            string           `json:"id"`
+	Url           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHtml   string           `json:"content_html,omitempty"`
+	ContentText   string           `json:"content_text,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name   string `json:"name"`
+	Url    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+func writeJsonFeed(buffer *bytes.Buffer, f *pagedFeed) error {
+	feed := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.title(),
+		HomePageUrl: f.homeUrl(),
+		FeedUrl:     f.pageUrl(f.Page),
+		Description: string(f.Blog.Description),
+		Items:       make([]jsonFeedItem, 0, len(f.Posts)),
+	}
+	if f.Page < f.LastPage {
+		feed.NextUrl = f.pageUrl(f.Page + 1)
+	}
+	if len(f.Blog.Logo) != 0 {
+		feed.Icon = absoluteUrl(f.Blog, string(f.Blog.Logo))
+	}
+	for index := range f.Posts {
+		post := &f.Posts[index]
+		item := jsonFeedItem{
+			Id:            string(post.Uuid),
+			Url:           postUrl(f.Blog, post),
+			Title:         string(post.Title),
+			Summary:       plainExcerpt(post, feedExcerptWords),
+			DatePublished: formatHeadDate(post.Date),
+			DateModified:  formatHeadDate(post.UpdatedAt),
+		}
+		if configuration.Config.FeedContent == "excerpt" {
+			item.ContentText = item.Summary
+		} else {
+			item.ContentHtml = string(post.Html)
+		}
+		if len(post.Image) != 0 {
+			item.Image = absoluteUrl(f.Blog, string(post.Image))
+		}
+		if post.Author != nil {
+			author := jsonFeedAuthor{Name: string(post.Author.Name), Url: string(f.Blog.Url) + "/author/" + post.Author.Slug + "/"}
+			if len(post.Author.Image) != 0 {
+				author.Avatar = absoluteUrl(f.Blog, string(post.Author.Image))
+			}
+			item.Authors = []jsonFeedAuthor{author}
+		}
+		for _, tag := range methods.PublicTags(post.Tags) {
+			item.Tags = append(item.Tags, string(tag.Name))
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(feed)
+}
@@ -118,12 +118,14 @@ func paginationDotTotalFunc(helper *structure.Helper, values *structure.RequestD
 		}
 		return []byte(strconv.FormatInt(count, 10))
 	} else if values.CurrentTemplate == 2 { // tag
-		count, err := database.RetrieveNumberOfPostsByTag(values.CurrentTag.Id)
+		count, err := retrieveNumberOfPostsByTag(values.CurrentTag.Id)
 		if err != nil {
 			log.Println("Couldn't get number of posts", err.Error())
 			return []byte{}
 		}
 		return []byte(strconv.FormatInt(count, 10))
+	} else if values.CurrentTemplate == 4 { // search
+		return []byte(strconv.FormatInt(values.SearchResultCount, 10))
 	}
 	return []byte{}
 }
@@ -169,7 +171,7 @@ func nextFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if values.CurrentTemplate == 0 { // index
 		count = values.Blog.PostCount
 	} else if values.CurrentTemplate == 2 { // tag
-		count, err = database.RetrieveNumberOfPostsByTag(values.CurrentTag.Id)
+		count, err = retrieveNumberOfPostsByTag(values.CurrentTag.Id)
 		if err != nil {
 			log.Println("Couldn't get number of posts for tag", err.Error())
 			return []byte{}
@@ -180,6 +182,8 @@ func nextFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 			log.Println("Couldn't get number of posts for author", err.Error())
 			return []byte{}
 		}
+	} else if values.CurrentTemplate == 4 { // search
+		count = values.SearchResultCount
 	}
 	maxPages := positiveCeilingInt64(float64(count) / float64(values.Blog.PostsPerPage))
 	if int64(values.CurrentIndexPage) < maxPages {
@@ -198,7 +202,7 @@ func pagesFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if values.CurrentTemplate == 0 { // index
 		count = values.Blog.PostCount
 	} else if values.CurrentTemplate == 2 { // tag
-		count, err = database.RetrieveNumberOfPostsByTag(values.CurrentTag.Id)
+		count, err = retrieveNumberOfPostsByTag(values.CurrentTag.Id)
 		if err != nil {
 			log.Println("Couldn't get number of posts for tag", err.Error())
 			return []byte{}
@@ -209,6 +213,8 @@ func pagesFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 			log.Println("Couldn't get number of posts for author", err.Error())
 			return []byte{}
 		}
+	} else if values.CurrentTemplate == 4 { // search
+		count = values.SearchResultCount
 	}
 	maxPages := positiveCeilingInt64(float64(count) / float64(values.Blog.PostsPerPage))
 	// Output at least 1 (even if there are no posts in the database)
@@ -222,6 +228,9 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 	if len(helper.Arguments) != 0 {
 		if helper.Arguments[0].Name == "prev" || helper.Arguments[0].Name == "pagination.prev" {
 			if values.CurrentIndexPage > 1 {
+				if values.CurrentTemplate == 4 { // search
+					return searchPageUrl(values.SearchQuery, values.CurrentIndexPage-1)
+				}
 				var buffer bytes.Buffer
 				if values.CurrentIndexPage == 2 {
 					if values.CurrentTemplate == 3 { // author
@@ -259,7 +268,7 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 			if values.CurrentTemplate == 0 { // index
 				count = values.Blog.PostCount
 			} else if values.CurrentTemplate == 2 { // tag
-				count, err = database.RetrieveNumberOfPostsByTag(values.CurrentTag.Id)
+				count, err = retrieveNumberOfPostsByTag(values.CurrentTag.Id)
 				if err != nil {
 					log.Println("Couldn't get number of posts for tag", err.Error())
 					return []byte{}
@@ -270,9 +279,14 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 					log.Println("Couldn't get number of posts for author", err.Error())
 					return []byte{}
 				}
+			} else if values.CurrentTemplate == 4 { // search
+				count = values.SearchResultCount
 			}
 			maxPages := positiveCeilingInt64(float64(count) / float64(values.Blog.PostsPerPage))
 			if int64(values.CurrentIndexPage) < maxPages {
+				if values.CurrentTemplate == 4 { // search
+					return searchPageUrl(values.SearchQuery, values.CurrentIndexPage+1)
+				}
 				var buffer bytes.Buffer
 				if values.CurrentTemplate == 3 { // author
 					buffer.WriteString("/author/")
@@ -296,6 +310,18 @@ func page_urlFunc(helper *structure.Helper, values *structure.RequestData) []byt
 	return []byte{}
 }
 
+// searchPageUrl returns the url of a page of search results
+func searchPageUrl(query string, page int) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("/search/?q=")
+	buffer.WriteString(url.QueryEscape(query))
+	if page > 1 {
+		buffer.WriteString("&page=")
+		buffer.WriteString(strconv.Itoa(page))
+	}
+	return buffer.Bytes()
+}
+
 func extendFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if len(helper.Arguments) != 0 {
 		return []byte(helper.Arguments[0].Name)
@@ -347,6 +373,11 @@ func body_classFunc(helper *structure.Helper, values *structure.RequestData) []b
 			buffer.WriteString(" paged archive-template")
 		}
 		return buffer.Bytes()
+	} else if values.CurrentTemplate == 4 { // search
+		if values.CurrentIndexPage > 1 {
+			return []byte("search-template paged")
+		}
+		return []byte("search-template")
 	}
 	// TODO: Delete this. Probably not needed.
 	return []byte("post-template")
@@ -354,23 +385,48 @@ func body_classFunc(helper *structure.Helper, values *structure.RequestData) []b
 
 func ghost_headFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	// SEO stuff:
-	// Output canonical url
+	// Output canonical url. A post can point to another url, e.g. if it was first published elsewhere.
 	var buffer bytes.Buffer
 	buffer.WriteString("<link rel=\"canonical\" href=\"")
-	buffer.Write(evaluateEscape(values.Blog.Url, helper.Unescaped))
-	buffer.WriteString(values.CurrentPath)
+	if values.CurrentTemplate == 1 && len(values.Posts[values.CurrentPostIndex].CanonicalUrl) != 0 { // post or page
+		canonicalUrl := values.Posts[values.CurrentPostIndex].CanonicalUrl
+		if canonicalUrl[0] == '/' {
+			buffer.Write(evaluateEscape(values.Blog.Url, helper.Unescaped))
+		}
+		buffer.Write(evaluateEscape(canonicalUrl, helper.Unescaped))
+	} else {
+		buffer.Write(evaluateEscape(values.Blog.Url, helper.Unescaped))
+		buffer.WriteString(values.CurrentPath)
+	}
 	buffer.WriteString("\">")
-	// TODO: structured data
+	// Social networks, structured data, feeds and pagination
+	writeSocialMeta(&buffer, values)
+	writeStructuredData(&buffer, values)
+	writeFeedLinks(&buffer, values)
+	writePaginationLinks(&buffer, values)
+	// Code injection of the blog and the post. It's written as is.
+	buffer.Write(values.Blog.CodeInjectionHead)
+	if values.CurrentTemplate == 1 { // post or page
+		buffer.Write(values.Posts[values.CurrentPostIndex].CodeInjectionHead)
+	}
 	return buffer.Bytes()
 }
 
 func ghost_footFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	// TODO: customized code injection
-	return []byte{}
+	// Code injection of the blog and the post. It's written as is.
+	var buffer bytes.Buffer
+	buffer.Write(values.Blog.CodeInjectionFoot)
+	if values.CurrentTemplate == 1 { // post or page
+		buffer.Write(values.Posts[values.CurrentPostIndex].CodeInjectionFoot)
+	}
+	return buffer.Bytes()
 }
 
 func meta_titleFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if values.CurrentTemplate == 1 { // post or page
+		if len(values.Posts[values.CurrentPostIndex].MetaTitle) != 0 {
+			return evaluateEscape(values.Posts[values.CurrentPostIndex].MetaTitle, helper.Unescaped)
+		}
 		return evaluateEscape(values.Posts[values.CurrentPostIndex].Title, helper.Unescaped)
 	} else if values.CurrentTemplate == 3 { // author
 		var buffer bytes.Buffer
@@ -380,12 +436,22 @@ func meta_titleFunc(helper *structure.Helper, values *structure.RequestData) []b
 		buffer.Write(values.Blog.Title)
 		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
 	} else if values.CurrentTemplate == 2 { // tag
+		if len(values.CurrentTag.MetaTitle) != 0 {
+			return evaluateEscape(values.CurrentTag.MetaTitle, helper.Unescaped)
+		}
 		var buffer bytes.Buffer
 		// TODO: Error handling if there is no Posts[values.CurrentPostIndex]
 		buffer.Write(values.CurrentTag.Name)
 		buffer.WriteString(" - ")
 		buffer.Write(values.Blog.Title)
 		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
+	} else if values.CurrentTemplate == 4 { // search
+		var buffer bytes.Buffer
+		buffer.WriteString("Search: ")
+		buffer.WriteString(values.SearchQuery)
+		buffer.WriteString(" - ")
+		buffer.Write(values.Blog.Title)
+		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
 	}
 	// index
 	return evaluateEscape(values.Blog.Title, helper.Unescaped)
@@ -394,7 +460,13 @@ func meta_titleFunc(helper *structure.Helper, values *structure.RequestData) []b
 func meta_descriptionFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	// TODO: Finish this
 	if values.CurrentTemplate == 1 || values.CurrentHelperContext == 1 { // post
+		// Fall back to the custom excerpt, which is written by hand as well
+		if len(values.Posts[values.CurrentPostIndex].MetaDescription) == 0 {
+			return evaluateEscape(values.Posts[values.CurrentPostIndex].CustomExcerpt, helper.Unescaped)
+		}
 		return evaluateEscape(values.Posts[values.CurrentPostIndex].MetaDescription, helper.Unescaped)
+	} else if values.CurrentTemplate == 2 && (len(values.CurrentTag.MetaDescription) != 0 || len(values.CurrentTag.Description) != 0) { // tag
+		return evaluateEscape([]byte(firstNonEmpty(values.CurrentTag.MetaDescription, values.CurrentTag.Description)), helper.Unescaped)
 	} else {
 		return evaluateEscape(values.Blog.Description, helper.Unescaped)
 	}
@@ -504,8 +576,25 @@ func postsFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 }
 
 func tagsFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	if len(values.Posts[values.CurrentPostIndex].Tags) > 0 {
-		separator := ", "
+	return tagListHelper(helper, methods.PublicTags(values.Posts[values.CurrentPostIndex].Tags), ", ")
+}
+
+func tagDotChildrenFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	children, err := methods.ChildTags(currentTag(values))
+	if err != nil {
+		log.Println("Couldn't get child tags", err.Error())
+		return []byte{}
+	}
+	return tagListHelper(helper, methods.PublicTags(children), ", ")
+}
+
+func tagDotBreadcrumbsFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	return tagListHelper(helper, methods.PublicTags(methods.ParentTags(currentTag(values))), " / ")
+}
+
+// tagListHelper writes the tags as a list of links. The separator, prefix, suffix and autolink arguments work like the ones of {{tags}}.
+func tagListHelper(helper *structure.Helper, tags []structure.Tag, separator string) []byte {
+	if len(tags) > 0 {
 		suffix := ""
 		prefix := ""
 		makeLink := true
@@ -530,7 +619,7 @@ func tagsFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 			buffer.WriteString(prefix)
 			buffer.WriteString(" ")
 		}
-		for index, tag := range values.Posts[values.CurrentPostIndex].Tags {
+		for index, tag := range tags {
 			if index != 0 {
 				buffer.WriteString(separator)
 			}
@@ -592,6 +681,11 @@ func urlFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 		buffer.WriteString(values.Posts[values.CurrentPostIndex].Slug)
 		buffer.WriteString("/")
 		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
+	} else if values.CurrentHelperContext == 2 { // tag
+		buffer.WriteString("/tag/")
+		buffer.WriteString(currentTag(values).Slug)
+		buffer.WriteString("/")
+		return evaluateEscape(buffer.Bytes(), helper.Unescaped)
 	} else if values.CurrentHelperContext == 3 { // author
 		buffer.WriteString("/author/")
 		// TODO: Error handling if there is no Posts[values.CurrentPostIndex]
@@ -614,41 +708,62 @@ func contentFunc(helper *structure.Helper, values *structure.RequestData) []byte
 	return values.Posts[values.CurrentPostIndex].Html
 }
 
+func snippetFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	// Snippets are escaped already and contain <mark> tags around the matches
+	if values.CurrentPostIndex < len(values.SearchSnippets) {
+		return values.SearchSnippets[values.CurrentPostIndex]
+	}
+	return []byte{}
+}
+
+func searchDotQueryFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	return evaluateEscape([]byte(values.SearchQuery), helper.Unescaped)
+}
+
 func excerptFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 	if values.CurrentHelperContext == 1 { // post
+		// Prefer the custom excerpt of the post. It's plain text and needs to be escaped after it has been shortened.
+		excerpt := conversion.StripTagsFromHtml(values.Posts[values.CurrentPostIndex].Html)
+		escape := func(value []byte) []byte {
+			return value
+		}
+		if len(values.Posts[values.CurrentPostIndex].CustomExcerpt) != 0 {
+			excerpt = values.Posts[values.CurrentPostIndex].CustomExcerpt
+			escape = func(value []byte) []byte {
+				return evaluateEscape(value, helper.Unescaped)
+			}
+		}
 		if len(helper.Arguments) != 0 {
 			arguments := methods.ProcessHelperArguments(helper.Arguments)
 			for key, value := range arguments {
 				if key == "words" {
 					number, err := strconv.Atoi(value)
 					if err == nil {
-						excerpt := conversion.StripTagsFromHtml(values.Posts[values.CurrentPostIndex].Html)
 						words := bytes.Fields(excerpt)
 						if len(words) < number {
-							return excerpt
+							return escape(excerpt)
 						}
-						return bytes.Join(words[:number], []byte(" "))
+						return escape(bytes.Join(words[:number], []byte(" ")))
 					}
 				} else if key == "characters" {
 					number, err := strconv.Atoi(value)
 					if err == nil {
 						// Use runes for UTF-8 support
-						runes := []rune(string(conversion.StripTagsFromHtml(values.Posts[values.CurrentPostIndex].Html)))
+						runes := []rune(string(excerpt))
 						if len(runes) < number {
-							return []byte(string(runes))
+							return escape([]byte(string(runes)))
 						}
-						return []byte(string(runes[:number]))
+						return escape([]byte(string(runes[:number])))
 					}
 				}
 			}
 		}
 		// Default to 50 words excerpt
-		excerpt := conversion.StripTagsFromHtml(values.Posts[values.CurrentPostIndex].Html)
 		words := bytes.Fields(excerpt)
 		if len(words) < 50 {
-			return excerpt
+			return escape(excerpt)
 		}
-		return bytes.Join(words[:50], []byte(" "))
+		return escape(bytes.Join(words[:50], []byte(" ")))
 	}
 	return []byte{}
 }
@@ -707,7 +822,7 @@ func atLastFunc(helper *structure.Helper, values *structure.RequestData) []byte
 		return []byte{}
 	}
 	if values.CurrentHelperContext == 2 { // tag
-		if values.CurrentTagIndex == (len(values.Posts[values.CurrentPostIndex].Tags) - 1) {
+		if values.CurrentTagIndex == (len(currentTags(values)) - 1) {
 			return []byte{1}
 		}
 		return []byte{}
@@ -762,7 +877,7 @@ func nameFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 		//buffer.Write(evaluateEscape([]byte(values.Posts[values.CurrentPostIndex].Tags[values.CurrentTagIndex].Name), helper.Unescaped))
 		//buffer.WriteString("</a>")
 		//return buffer.Bytes()
-		return evaluateEscape(values.Posts[values.CurrentPostIndex].Tags[values.CurrentTagIndex].Name, helper.Unescaped)
+		return evaluateEscape(currentTag(values).Name, helper.Unescaped)
 	}
 	// If author (commented out the code for generating a link. Ghost doesn't seem to do that).
 	//var buffer bytes.Buffer
@@ -778,19 +893,62 @@ func nameFunc(helper *structure.Helper, values *structure.RequestData) []byte {
 }
 
 func tagDotNameFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	if len(values.CurrentTag.Name) != 0 {
-		return evaluateEscape(values.CurrentTag.Name, helper.Unescaped)
-	} else {
-		return evaluateEscape(values.Posts[values.CurrentPostIndex].Tags[values.CurrentTagIndex].Name, helper.Unescaped)
-	}
+	return evaluateEscape(currentTag(values).Name, helper.Unescaped)
 }
 
 func tagDotSlugFunc(helper *structure.Helper, values *structure.RequestData) []byte {
-	if values.CurrentTag.Slug != "" {
-		return evaluateEscape([]byte(values.CurrentTag.Slug), helper.Unescaped)
-	} else {
-		return evaluateEscape([]byte(values.Posts[values.CurrentPostIndex].Tags[values.CurrentTagIndex].Slug), helper.Unescaped)
+	return evaluateEscape([]byte(currentTag(values).Slug), helper.Unescaped)
+}
+
+func tagDotDescriptionFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	return evaluateEscape(currentTag(values).Description, helper.Unescaped)
+}
+
+func tagDotImageFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	return evaluateEscape(currentTag(values).Image, helper.Unescaped)
+}
+
+func tagDotMetaTitleFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	return evaluateEscape(currentTag(values).MetaTitle, helper.Unescaped)
+}
+
+func tagDotMetaDescriptionFunc(helper *structure.Helper, values *structure.RequestData) []byte {
+	return evaluateEscape(currentTag(values).MetaDescription, helper.Unescaped)
+}
+
+// currentTag returns the current tag of {{#foreach tags}}, {{#foreach tag.children}} and {{#foreach tag.breadcrumbs}} or the tag of the tag page.
+func currentTag(values *structure.RequestData) *structure.Tag {
+	if values.CurrentTagList != nil {
+		return &values.CurrentTagList[values.CurrentTagIndex]
+	}
+	if values.CurrentTag != nil && values.CurrentTag.Slug != "" {
+		return values.CurrentTag
+	}
+	return &values.Posts[values.CurrentPostIndex].Tags[values.CurrentTagIndex]
+}
+
+// currentTags returns the tags that are iterated over in the tag context.
+func currentTags(values *structure.RequestData) []structure.Tag {
+	if values.CurrentTagList != nil {
+		return values.CurrentTagList
+	}
+	return values.Posts[values.CurrentPostIndex].Tags
+}
+
+// foreachTagList executes the block once for each of the tags, with the tag as context.
+func foreachTagList(helper *structure.Helper, values *structure.RequestData, tags []structure.Tag) []byte {
+	// Restore the tag list of the enclosing block (e.g. {{#foreach tags}}) afterwards
+	defer func(list []structure.Tag, index int) {
+		values.CurrentTagList = list
+		values.CurrentTagIndex = index
+	}(values.CurrentTagList, values.CurrentTagIndex)
+	var buffer bytes.Buffer
+	for index, _ := range tags {
+		values.CurrentTagList = tags
+		values.CurrentTagIndex = index
+		buffer.Write(executeHelper(helper, values, 2)) // context = tag
 	}
+	return buffer.Bytes()
 }
 
 func idFunc(helper *structure.Helper, values *structure.RequestData) []byte {
@@ -820,14 +978,16 @@ func foreachFunc(helper *structure.Helper, values *structure.RequestData) []byte
 			}
 			return buffer.Bytes()
 		case "tags":
-			var buffer bytes.Buffer
-			for index, _ := range values.Posts[values.CurrentPostIndex].Tags {
-				//if values.Posts[values.CurrentPostIndex].Tags[index].Id != 0 { // If tag is not empty (Commented out for now. Not neccessary.)
-				values.CurrentTagIndex = index
-				buffer.Write(executeHelper(helper, values, 2)) // context = tag
-				//}
+			return foreachTagList(helper, values, methods.PublicTags(values.Posts[values.CurrentPostIndex].Tags))
+		case "tag.children":
+			children, err := methods.ChildTags(currentTag(values))
+			if err != nil {
+				log.Println("Couldn't get child tags", err.Error())
+				return []byte{}
 			}
-			return buffer.Bytes()
+			return foreachTagList(helper, values, methods.PublicTags(children))
+		case "tag.breadcrumbs":
+			return foreachTagList(helper, values, methods.PublicTags(methods.ParentTags(currentTag(values))))
 		case "navigation":
 			var buffer bytes.Buffer
 			for index, _ := range values.Blog.NavigationItems {
@@ -0,0 +1,316 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"journey/conversion"
+	"journey/structure"
+	"journey/structure/methods"
+)
+
+// Number of words of the post content that are used if a post has no description
+const headDescriptionWords = 50
+
+// writeSocialMeta writes the Open Graph and Twitter Card meta tags for the current page.
+func writeSocialMeta(buffer *bytes.Buffer, values *structure.RequestData) {
+	blog := values.Blog
+	var ogType, title, description, pageUrl, image, twitterTitle, twitterDescription, twitterImage string
+	switch values.CurrentTemplate {
+	case 1: // post or page
+		post := &values.Posts[values.CurrentPostIndex]
+		ogType = "article"
+		title = firstNonEmpty(post.OgTitle, post.MetaTitle, post.Title)
+		description = firstNonEmpty(post.OgDescription, []byte(postDescription(post)))
+		pageUrl = postUrl(blog, post)
+		image = absoluteUrl(blog, firstNonEmpty(post.OgImage, post.Image))
+		twitterTitle = firstNonEmpty(post.TwitterTitle, post.MetaTitle, post.Title)
+		twitterDescription = firstNonEmpty(post.TwitterDescription, []byte(postDescription(post)))
+		twitterImage = absoluteUrl(blog, firstNonEmpty(post.TwitterImage, post.OgImage, post.Image))
+	case 2: // tag
+		ogType = "website"
+		title = firstNonEmpty(values.CurrentTag.MetaTitle, []byte(string(values.CurrentTag.Name)+" - "+string(blog.Title)))
+		description = firstNonEmpty(values.CurrentTag.MetaDescription, values.CurrentTag.Description, blog.Description)
+		pageUrl = string(blog.Url) + values.CurrentPath
+		image = absoluteUrl(blog, firstNonEmpty(values.CurrentTag.Image, blog.Cover))
+	case 3: // author
+		if len(values.Posts) == 0 {
+			return
+		}
+		author := values.Posts[values.CurrentPostIndex].Author
+		ogType = "profile"
+		title = string(author.Name) + " - " + string(blog.Title)
+		description = firstNonEmpty(author.Bio, blog.Description)
+		pageUrl = string(blog.Url) + values.CurrentPath
+		image = absoluteUrl(blog, firstNonEmpty(author.Cover, blog.Cover))
+	case 0: // index
+		ogType = "website"
+		title = string(blog.Title)
+		description = string(blog.Description)
+		pageUrl = string(blog.Url) + values.CurrentPath
+		image = absoluteUrl(blog, string(blog.Cover))
+	default:
+		return
+	}
+	if twitterTitle == "" {
+		twitterTitle, twitterDescription, twitterImage = title, description, image
+	}
+	writeMeta(buffer, "property", "og:site_name", string(blog.Title))
+	writeMeta(buffer, "property", "og:type", ogType)
+	writeMeta(buffer, "property", "og:title", title)
+	writeMeta(buffer, "property", "og:description", description)
+	writeMeta(buffer, "property", "og:url", pageUrl)
+	writeMeta(buffer, "property", "og:image", image)
+	if values.CurrentTemplate == 1 {
+		post := &values.Posts[values.CurrentPostIndex]
+		writeMeta(buffer, "property", "article:published_time", formatHeadDate(post.Date))
+		writeMeta(buffer, "property", "article:modified_time", formatHeadDate(post.UpdatedAt))
+		for _, tag := range methods.PublicTags(post.Tags) {
+			writeMeta(buffer, "property", "article:tag", string(tag.Name))
+		}
+	}
+	if twitterImage != "" {
+		writeMeta(buffer, "name", "twitter:card", "summary_large_image")
+	} else {
+		writeMeta(buffer, "name", "twitter:card", "summary")
+	}
+	writeMeta(buffer, "name", "twitter:title", twitterTitle)
+	writeMeta(buffer, "name", "twitter:description", twitterDescription)
+	writeMeta(buffer, "name", "twitter:url", pageUrl)
+	writeMeta(buffer, "name", "twitter:image", twitterImage)
+}
+
+// writeStructuredData writes the JSON-LD of the current page: an Article for posts, a Person for authors and the WebSite for the index.
+// Every page except the index also gets a BreadcrumbList.
+func writeStructuredData(buffer *bytes.Buffer, values *structure.RequestData) {
+	blog := values.Blog
+	home := breadcrumb{string(blog.Title), string(blog.Url) + "/"}
+	switch values.CurrentTemplate {
+	case 1: // post or page
+		post := &values.Posts[values.CurrentPostIndex]
+		url := postUrl(blog, post)
+		publicTags := methods.PublicTags(post.Tags)
+		tags := make([]string, len(publicTags))
+		for index, _ := range publicTags {
+			tags[index] = string(publicTags[index].Name)
+		}
+		article := map[string]interface{}{
+			"@context":         "https://schema.org",
+			"@type":            "Article",
+			"headline":         firstNonEmpty(post.MetaTitle, post.Title),
+			"url":              url,
+			"mainEntityOfPage": map[string]interface{}{"@type": "WebPage", "@id": url},
+			"datePublished":    formatHeadDate(post.Date),
+			"dateModified":     formatHeadDate(post.UpdatedAt),
+			"author":           personData(blog, post.Author),
+			"publisher":        publisherData(blog),
+		}
+		setIfNotEmpty(article, "description", postDescription(post))
+		setIfNotEmpty(article, "image", absoluteUrl(blog, string(post.Image)))
+		setIfNotEmpty(article, "keywords", strings.Join(tags, ", "))
+		writeJsonLd(buffer, article)
+		crumbs := []breadcrumb{home}
+		if !post.IsPage && len(publicTags) != 0 {
+			crumbs = append(crumbs, tagBreadcrumbs(blog, &publicTags[0])...)
+		}
+		writeJsonLd(buffer, breadcrumbData(append(crumbs, breadcrumb{string(post.Title), url})))
+	case 2: // tag
+		writeJsonLd(buffer, breadcrumbData(append([]breadcrumb{home}, tagBreadcrumbs(blog, values.CurrentTag)...)))
+	case 3: // author
+		if len(values.Posts) == 0 {
+			return
+		}
+		author := values.Posts[values.CurrentPostIndex].Author
+		person := personData(blog, author)
+		person["@context"] = "https://schema.org"
+		setIfNotEmpty(person, "description", string(author.Bio))
+		writeJsonLd(buffer, person)
+		writeJsonLd(buffer, breadcrumbData([]breadcrumb{home, {string(author.Name), string(blog.Url) + "/author/" + author.Slug + "/"}}))
+	case 0: // index
+		website := map[string]interface{}{
+			"@context":  "https://schema.org",
+			"@type":     "WebSite",
+			"name":      string(blog.Title),
+			"url":       string(blog.Url) + "/",
+			"publisher": publisherData(blog),
+			"potentialAction": map[string]interface{}{
+				"@type":       "SearchAction",
+				"target":      string(blog.Url) + "/search/?q={search_term_string}",
+				"query-input": "required name=search_term_string",
+			},
+		}
+		setIfNotEmpty(website, "description", string(blog.Description))
+		setIfNotEmpty(website, "image", absoluteUrl(blog, string(blog.Cover)))
+		writeJsonLd(buffer, website)
+	}
+}
+
+// writeFeedLinks writes the alternate links of the feeds that belong to the current page. The feeds of the whole blog are always included.
+func writeFeedLinks(buffer *bytes.Buffer, values *structure.RequestData) {
+	blog := values.Blog
+	if values.CurrentTemplate == 2 { // tag
+		writeFeedAlternates(buffer, string(values.CurrentTag.Name)+" - "+string(blog.Title), string(blog.Url)+"/tag/"+values.CurrentTag.Slug+"/")
+	} else if values.CurrentTemplate == 3 && len(values.Posts) != 0 { // author
+		author := values.Posts[values.CurrentPostIndex].Author
+		writeFeedAlternates(buffer, string(author.Name)+" - "+string(blog.Title), string(blog.Url)+"/author/"+author.Slug+"/")
+	}
+	writeFeedAlternates(buffer, string(blog.Title), string(blog.Url)+"/")
+}
+
+// writeFeedAlternates writes the links to the rss, atom and json feed below the given url.
+func writeFeedAlternates(buffer *bytes.Buffer, title string, url string) {
+	writeAlternate(buffer, "application/rss+xml", title, url+"rss/")
+	writeAlternate(buffer, "application/atom+xml", title, url+"atom/")
+	writeAlternate(buffer, "application/feed+json", title, url+"feed.json")
+}
+
+// writePaginationLinks writes the prev and next links of paginated pages.
+func writePaginationLinks(buffer *bytes.Buffer, values *structure.RequestData) {
+	if values.CurrentTemplate != 0 && values.CurrentTemplate != 2 && values.CurrentTemplate != 3 { // index, tag and author
+		return
+	}
+	if values.CurrentTemplate == 3 && len(values.Posts) == 0 {
+		return
+	}
+	for _, direction := range []string{"prev", "next"} {
+		pageHelper := structure.Helper{Name: "page_url", Arguments: []structure.Helper{{Name: direction}}}
+		path := page_urlFunc(&pageHelper, values)
+		if len(path) != 0 {
+			buffer.WriteString("<link rel=\"" + direction + "\" href=\"")
+			buffer.WriteString(html.EscapeString(string(values.Blog.Url) + string(path)))
+			buffer.WriteString("\">")
+		}
+	}
+}
+
+type breadcrumb struct {
+	name string
+	url  string
+}
+
+// tagBreadcrumbs returns the breadcrumbs of the parents of the tag, followed by the one of the tag.
+func tagBreadcrumbs(blog *structure.Blog, tag *structure.Tag) []breadcrumb {
+	tags := append(methods.PublicTags(methods.ParentTags(tag)), *tag)
+	crumbs := make([]breadcrumb, len(tags))
+	for index, _ := range tags {
+		crumbs[index] = breadcrumb{string(tags[index].Name), string(blog.Url) + "/tag/" + tags[index].Slug + "/"}
+	}
+	return crumbs
+}
+
+func breadcrumbData(crumbs []breadcrumb) map[string]interface{} {
+	items := make([]map[string]interface{}, len(crumbs))
+	for index, crumb := range crumbs {
+		items[index] = map[string]interface{}{"@type": "ListItem", "position": index + 1, "name": crumb.name, "item": crumb.url}
+	}
+	return map[string]interface{}{"@context": "https://schema.org", "@type": "BreadcrumbList", "itemListElement": items}
+}
+
+func personData(blog *structure.Blog, author *structure.User) map[string]interface{} {
+	person := map[string]interface{}{
+		"@type": "Person",
+		"name":  string(author.Name),
+		"url":   string(blog.Url) + "/author/" + author.Slug + "/",
+	}
+	setIfNotEmpty(person, "image", absoluteUrl(blog, string(author.Image)))
+	if len(author.Website) != 0 {
+		person["sameAs"] = []string{string(author.Website)}
+	}
+	return person
+}
+
+func publisherData(blog *structure.Blog) map[string]interface{} {
+	publisher := map[string]interface{}{"@type": "Organization", "name": string(blog.Title)}
+	if logo := absoluteUrl(blog, string(blog.Logo)); logo != "" {
+		publisher["logo"] = map[string]interface{}{"@type": "ImageObject", "url": logo}
+	}
+	return publisher
+}
+
+// writeJsonLd writes the data as a JSON-LD script. json.Marshal escapes <, > and &, so the data can't end the script early.
+func writeJsonLd(buffer *bytes.Buffer, data map[string]interface{}) {
+	jsonLd, err := json.Marshal(data)
+	if err != nil {
+		log.Println("Couldn't generate structured data:", err)
+		return
+	}
+	buffer.WriteString("<script type=\"application/ld+json\">")
+	buffer.Write(jsonLd)
+	buffer.WriteString("</script>")
+}
+
+// writeMeta writes a meta tag. Tags without content are left out.
+func writeMeta(buffer *bytes.Buffer, attribute string, name string, content string) {
+	if content == "" {
+		return
+	}
+	buffer.WriteString("<meta " + attribute + "=\"" + name + "\" content=\"")
+	buffer.WriteString(html.EscapeString(content))
+	buffer.WriteString("\">")
+}
+
+func writeAlternate(buffer *bytes.Buffer, mimeType string, title string, url string) {
+	buffer.WriteString("<link rel=\"alternate\" type=\"" + mimeType + "\" title=\"")
+	buffer.WriteString(html.EscapeString(title))
+	buffer.WriteString("\" href=\"")
+	buffer.WriteString(html.EscapeString(url))
+	buffer.WriteString("\">")
+}
+
+// postDescription returns the meta description of the post, its custom excerpt or the beginning of its content as plain text.
+func postDescription(post *structure.Post) string {
+	if len(post.MetaDescription) != 0 {
+		return string(post.MetaDescription)
+	}
+	return plainExcerpt(post, headDescriptionWords)
+}
+
+// plainExcerpt returns the custom excerpt of the post or the first words of its text.
+func plainExcerpt(post *structure.Post, length int) string {
+	if len(post.CustomExcerpt) != 0 {
+		return string(post.CustomExcerpt)
+	}
+	words := strings.Fields(html.UnescapeString(string(conversion.StripTagsFromHtml(post.Html))))
+	if len(words) > length {
+		words = words[:length]
+	}
+	return strings.Join(words, " ")
+}
+
+func postUrl(blog *structure.Blog, post *structure.Post) string {
+	return string(blog.Url) + "/" + post.Slug + "/"
+}
+
+// absoluteUrl prefixes paths on the blog (e.g. uploaded images) with the blog url.
+func absoluteUrl(blog *structure.Blog, url string) string {
+	if strings.HasPrefix(url, "/") && !strings.HasPrefix(url, "//") {
+		return string(blog.Url) + url
+	}
+	return url
+}
+
+func formatHeadDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(time.RFC3339)
+}
+
+func firstNonEmpty(values ...[]byte) string {
+	for _, value := range values {
+		if len(value) != 0 {
+			return string(value)
+		}
+	}
+	return ""
+}
+
+func setIfNotEmpty(data map[string]interface{}, key string, value string) {
+	if value != "" {
+		data[key] = value
+	}
+}
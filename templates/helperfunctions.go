@@ -48,8 +48,14 @@ var helperFuctions = map[string]func(*structure.Helper, *structure.RequestData)
 	"post.id":    idFunc,
 
 	// Tag functions
-	"tag.name": tagDotNameFunc,
-	"tag.slug": tagDotSlugFunc,
+	"tag.name":             tagDotNameFunc,
+	"tag.slug":             tagDotSlugFunc,
+	"tag.description":      tagDotDescriptionFunc,
+	"tag.image":            tagDotImageFunc,
+	"tag.meta_title":       tagDotMetaTitleFunc,
+	"tag.meta_description": tagDotMetaDescriptionFunc,
+	"tag.children":         tagDotChildrenFunc,
+	"tag.breadcrumbs":      tagDotBreadcrumbsFunc,
 
 	// Author functions
 	"author":          authorFunc,
@@ -66,6 +72,10 @@ var helperFuctions = map[string]func(*structure.Helper, *structure.RequestData)
 	"author.cover":    coverFunc,
 	"author.location": locationFunc,
 
+	// Search functions
+	"snippet":      snippetFunc,
+	"search.query": searchDotQueryFunc,
+
 	// Navigation functions
 	"navigation": navigationFunc,
 	"label":      labelFunc,
@@ -1,80 +1,250 @@
 package templates
 
 import (
+	"bytes"
 	"encoding/xml"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"journey/configuration"
 	"journey/database"
+	"journey/structure"
 	"journey/structure/methods"
 )
 
+const sitemapXmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+const sitemapImageXmlns = "http://www.google.com/schemas/sitemap-image/1.1"
+
+// A sitemap may list up to 50,000 urls. Bigger sitemaps are split into pages (e.g. sitemap-posts.xml?page=2).
+const sitemapUrlsPerFile = 50000
+
 type URL struct {
-	XMLName    xml.Name `xml:"url"`
-	Loc        string   `xml:"loc"`
-	LastMod    string   `xml:"lastmod,omitempty"`
-	ChangeFreq string   `xml:"changefreq,omitempty"`
-	Priority   string   `xml:"priority,omitempty"`
+	XMLName    xml.Name       `xml:"url"`
+	Loc        string         `xml:"loc"`
+	LastMod    string         `xml:"lastmod,omitempty"`
+	ChangeFreq string         `xml:"changefreq,omitempty"`
+	Priority   string         `xml:"priority,omitempty"`
+	Images     []SitemapImage `xml:"image:image"`
+	updatedAt  *time.Time
+}
+
+type SitemapImage struct {
+	Loc string `xml:"image:loc"`
 }
 
 type URLSet struct {
-	XMLName xml.Name `xml:"urlset"`
-	Xmlns   string   `xml:"xmlns,attr"`
-	URLs    []URL    `xml:"url"`
+	XMLName    xml.Name `xml:"urlset"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	XmlnsImage string   `xml:"xmlns:image,attr,omitempty"`
+	URLs       []URL    `xml:"url"`
+}
+
+type Sitemap struct {
+	XMLName xml.Name `xml:"sitemap"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+type SitemapIndex struct {
+	XMLName  xml.Name  `xml:"sitemapindex"`
+	Xmlns    string    `xml:"xmlns,attr"`
+	Sitemaps []Sitemap `xml:"sitemap"`
 }
 
-func ShowSitemap(w http.ResponseWriter) error {
+// The sub-sitemaps /sitemap.xml points to. Each one is served at /sitemap-<name>.xml.
+var sitemapTypes = []struct {
+	name string
+	urls func(baseURL string) ([]URL, error)
+}{
+	{"posts", postSitemapUrls},
+	{"pages", pageSitemapUrls},
+	{"tags", tagSitemapUrls},
+	{"authors", authorSitemapUrls},
+}
+
+// Generated sitemaps by file name (plus page parameter). The global blog is regenerated on every post change,
+// so the files are generated again once it isn't the blog they were generated with anymore.
+var sitemapCache = struct {
+	sync.Mutex
+	blog  *structure.Blog
+	files map[string][]byte
+}{}
+
+// InvalidateSitemap makes sure the sitemaps are generated again on the next request, e.g. after an author was changed.
+func InvalidateSitemap() {
+	sitemapCache.Lock()
+	defer sitemapCache.Unlock()
+	sitemapCache.files = nil
+}
+
+// ShowSitemap writes the sitemap with the given file name (e.g. sitemap.xml or sitemap-posts.xml).
+func ShowSitemap(w http.ResponseWriter, r *http.Request, name string, page int) error {
+	files, err := sitemapFiles()
+	if err != nil {
+		return err
+	}
+	data, ok := files[sitemapFileKey(name, page)]
+	if !ok {
+		http.NotFound(w, r)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	_, err = w.Write(data)
+	return err
+}
+
+func sitemapFiles() (map[string][]byte, error) {
 	methods.Blog.RLock()
 	defer methods.Blog.RUnlock()
+	sitemapCache.Lock()
+	defer sitemapCache.Unlock()
+	if sitemapCache.files != nil && sitemapCache.blog == methods.Blog {
+		return sitemapCache.files, nil
+	}
+	files, err := generateSitemaps()
+	if err != nil {
+		return nil, err
+	}
+	sitemapCache.blog = methods.Blog
+	sitemapCache.files = files
+	return files, nil
+}
 
+func generateSitemaps() (map[string][]byte, error) {
 	baseURL := configuration.Config.Url
 	if configuration.Config.HttpsUsage != "None" {
 		baseURL = configuration.Config.HttpsUrl
 	}
+	files := make(map[string][]byte)
+	index := SitemapIndex{Xmlns: sitemapXmlns, Sitemaps: []Sitemap{}}
+	for _, sitemapType := range sitemapTypes {
+		urls, err := sitemapType.urls(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		name := "sitemap-" + sitemapType.name + ".xml"
+		// Every sub-sitemap has at least one page, even if it's empty
+		for page := 1; page == 1 || (page-1)*sitemapUrlsPerFile < len(urls); page++ {
+			end := page * sitemapUrlsPerFile
+			if end > len(urls) {
+				end = len(urls)
+			}
+			urlset := URLSet{Xmlns: sitemapXmlns, XmlnsImage: sitemapImageXmlns, URLs: urls[(page-1)*sitemapUrlsPerFile : end]}
+			data, err := encodeSitemap(urlset)
+			if err != nil {
+				return nil, err
+			}
+			key := sitemapFileKey(name, page)
+			files[key] = data
+			index.Sitemaps = append(index.Sitemaps, Sitemap{Loc: baseURL + "/" + key, LastMod: formatSitemapDate(newestSitemapUpdate(urlset.URLs))})
+		}
+	}
+	data, err := encodeSitemap(index)
+	if err != nil {
+		return nil, err
+	}
+	files["sitemap.xml"] = data
+	return files, nil
+}
 
-	urlset := URLSet{
-		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
-		URLs:  []URL{},
+func sitemapFileKey(name string, page int) string {
+	if page > 1 {
+		return name + "?page=" + strconv.Itoa(page)
 	}
+	return name
+}
 
-	urlset.URLs = append(urlset.URLs, URL{
-		Loc:        baseURL,
-		ChangeFreq: "daily",
-		Priority:   "1.0",
-	})
+func encodeSitemap(document interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buffer)
+	encoder.Indent("", "  ")
+	err := encoder.Encode(document)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
 
-	posts, err := database.RetrievePostsForIndex(9999, 0)
+func postSitemapUrls(baseURL string) ([]URL, error) {
+	posts, err := database.RetrieveSitemapPosts(false)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return sitemapUrls(baseURL, "/", posts, "weekly", "0.8"), nil
+}
+
+// pageSitemapUrls returns the urls of the pages and of the home page of the blog.
+func pageSitemapUrls(baseURL string) ([]URL, error) {
+	posts, err := database.RetrieveSitemapPosts(false)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := database.RetrieveSitemapPosts(true)
+	if err != nil {
+		return nil, err
+	}
+	home := URL{Loc: baseURL + "/", ChangeFreq: "daily", Priority: "1.0"}
+	home.updatedAt = newestSitemapUpdate(sitemapUrls(baseURL, "/", posts, "", ""))
+	home.LastMod = formatSitemapDate(home.updatedAt)
+	return append([]URL{home}, sitemapUrls(baseURL, "/", pages, "monthly", "0.6")...), nil
+}
+
+func tagSitemapUrls(baseURL string) ([]URL, error) {
+	tags, err := database.RetrieveSitemapTags()
+	if err != nil {
+		return nil, err
+	}
+	return sitemapUrls(baseURL, "/tag/", tags, "weekly", "0.6"), nil
+}
+
+func authorSitemapUrls(baseURL string) ([]URL, error) {
+	authors, err := database.RetrieveSitemapAuthors()
+	if err != nil {
+		return nil, err
 	}
+	return sitemapUrls(baseURL, "/author/", authors, "weekly", "0.6"), nil
+}
 
-	for _, post := range posts {
-		if post.IsPublished && !post.IsPage {
-			urlset.URLs = append(urlset.URLs, URL{
-				Loc:        baseURL + "/" + post.Slug + "/",
-				LastMod:    post.Date.Format(time.RFC3339),
-				ChangeFreq: "weekly",
-				Priority:   "0.8",
-			})
+func sitemapUrls(baseURL string, path string, entries []structure.SitemapEntry, changeFreq string, priority string) []URL {
+	urls := make([]URL, 0, len(entries))
+	for _, entry := range entries {
+		url := URL{
+			Loc:        baseURL + path + entry.Slug + "/",
+			LastMod:    formatSitemapDate(entry.UpdatedAt),
+			ChangeFreq: changeFreq,
+			Priority:   priority,
+			updatedAt:  entry.UpdatedAt,
+		}
+		// Cover images are uploaded to the blog or absolute urls
+		if len(entry.Image) != 0 {
+			image := string(entry.Image)
+			if strings.HasPrefix(image, "/") && !strings.HasPrefix(image, "//") {
+				image = baseURL + image
+			}
+			url.Images = []SitemapImage{{Loc: image}}
 		}
+		urls = append(urls, url)
 	}
+	return urls
+}
 
-	for _, post := range posts {
-		if post.IsPublished && post.IsPage {
-			urlset.URLs = append(urlset.URLs, URL{
-				Loc:        baseURL + "/" + post.Slug + "/",
-				LastMod:    post.Date.Format(time.RFC3339),
-				ChangeFreq: "monthly",
-				Priority:   "0.6",
-			})
+func newestSitemapUpdate(urls []URL) *time.Time {
+	var newest *time.Time
+	for _, url := range urls {
+		if url.updatedAt != nil && (newest == nil || url.updatedAt.After(*newest)) {
+			newest = url.updatedAt
 		}
 	}
-	
-	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>` + "\n"))
+	return newest
+}
 
-	encoder := xml.NewEncoder(w)
-	encoder.Indent("", "  ")
-	return encoder.Encode(urlset)
+func formatSitemapDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(time.RFC3339)
 }
@@ -0,0 +1,92 @@
+// Package totp implements time-based one-time passwords (RFC 6238) as used by authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Codes change every 30 seconds and have 6 digits (the defaults of all common authenticator apps)
+const period = 30
+const digits = 6
+
+// Number of periods before and after the current one that are accepted to allow for clock drift
+const skew = 1
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 encoded secret of 160 bits.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 20)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// Code returns the code for the given secret and time.
+func Code(secret string, t time.Time) (string, error) {
+	return codeForStep(secret, step(t))
+}
+
+// Validate checks the code against the codes of the current time step and its neighbours. Codes of a step
+// that is not after lastStep are rejected so that a code can't be used twice. If the code is valid, its step
+// is returned and has to be stored as the new lastStep.
+func Validate(secret string, code string, t time.Time, lastStep int64) (int64, bool) {
+	code = strings.Replace(strings.TrimSpace(code), " ", "", -1)
+	if len(code) != digits {
+		return 0, false
+	}
+	current := step(t)
+	for offset := int64(-skew); offset <= skew; offset++ {
+		candidate := current + offset
+		if candidate <= lastStep {
+			continue
+		}
+		expected, err := codeForStep(secret, candidate)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return candidate, true
+		}
+	}
+	return 0, false
+}
+
+// ProvisioningUri returns the otpauth:// uri that authenticator apps read from a QR code.
+func ProvisioningUri(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	parameters := url.Values{}
+	parameters.Set("secret", secret)
+	parameters.Set("issuer", issuer)
+	return "otpauth://totp/" + label + "?" + parameters.Encode()
+}
+
+func step(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+func codeForStep(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+	// Dynamic truncation as described in RFC 4226
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000), nil
+}
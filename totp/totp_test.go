@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Base32 encoding of the ascii secret "12345678901234567890" used by the test vectors in RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8 digit codes, these are the last 6 digits
+var codeTests = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, tt := range codeTests {
+		code, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Errorf("Code(%d) returned error %v", tt.unix, err)
+			continue
+		}
+		if code != tt.code {
+			t.Errorf("Code(%d) = %q, want %q", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	currentStep := now.Unix() / period
+	// Codes of the neighbouring steps are accepted
+	for offset := int64(-1); offset <= 1; offset++ {
+		code, _ := Code(rfcSecret, now.Add(time.Duration(offset*period)*time.Second))
+		usedStep, ok := Validate(rfcSecret, code, now, 0)
+		if !ok || usedStep != currentStep+offset {
+			t.Errorf("Validate with offset %d = (%d, %v), want (%d, true)", offset, usedStep, ok, currentStep+offset)
+		}
+	}
+	// Codes from further away are rejected
+	code, _ := Code(rfcSecret, now.Add(-2*period*time.Second))
+	if _, ok := Validate(rfcSecret, code, now, 0); ok {
+		t.Errorf("Validate accepted a code from two steps ago")
+	}
+	// A code can't be used twice
+	code, _ = Code(rfcSecret, now)
+	if _, ok := Validate(rfcSecret, code, now, currentStep); ok {
+		t.Errorf("Validate accepted a code of an already used step")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now, 0); ok {
+		t.Errorf("Validate accepted a code with the wrong length")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("GenerateSecret returned %q with length %d, want 32", secret, len(secret))
+	}
+	if _, err := Code(secret, time.Now()); err != nil {
+		t.Errorf("Code with generated secret returned error %v", err)
+	}
+}
+
+func TestProvisioningUri(t *testing.T) {
+	uri := ProvisioningUri("My Blog", "owner", "ABC")
+	want := "otpauth://totp/My%20Blog:owner?issuer=My+Blog&secret=ABC"
+	if uri != want {
+		t.Errorf("ProvisioningUri = %q, want %q", uri, want)
+	}
+}